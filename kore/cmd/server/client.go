@@ -0,0 +1,340 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gg-glitch-88/meshigo-kore/ydin/state"
+	"github.com/gg-glitch-88/meshigo-kore/ydin/transport"
+)
+
+// defaultServer is where the client subcommands look for the gateway.
+const defaultServer = "http://127.0.0.1:8080"
+
+// apiClient talks to a running gateway's /api/v1.
+type apiClient struct {
+	server string
+	json   bool
+	http   http.Client
+}
+
+// newClientFlags returns a flag set for the client subcommand name, with
+// -server and -json bound to a new apiClient.
+func newClientFlags(name, usage string) (*flag.FlagSet, *apiClient) {
+	c := &apiClient{http: http.Client{Timeout: 30 * time.Second}}
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.StringVar(&c.server, "server", defaultServer, "gateway base URL")
+	fs.BoolVar(&c.json, "json", false, "print the API's JSON instead of text")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: meshkore "+usage)
+		fs.PrintDefaults()
+	}
+	return fs, c
+}
+
+// call sends a request to path below /api/v1 and returns the response
+// body. A non-2xx response is an error carrying the API's message.
+func (c *apiClient) call(method, path string, body any) ([]byte, error) {
+	var rd io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		rd = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, strings.TrimRight(c.server, "/")+"/api/v1"+path, rd)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		msg := strings.TrimSpace(string(data))
+		if msg == "" {
+			msg = resp.Status
+		}
+		return nil, fmt.Errorf("%s %s: %s", method, path, msg)
+	}
+	return data, nil
+}
+
+// get fetches path and, unless -json is set, decodes it into out. With
+// -json the body is printed to w and get reports printed.
+func (c *apiClient) get(w io.Writer, path string, out any) (printed bool, err error) {
+	data, err := c.call(http.MethodGet, path, nil)
+	if err != nil {
+		return false, err
+	}
+	if c.json {
+		return true, printJSON(w, data)
+	}
+	return false, json.Unmarshal(data, out)
+}
+
+// ── nodes ─────────────────────────────────────────────────────────────────
+
+// runNodes implements `meshkore nodes`: every known node, most recently
+// heard first.
+func runNodes(args []string, stdout io.Writer) error {
+	fs, c := newClientFlags("nodes", "nodes [-server URL] [-json]")
+	if err := parseClientFlags(fs, args, 0); err != nil {
+		return err
+	}
+	var resp struct {
+		Nodes []*state.Node `json:"nodes"`
+	}
+	if printed, err := c.get(stdout, "/nodes", &resp); printed || err != nil {
+		return err
+	}
+	sort.Slice(resp.Nodes, func(i, j int) bool {
+		return resp.Nodes[i].LastSeen.After(resp.Nodes[j].LastSeen)
+	})
+
+	tw := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tSHORT\tNAME\tHARDWARE\tLAST HEARD\tSNR\tHOPS\tBATTERY")
+	for _, n := range resp.Nodes {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			n.NodeIDHex, n.ShortName, n.LongName, n.Hardware,
+			ago(n.LastSeen), formatSNR(n.SNR), formatHops(n.HopsAway), formatBattery(n.BatteryLevel))
+	}
+	return tw.Flush()
+}
+
+// runNode implements `meshkore node <id>`: everything known about one node.
+func runNode(args []string, stdout io.Writer) error {
+	fs, c := newClientFlags("node", "node [-server URL] [-json] <id>")
+	if err := parseClientFlags(fs, args, 1); err != nil {
+		return err
+	}
+	id, err := parseNodeArg(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	var n state.Node
+	if printed, err := c.get(stdout, "/nodes/"+id, &n); printed || err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	row := func(k, format string, args ...any) { fmt.Fprintf(tw, "%s\t"+format+"\n", append([]any{k}, args...)...) }
+	row("ID", "%s (%d)", n.NodeIDHex, n.NodeID)
+	row("Name", "%s (%s)", n.LongName, n.ShortName)
+	row("Hardware", "%s", n.Hardware)
+	row("Role", "%s", n.Role)
+	if n.FirmwareVersion != "" {
+		row("Firmware", "%s", n.FirmwareVersion)
+	}
+	row("Licensed", "%t", n.IsLicensed)
+	row("Public key", "%t", len(n.PublicKey) > 0)
+	row("Last heard", "%s", ago(n.LastSeen))
+	row("SNR", "%s", formatSNR(n.SNR))
+	row("Hops away", "%s", formatHops(n.HopsAway))
+	row("Via MQTT", "%t", n.ViaMQTT)
+	row("Battery", "%s", formatBattery(n.BatteryLevel))
+	if n.Voltage != 0 {
+		row("Voltage", "%.2f V", n.Voltage)
+	}
+	row("Channel util", "%.1f%%", n.ChannelUtil)
+	if n.Lat != 0 || n.Lon != 0 {
+		row("Position", "%.5f, %.5f, %d m", n.Lat, n.Lon, n.Alt)
+	}
+	return tw.Flush()
+}
+
+// ── send ──────────────────────────────────────────────────────────────────
+
+// runSend implements `meshkore send [-to id] [-channel n] <text>`: it
+// queues a text message, broadcast unless -to names a node.
+func runSend(args []string, stdout io.Writer) error {
+	fs, c := newClientFlags("send", "send [-server URL] [-json] [-to id] [-channel n] <text>...")
+	to := fs.String("to", "broadcast", `destination node, e.g. !deadbeef, or "broadcast"`)
+	channel := fs.Int("channel", 0, "channel index 0–7")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	text := strings.Join(fs.Args(), " ")
+	if strings.TrimSpace(text) == "" {
+		fs.Usage()
+		return errors.New("send: no message text")
+	}
+	if *to != "broadcast" {
+		id, err := parseNodeArg(*to)
+		if err != nil {
+			return fmt.Errorf("send: %w", err)
+		}
+		*to = id
+	}
+
+	data, err := c.call(http.MethodPost, "/messages", map[string]any{
+		"to_node": *to,
+		"channel": *channel,
+		"text":    text,
+	})
+	if err != nil {
+		return err
+	}
+	if c.json {
+		return printJSON(stdout, data)
+	}
+	var resp struct {
+		ID     int64  `json:"id"`
+		MeshID string `json:"mesh_id"`
+		Status string `json:"status"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "message %d %s (mesh id %s) to %s on channel %d\n",
+		resp.ID, resp.Status, resp.MeshID, *to, *channel)
+	return nil
+}
+
+// ── status ────────────────────────────────────────────────────────────────
+
+// statusResponse is GET /api/v1/status.
+type statusResponse struct {
+	Status        string                 `json:"status"`
+	Connection    string                 `json:"connection"`
+	NodeCount     int                    `json:"node_count"`
+	Subscribers   int                    `json:"subscribers"`
+	Outbox        int                    `json:"outbox"`
+	UptimeSeconds int64                  `json:"uptime_seconds"`
+	Links         []transport.LinkHealth `json:"links"`
+	Device        *struct {
+		NodeID       string    `json:"node_id"`
+		Firmware     string    `json:"firmware"`
+		Hardware     string    `json:"hardware"`
+		Role         string    `json:"role"`
+		ConfiguredAt time.Time `json:"configured_at"`
+	} `json:"device"`
+}
+
+// runStatus implements `meshkore status`: gateway and link health.
+func runStatus(args []string, stdout io.Writer) error {
+	fs, c := newClientFlags("status", "status [-server URL] [-json]")
+	if err := parseClientFlags(fs, args, 0); err != nil {
+		return err
+	}
+	var st statusResponse
+	if printed, err := c.get(stdout, "/status", &st); printed || err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "Status\t%s\n", st.Status)
+	fmt.Fprintf(tw, "Uptime\t%s\n", time.Duration(st.UptimeSeconds)*time.Second)
+	fmt.Fprintf(tw, "Connection\t%s\n", st.Connection)
+	if d := st.Device; d != nil {
+		fmt.Fprintf(tw, "Device\t%s %s, firmware %s, configured %s\n", d.NodeID, d.Hardware, d.Firmware, ago(d.ConfiguredAt))
+	} else {
+		fmt.Fprintf(tw, "Device\tnot configured\n")
+	}
+	fmt.Fprintf(tw, "Nodes\t%d\n", st.NodeCount)
+	fmt.Fprintf(tw, "Subscribers\t%d\n", st.Subscribers)
+	fmt.Fprintf(tw, "Outbox\t%d\n", st.Outbox)
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if len(st.Links) == 0 {
+		return nil
+	}
+
+	fmt.Fprintln(stdout)
+	tw = tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "LINK\tKIND\tSTATE\tIN\tOUT\tLAST FRAME\tERROR")
+	for _, l := range st.Links {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\t%s\t%s\n",
+			l.Name, l.Kind, l.State, l.FramesIn, l.FramesOut, ago(l.LastFrame), l.LastError)
+	}
+	return tw.Flush()
+}
+
+// ── helpers ───────────────────────────────────────────────────────────────
+
+// parseClientFlags parses args and checks there are exactly n arguments.
+func parseClientFlags(fs *flag.FlagSet, args []string, n int) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != n {
+		fs.Usage()
+		return fmt.Errorf("%s: want %d argument(s), got %d", fs.Name(), n, fs.NArg())
+	}
+	return nil
+}
+
+// parseNodeArg normalises a node ID given as "!deadbeef" or "deadbeef".
+func parseNodeArg(s string) (string, error) {
+	n, err := strconv.ParseUint(strings.TrimPrefix(s, "!"), 16, 32)
+	if err != nil {
+		return "", fmt.Errorf("invalid node id %q", s)
+	}
+	return fmt.Sprintf("!%08x", n), nil
+}
+
+func printJSON(w io.Writer, data []byte) error {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, data, "", "  "); err != nil {
+		return err
+	}
+	buf.WriteByte('\n')
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// ago formats t relative to now, to the second.
+func ago(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	d := time.Since(t).Round(time.Second)
+	if d < time.Second {
+		return "just now"
+	}
+	return d.String() + " ago"
+}
+
+func formatSNR(snr float32) string {
+	if snr == 0 {
+		return "-"
+	}
+	return strconv.FormatFloat(float64(snr), 'f', 1, 32) + " dB"
+}
+
+func formatHops(hops int) string {
+	if hops < 0 {
+		return "-"
+	}
+	return strconv.Itoa(hops)
+}
+
+func formatBattery(level uint32) string {
+	switch {
+	case level == 0:
+		return "-"
+	case level > 100:
+		return "powered"
+	}
+	return strconv.Itoa(int(level)) + "%"
+}
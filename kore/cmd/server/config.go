@@ -0,0 +1,36 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/gg-glitch-88/meshigo-kore/ydin/config"
+)
+
+// runConfig implements `meshkore config [-config file] [-set key=value]`:
+// it prints the effective configuration, secrets redacted, or every
+// invalid setting.
+func runConfig(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("config", flag.ContinueOnError)
+	var opts config.Options
+	opts.Register(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: meshkore config [-config file] [-set key=value]...")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return fmt.Errorf("config: unexpected argument %q", fs.Arg(0))
+	}
+
+	cfg, err := config.Load(opts, os.Environ())
+	if err != nil {
+		return err
+	}
+	return cfg.Write(stdout)
+}
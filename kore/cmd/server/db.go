@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/gg-glitch-88/meshigo-kore/ydin/config"
+	"github.com/gg-glitch-88/meshigo-kore/ydin/store"
+)
+
+// runDB implements `meshkore db [-config file] [-db path] migrate|status|rollback`.
+// The database is store.path from the configuration unless -db is given.
+//
+//	migrate [-to N]   apply pending migrations (up to N)
+//	status            list migrations and whether each is applied
+//	rollback [-to N]  revert the latest migration (or down to N)
+func runDB(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("db", flag.ContinueOnError)
+	var opts config.Options
+	opts.Register(fs)
+	dbPath := fs.String("db", "", "SQLite database (overrides store.path)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: meshkore db [-config file] [-db path] migrate [-to N] | status | rollback [-to N]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("db: expected a subcommand")
+	}
+	cmd, rest := fs.Arg(0), fs.Args()[1:]
+
+	sub := flag.NewFlagSet("db "+cmd, flag.ContinueOnError)
+	to := sub.Int("to", -1, "target schema version")
+	if err := sub.Parse(rest); err != nil {
+		return err
+	}
+	if sub.NArg() != 0 {
+		return fmt.Errorf("db %s: unexpected argument %q", cmd, sub.Arg(0))
+	}
+
+	if *dbPath != "" {
+		opts.Set = append(opts.Set, "store.path="+*dbPath)
+	}
+	cfg, err := config.Load(opts, os.Environ())
+	if err != nil {
+		return err
+	}
+	db, err := store.Open(cfg.Store.Path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	switch cmd {
+	case "migrate":
+		from, err := store.SchemaVersion(db)
+		if err != nil {
+			return err
+		}
+		target := *to
+		if target < 0 {
+			target = store.LatestVersion()
+		}
+		if err := store.MigrateTo(db, target); err != nil {
+			return err
+		}
+		if from == target {
+			fmt.Fprintf(stdout, "schema is up to date at version %d\n", target)
+		} else {
+			fmt.Fprintf(stdout, "migrated from version %d to %d\n", from, target)
+		}
+		return nil
+
+	case "rollback":
+		from, err := store.SchemaVersion(db)
+		if err != nil {
+			return err
+		}
+		target := *to
+		if target < 0 {
+			target = from - 1
+		}
+		if err := store.Rollback(db, target); err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "rolled back from version %d to %d\n", from, target)
+		return nil
+
+	case "status":
+		status, err := store.Status(db)
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED")
+		for _, s := range status {
+			applied := "pending"
+			if s.Applied {
+				applied = s.AppliedAt.Format("2006-01-02 15:04:05Z")
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%s\n", s.Version, s.Name, applied)
+		}
+		return tw.Flush()
+	}
+	return fmt.Errorf("db: unknown subcommand %q (want migrate, status or rollback)", cmd)
+}
@@ -1,30 +1,50 @@
 package main
 
 import (
-	"context"
+	"io"
 	"log/slog"
 	"os"
-
-	"github.com/gg-glitch-88/meshigo-kore/kore/internal/adapters"
-	"github.com/gg-glitch-88/meshigo-kore/kore/internal/domain"
 )
 
-func main() {
-	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+// command is a meshkore subcommand and the message logged if it fails.
+type command struct {
+	run    func(args []string, stdout io.Writer) error
+	failed string
+}
 
-	// 1. Initialize Adapters (Infrastructure)
-	repo := adapters.NewInMemoryRepo()
+var commands = map[string]command{
+	"replay": {runReplay, "Replay failed"},
+	"demo":   {runDemo, "Demo failed"},
+	"config": {runConfig, "Config failed"},
+	"db":     {runDB, "Database command failed"},
+	"nodes":  {runNodes, "Listing nodes failed"},
+	"node":   {runNode, "Fetching node failed"},
+	"send":   {runSend, "Sending message failed"},
+	"tail":   {runTail, "Event stream failed"},
+	"status": {runStatus, "Fetching status failed"},
+}
 
-	// 2. Inject into Domain Logic
-	// The compiler checks here if 'repo' satisfies 'domain.DataProvider'
-	logic := domain.NewLogicHandler(repo)
+func main() {
+	// Stdout carries the commands' output (e.g. replay's JSON events), so
+	// failures go to stderr.
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
 
-	// 3. Execute
-	ctx := context.Background()
-	if err := logic.Execute(ctx, "user-123"); err != nil {
-		logger.Error("Execution failed", "error", err)
-		os.Exit(1)
+	if len(os.Args) > 1 {
+		if cmd, ok := commands[os.Args[1]]; ok {
+			if err := cmd.run(os.Args[2:], os.Stdout); err != nil {
+				logger.Error(cmd.failed, "error", err)
+				os.Exit(1)
+			}
+			return
+		}
 	}
 
-	logger.Info("Process finished successfully")
-}
\ No newline at end of file
+	args := os.Args[1:]
+	if len(args) > 0 && args[0] == "serve" {
+		args = args[1:]
+	}
+	if err := runServe(args); err != nil {
+		logger.Error("Server failed", "error", err)
+		os.Exit(1)
+	}
+}
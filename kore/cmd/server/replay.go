@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/gg-glitch-88/meshigo-kore/ydin/config"
+	"github.com/gg-glitch-88/meshigo-kore/ydin/gateway"
+	"github.com/gg-glitch-88/meshigo-kore/ydin/store"
+	"github.com/gg-glitch-88/meshigo-kore/ydin/transport"
+)
+
+// replayDrainIdle is how long the gateway must stay quiet after the last
+// frame before a replay is considered complete.
+const replayDrainIdle = 500 * time.Millisecond
+
+// runReplay implements `meshkore replay [flags] <capture>`: it feeds a
+// capture file through a full gateway (ingest, state, event bus, REST API)
+// and prints every event as a JSON line on stdout.
+func runReplay(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	speed := fs.Float64("speed", 0, "playback speed: 1 = real time, 10 = 10x, 0 = as fast as possible")
+	dbPath := fs.String("db", ":memory:", "SQLite database to replay into")
+	listen := fs.String("listen", "127.0.0.1:0", "address for the REST API while replaying")
+	verbose := fs.Bool("v", false, "log gateway activity to stderr")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: meshkore replay [flags] <capture>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("replay: expected one capture file")
+	}
+
+	log := zap.NewNop()
+	if *verbose {
+		l, err := zap.NewDevelopment()
+		if err != nil {
+			return err
+		}
+		log = l
+		defer log.Sync() //nolint:errcheck
+	}
+
+	rt, err := transport.NewReplayTransport(fs.Arg(0), *speed, log)
+	if err != nil {
+		return err
+	}
+	db, err := store.Open(*dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if err := store.Migrate(db); err != nil {
+		return err
+	}
+
+	cfg := config.Default()
+	cfg.Gateway.ListenAddr = *listen
+	g, err := gateway.NewWithTransport(cfg, db, rt, log)
+	if err != nil {
+		return err
+	}
+
+	// At -speed 0 the gateway publishes far faster than a terminal prints:
+	// make it wait for us rather than drop events.
+	events, unsubscribe := g.SubscribeBlocking()
+	defer unsubscribe()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	gwErr := make(chan error, 1)
+	go func() { gwErr <- g.Start(ctx) }()
+
+	enc := json.NewEncoder(stdout)
+	played := rt.Done()
+	for {
+		select {
+		case e := <-events:
+			if err := enc.Encode(e); err != nil {
+				return err
+			}
+		case <-played:
+			// Let the gateway drain the frames still queued, then stop it.
+			// Keep reading while it shuts down: it waits for us.
+			drainEvents(events, enc)
+			cancel()
+			played = nil
+		case err := <-gwErr:
+			if err != nil {
+				return err
+			}
+			return rt.Err()
+		}
+	}
+}
+
+// drainEvents prints events until the gateway has been quiet for a moment.
+func drainEvents(events <-chan gateway.Event, enc *json.Encoder) {
+	for {
+		select {
+		case e := <-events:
+			enc.Encode(e) //nolint:errcheck
+		case <-time.After(replayDrainIdle):
+			return
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/gg-glitch-88/meshigo-kore/ydin/config"
+	"github.com/gg-glitch-88/meshigo-kore/ydin/gateway"
+	"github.com/gg-glitch-88/meshigo-kore/ydin/replication"
+	"github.com/gg-glitch-88/meshigo-kore/ydin/store"
+)
+
+// The README's state indicators, logged at the matching lifecycle points.
+const (
+	kaomojiReady        = "₍ᐢ. ̫ .ᐢ₎"
+	kaomojiTransmission = "ᕙ(`▿´)ᕗ"
+	kaomojiConflict     = "(╬ Ò﹏Ó)"
+	kaomojiNodeFailure  = "(✖╭╮✖)"
+	kaomojiShutdown     = "(－_－) zzZ"
+)
+
+// linkCheckInterval is how often link health is checked for lost links.
+const linkCheckInterval = 5 * time.Second
+
+// runServe implements `meshkore [serve] [-config file] [-set key=value]`:
+// it migrates the database, then runs the gateway and the replication
+// manager until SIGINT or SIGTERM and shuts both down. SIGHUP, like
+// POST /api/v1/admin/reload, re-reads the configuration.
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	var opts config.Options
+	opts.Register(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: meshkore [serve] [-config file] [-set key=value]...")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return fmt.Errorf("serve: unexpected argument %q", fs.Arg(0))
+	}
+
+	cfg, err := config.Load(opts, os.Environ())
+	if err != nil {
+		return err
+	}
+	level, err := zap.ParseAtomicLevel(strings.ToLower(cfg.Log.Level))
+	if err != nil {
+		return err
+	}
+	zc := zap.NewProductionConfig()
+	zc.Level = level
+	log, err := zc.Build()
+	if err != nil {
+		return err
+	}
+	defer log.Sync() //nolint:errcheck
+
+	if err := os.MkdirAll(filepath.Dir(cfg.Store.Path), 0o750); err != nil {
+		return fmt.Errorf("serve: %w", err)
+	}
+	db, err := store.Open(cfg.Store.Path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	from, err := store.SchemaVersion(db)
+	if err != nil {
+		return err
+	}
+	if err := store.Migrate(db); err != nil {
+		return err
+	}
+	if from != store.LatestVersion() {
+		log.Info("meshkore: database migrated",
+			zap.Int("from", from), zap.Int("to", store.LatestVersion()))
+	}
+
+	g, err := gateway.New(cfg, db, log)
+	if err != nil {
+		return err
+	}
+	rep := replication.New(&cfg.Replication, db, log)
+	g.SetLogLevel(level)
+	g.SetReplication(rep)
+	g.SetConfigLoader(func() (*config.Config, error) {
+		next, err := config.Load(opts, os.Environ())
+		if err != nil {
+			log.Warn("meshkore: config rejected "+kaomojiConflict, zap.Error(err))
+		}
+		return next, err
+	})
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	events, unsubscribe := g.Subscribe()
+	defer unsubscribe()
+	go watchLifecycle(ctx, g, events, log)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := rep.Start(ctx); err != nil {
+			log.Error("meshkore: replication stopped", zap.Error(err))
+		}
+	}()
+
+	log.Info("meshkore: starting",
+		zap.String("listen_addr", cfg.Gateway.ListenAddr),
+		zap.String("db", cfg.Store.Path),
+		zap.Int("links", len(cfg.Transport.Links)))
+	err = g.Start(ctx)
+
+	// The gateway may have failed on its own; take replication down too.
+	stop()
+	wg.Wait()
+	if err != nil {
+		log.Error("meshkore: gateway failed "+kaomojiNodeFailure, zap.Error(err))
+		return err
+	}
+	log.Info("meshkore: shut down " + kaomojiShutdown)
+	return nil
+}
+
+// watchLifecycle logs the README's state indicators as the gateway's
+// events and link health change, until ctx is done or events is closed.
+func watchLifecycle(ctx context.Context, g *gateway.GatewayService, events <-chan gateway.Event, log *zap.Logger) {
+	ticker := time.NewTicker(linkCheckInterval)
+	defer ticker.Stop()
+
+	links := make(map[string]string) // link name → state at the last check
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			switch data := e.Data.(type) {
+			case *gateway.StatusEvent:
+				if data.State == "configured" {
+					log.Info("meshkore: system ready "+kaomojiReady,
+						zap.String("node", data.MyNodeID),
+						zap.Int("nodes", data.NodeCount),
+						zap.Int("channels", data.Channels))
+				}
+			case *gateway.MessageStatusEvent:
+				if data.Status == store.MessageStatusSent || data.Status == store.MessageStatusRelayed {
+					log.Info("meshkore: message transmitted "+kaomojiTransmission,
+						zap.Int64("id", data.ID),
+						zap.String("mesh_id", data.MeshID),
+						zap.Int("attempts", data.Attempts))
+				}
+			case *gateway.DeliveryEvent:
+				if data.Status == store.MessageStatusFailed {
+					log.Warn("meshkore: delivery failed "+kaomojiNodeFailure,
+						zap.Int64("id", data.ID),
+						zap.String("mesh_id", data.MeshID),
+						zap.String("reason", data.ErrorReason))
+				}
+			case *gateway.ReloadResult:
+				if len(data.RestartRequired) > 0 {
+					keys := make([]string, len(data.RestartRequired))
+					for i, c := range data.RestartRequired {
+						keys[i] = c.Key
+					}
+					log.Warn("meshkore: running config differs from file until restart "+kaomojiConflict,
+						zap.Strings("keys", keys))
+				}
+			}
+
+		case <-ticker.C:
+			current := make(map[string]string)
+			for _, h := range g.Links() {
+				if links[h.Name] == "connected" && h.State != "connected" {
+					log.Warn("meshkore: link lost "+kaomojiNodeFailure,
+						zap.String("link", h.Name),
+						zap.String("state", h.State),
+						zap.String("error", h.LastError))
+				}
+				current[h.Name] = h.State
+			}
+			links = current
+		}
+	}
+}
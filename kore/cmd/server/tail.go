@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/gg-glitch-88/meshigo-kore/ydin/gateway"
+	"github.com/gg-glitch-88/meshigo-kore/ydin/state"
+	"github.com/gg-glitch-88/meshigo-kore/ydin/store"
+)
+
+// runTail implements `meshkore tail`: it follows /api/v1/events, one line
+// per event, until interrupted.
+func runTail(args []string, stdout io.Writer) error {
+	fs, c := newClientFlags("tail", "tail [-server URL] [-json] [-type t,...] [-node id] [-channel n]")
+	types := fs.String("type", "", "only these event types, comma-separated, e.g. message,delivery")
+	node := fs.String("node", "", "only events about this node, e.g. !deadbeef")
+	channel := fs.Int("channel", -1, "only messages on this channel")
+	if err := parseClientFlags(fs, args, 0); err != nil {
+		return err
+	}
+
+	f := eventFilter{channel: *channel}
+	if *types != "" {
+		f.types = strings.Split(*types, ",")
+	}
+	if *node != "" {
+		id, err := parseNodeArg(*node)
+		if err != nil {
+			return fmt.Errorf("tail: %w", err)
+		}
+		f.node = id
+	}
+
+	wsURL := strings.TrimRight(c.server, "/") + "/api/v1/events"
+	if rest, ok := strings.CutPrefix(wsURL, "http"); ok {
+		wsURL = "ws" + rest // http → ws, https → wss
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, wsURL, nil)
+	if err != nil {
+		return fmt.Errorf("tail: %w", err)
+	}
+	defer conn.Close()
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("tail: %w", err)
+		}
+		var e struct {
+			Type      gateway.EventType `json:"type"`
+			Timestamp time.Time         `json:"timestamp"`
+			Data      json.RawMessage   `json:"data"`
+		}
+		if err := json.Unmarshal(data, &e); err != nil {
+			return fmt.Errorf("tail: %w", err)
+		}
+		sum, err := summarize(e.Type, e.Data)
+		if err != nil {
+			return fmt.Errorf("tail: %s event: %w", e.Type, err)
+		}
+		if !f.match(e.Type, sum) {
+			continue
+		}
+		if c.json {
+			fmt.Fprintf(stdout, "%s\n", data)
+			continue
+		}
+		fmt.Fprintf(stdout, "%s  %-15s %s\n", e.Timestamp.Local().Format("15:04:05"), e.Type, sum.text)
+	}
+}
+
+// eventSummary is what tail shows and filters on for one event.
+type eventSummary struct {
+	text    string
+	nodes   []string // "!deadbeef" of every node involved
+	channel int      // -1 unless the event is a message
+}
+
+type eventFilter struct {
+	types   []string
+	node    string
+	channel int
+}
+
+func (f eventFilter) match(t gateway.EventType, s eventSummary) bool {
+	if len(f.types) > 0 && !slices.Contains(f.types, string(t)) {
+		return false
+	}
+	if f.node != "" && !slices.Contains(s.nodes, f.node) {
+		return false
+	}
+	return f.channel < 0 || s.channel == f.channel
+}
+
+// summarize decodes the data of an event of type t into one line of text.
+// Event types this build does not know are shown raw.
+func summarize(t gateway.EventType, data json.RawMessage) (eventSummary, error) {
+	s := eventSummary{channel: -1}
+	var err error
+	switch t {
+	case gateway.EventMessage:
+		var m store.Message
+		if err = json.Unmarshal(data, &m); err == nil {
+			s.nodes = []string{m.FromNode, m.ToNode}
+			s.channel = m.Channel
+			s.text = fmt.Sprintf("%s → %s ch%d: %q", m.FromNode, m.ToNode, m.Channel, m.Payload)
+		}
+	case gateway.EventNodeUpdate:
+		var n state.Node
+		if err = json.Unmarshal(data, &n); err == nil {
+			s.nodes = []string{n.NodeIDHex}
+			s.text = fmt.Sprintf("%s %s (%s) snr %s hops %s", n.NodeIDHex, n.LongName, n.ShortName, formatSNR(n.SNR), formatHops(n.HopsAway))
+		}
+	case gateway.EventPositionUpdate:
+		var p gateway.PositionEvent
+		if err = json.Unmarshal(data, &p); err == nil {
+			s.nodes = []string{p.NodeID}
+			s.text = fmt.Sprintf("%s at %.5f, %.5f, %d m", p.NodeID, p.Lat, p.Lon, p.Alt)
+		}
+	case gateway.EventTelemetry:
+		var te gateway.TelemetryEvent
+		if err = json.Unmarshal(data, &te); err == nil {
+			s.nodes = []string{te.NodeID}
+			s.text = te.NodeID + " " + telemetryText(&te)
+		}
+	case gateway.EventMessageStatus:
+		var ms gateway.MessageStatusEvent
+		if err = json.Unmarshal(data, &ms); err == nil {
+			s.text = fmt.Sprintf("message %d %s (attempt %d)", ms.ID, ms.Status, ms.Attempts)
+			if ms.Error != "" {
+				s.text += ": " + ms.Error
+			}
+		}
+	case gateway.EventDelivery:
+		var d gateway.DeliveryEvent
+		if err = json.Unmarshal(data, &d); err == nil {
+			s.nodes = []string{d.AckFrom}
+			s.text = fmt.Sprintf("message %d %s", d.ID, d.Status)
+			if d.AckFrom != "" {
+				s.text += fmt.Sprintf(" by %s in %dms", d.AckFrom, d.RTTMillis)
+			}
+			if d.ErrorReason != "" {
+				s.text += ": " + d.ErrorReason
+			}
+		}
+	case gateway.EventStatus:
+		var st gateway.StatusEvent
+		if err = json.Unmarshal(data, &st); err == nil {
+			s.nodes = []string{st.MyNodeID}
+			s.text = fmt.Sprintf("device %s, %d nodes, %d channels", st.State, st.NodeCount, st.Channels)
+		}
+	case gateway.EventConfigReload:
+		var r gateway.ReloadResult
+		if err = json.Unmarshal(data, &r); err == nil {
+			s.text = fmt.Sprintf("%d settings applied, %d need a restart", len(r.Applied), len(r.RestartRequired))
+		}
+	default:
+		s.text = string(data)
+	}
+	if err != nil {
+		return s, err
+	}
+	if s.text == "" {
+		return s, errors.New("empty event")
+	}
+	return s, nil
+}
+
+func telemetryText(te *gateway.TelemetryEvent) string {
+	switch {
+	case te.Device != nil:
+		d := te.Device
+		return fmt.Sprintf("battery %s %.2f V, channel util %.1f%%, air util %.1f%%",
+			formatBattery(d.BatteryLevel), d.Voltage, d.ChannelUtil, d.AirUtil)
+	case te.Environment != nil:
+		var parts []string
+		if v := te.Environment.Temperature; v != nil {
+			parts = append(parts, fmt.Sprintf("%.1f °C", *v))
+		}
+		if v := te.Environment.RelativeHumidity; v != nil {
+			parts = append(parts, fmt.Sprintf("%.0f%% RH", *v))
+		}
+		if v := te.Environment.BarometricPressure; v != nil {
+			parts = append(parts, fmt.Sprintf("%.1f hPa", *v))
+		}
+		return "environment " + strings.Join(parts, ", ")
+	case te.Power != nil:
+		p := te.Power
+		return fmt.Sprintf("power ch1 %.2f V %.0f mA", p.Ch1Voltage, p.Ch1Current)
+	}
+	return "telemetry"
+}
@@ -2,13 +2,17 @@ package adapters
 
 import (
 	"context"
-	"errors"
+	"sync"
+
+	"github.com/gg-glitch-88/meshigo-kore/kore/internal/domain"
 )
 
 // InMemoryRepo is a concrete struct.
 // Note: It does NOT say "implements DataProvider".
 // It just *happens* to satisfy the interface. This is Duck Typing.
+// It is safe for concurrent use.
 type InMemoryRepo struct {
+	mu    sync.RWMutex
 	store map[string]string
 }
 
@@ -19,9 +23,24 @@ func NewInMemoryRepo() *InMemoryRepo {
 }
 
 func (r *InMemoryRepo) FetchData(ctx context.Context, id string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	val, ok := r.store[id]
 	if !ok {
-		return "", errors.New("not found")
+		return "", domain.ErrNotFound
 	}
 	return val, nil
-}
\ No newline at end of file
+}
+
+func (r *InMemoryRepo) StoreData(ctx context.Context, id, data string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.store[id] = data
+	return nil
+}
@@ -0,0 +1,17 @@
+package adapters
+
+import (
+	"testing"
+
+	"github.com/gg-glitch-88/meshigo-kore/kore/internal/adapters/providertest"
+	"github.com/gg-glitch-88/meshigo-kore/kore/internal/domain"
+)
+
+func TestInMemoryRepo(t *testing.T) {
+	err := providertest.TestDataStore(func() (domain.DataStore, error) {
+		return NewInMemoryRepo(), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,198 @@
+// Package providertest checks adapters against the contract the domain
+// expects of a domain.DataStore, in the manner of testing/fstest: every
+// adapter runs the same checks, so they stay interchangeable.
+//
+// In a test:
+//
+//	if err := providertest.TestDataStore(newStore); err != nil {
+//		t.Fatal(err)
+//	}
+package providertest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/gg-glitch-88/meshigo-kore/kore/internal/domain"
+)
+
+// concurrentWriters and concurrentWrites size the concurrency check.
+const (
+	concurrentWriters = 8
+	concurrentWrites  = 50
+)
+
+// TestDataStore runs every check against its own store from newStore,
+// which must return a new, empty one each time, and reports every broken
+// expectation.
+func TestDataStore(newStore func() (domain.DataStore, error)) error {
+	var errs []error
+	for _, c := range checks {
+		s, err := newStore()
+		if err != nil {
+			return fmt.Errorf("providertest: new store: %w", err)
+		}
+		if err := c.run(context.Background(), s); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+var checks = []struct {
+	name string
+	run  func(ctx context.Context, s domain.DataStore) error
+}{
+	{"missing id", checkMissing},
+	{"round trip", checkRoundTrip},
+	{"overwrite", checkOverwrite},
+	{"empty data", checkEmpty},
+	{"distinct ids", checkDistinct},
+	{"cancelled context", checkCancelled},
+	{"concurrent use", checkConcurrent},
+	{"logic handler", checkLogic},
+}
+
+func checkMissing(ctx context.Context, s domain.DataStore) error {
+	_, err := s.FetchData(ctx, "!deadbeef")
+	if !errors.Is(err, domain.ErrNotFound) {
+		return fmt.Errorf("FetchData of an unknown id: got error %v, want domain.ErrNotFound", err)
+	}
+	return nil
+}
+
+func checkRoundTrip(ctx context.Context, s domain.DataStore) error {
+	for _, data := range []string{
+		"hello mesh",
+		"₍ᐢ. ̫ .ᐢ₎ ᕙ(`▿´)ᕗ",
+		"two\nlines, 'quotes' and \"double quotes\"",
+	} {
+		if err := s.StoreData(ctx, "!deadbeef", data); err != nil {
+			return fmt.Errorf("StoreData: %w", err)
+		}
+		if err := expect(ctx, s, "!deadbeef", data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkOverwrite(ctx context.Context, s domain.DataStore) error {
+	for _, data := range []string{"first", "second"} {
+		if err := s.StoreData(ctx, "user-123", data); err != nil {
+			return fmt.Errorf("StoreData: %w", err)
+		}
+	}
+	return expect(ctx, s, "user-123", "second")
+}
+
+// checkEmpty makes sure empty data is stored, not taken for "missing".
+func checkEmpty(ctx context.Context, s domain.DataStore) error {
+	if err := s.StoreData(ctx, "user-123", ""); err != nil {
+		return fmt.Errorf("StoreData: %w", err)
+	}
+	return expect(ctx, s, "user-123", "")
+}
+
+func checkDistinct(ctx context.Context, s domain.DataStore) error {
+	want := map[string]string{"a": "lower", "A": "upper", "a ": "trailing space", "": "empty id"}
+	for id, data := range want {
+		if err := s.StoreData(ctx, id, data); err != nil {
+			return fmt.Errorf("StoreData(%q): %w", id, err)
+		}
+	}
+	for id, data := range want {
+		if err := expect(ctx, s, id, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkCancelled makes sure a cancelled context fails both calls with
+// context.Canceled and writes nothing.
+func checkCancelled(ctx context.Context, s domain.DataStore) error {
+	if err := s.StoreData(ctx, "user-123", "before"); err != nil {
+		return fmt.Errorf("StoreData: %w", err)
+	}
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := s.StoreData(cctx, "user-123", "after"); !errors.Is(err, context.Canceled) {
+		return fmt.Errorf("StoreData: got error %v, want context.Canceled", err)
+	}
+	if _, err := s.FetchData(cctx, "user-123"); !errors.Is(err, context.Canceled) {
+		return fmt.Errorf("FetchData: got error %v, want context.Canceled", err)
+	}
+	return expect(ctx, s, "user-123", "before")
+}
+
+func checkConcurrent(ctx context.Context, s domain.DataStore) error {
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for w := 0; w < concurrentWriters; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < concurrentWrites; i++ {
+				id := fmt.Sprintf("w%d-%d", w, i)
+				err := s.StoreData(ctx, id, id)
+				if err == nil {
+					_, err = s.FetchData(ctx, fmt.Sprintf("w%d-%d", (w+1)%concurrentWriters, i))
+					if errors.Is(err, domain.ErrNotFound) {
+						err = nil // not written yet
+					}
+				}
+				if err != nil {
+					mu.Lock()
+					errs = append(errs, err)
+					mu.Unlock()
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	for w := 0; w < concurrentWriters; w++ {
+		for i := 0; i < concurrentWrites; i++ {
+			id := fmt.Sprintf("w%d-%d", w, i)
+			if err := expect(ctx, s, id, id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkLogic runs the domain's LogicHandler over the store.
+func checkLogic(ctx context.Context, s domain.DataStore) error {
+	if err := s.StoreData(ctx, "user-123", "payload"); err != nil {
+		return fmt.Errorf("StoreData: %w", err)
+	}
+	logic := domain.NewLogicHandler(s)
+	if err := logic.Execute(ctx, "user-123"); err != nil {
+		return fmt.Errorf("Execute of a stored id: %w", err)
+	}
+	if err := logic.Execute(ctx, "user-456"); !errors.Is(err, domain.ErrNotFound) {
+		return fmt.Errorf("Execute of an unknown id: got error %v, want domain.ErrNotFound", err)
+	}
+	return nil
+}
+
+func expect(ctx context.Context, s domain.DataStore, id, want string) error {
+	got, err := s.FetchData(ctx, id)
+	if err != nil {
+		return fmt.Errorf("FetchData(%q): %w", id, err)
+	}
+	if got != want {
+		return fmt.Errorf("FetchData(%q) = %q, want %q", id, got, want)
+	}
+	return nil
+}
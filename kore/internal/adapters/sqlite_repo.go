@@ -0,0 +1,41 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gg-glitch-88/meshigo-kore/kore/internal/domain"
+	"github.com/gg-glitch-88/meshigo-kore/ydin/store"
+)
+
+// SQLiteRepo runs the domain over the gateway's database. The data of a
+// node ID ("!deadbeef") is the mesh's record of that node, as a JSON
+// object (see store.DB.NodeData), so domain logic sees what the gateway has
+// heard. StoreData keeps the domain's own records in a table of their own:
+// the mesh tables belong to the gateway and are never written here. A
+// record stored under a node ID takes the place of the node's mesh data.
+// The database must be migrated (store.Migrate) first.
+// It is safe for concurrent use.
+type SQLiteRepo struct {
+	db *store.DB
+}
+
+func NewSQLiteRepo(db *store.DB) *SQLiteRepo {
+	return &SQLiteRepo{db: db}
+}
+
+func (r *SQLiteRepo) FetchData(ctx context.Context, id string) (string, error) {
+	data, err := r.db.GetData(ctx, id)
+	if errors.Is(err, store.ErrDataNotFound) {
+		data, err = r.db.NodeData(ctx, id)
+	}
+	if errors.Is(err, store.ErrDataNotFound) {
+		return "", fmt.Errorf("%w: %q", domain.ErrNotFound, id)
+	}
+	return data, err
+}
+
+func (r *SQLiteRepo) StoreData(ctx context.Context, id, data string) error {
+	return r.db.PutData(ctx, id, data)
+}
@@ -0,0 +1,109 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gg-glitch-88/meshigo-kore/kore/internal/adapters/providertest"
+	"github.com/gg-glitch-88/meshigo-kore/kore/internal/domain"
+	"github.com/gg-glitch-88/meshigo-kore/ydin/state"
+	"github.com/gg-glitch-88/meshigo-kore/ydin/store"
+)
+
+func openTestDB(t *testing.T, name string) *store.DB {
+	t.Helper()
+	db, err := store.Open(filepath.Join(t.TempDir(), name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := store.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestSQLiteRepo(t *testing.T) {
+	n := 0
+	err := providertest.TestDataStore(func() (domain.DataStore, error) {
+		n++
+		return NewSQLiteRepo(openTestDB(t, fmt.Sprintf("repo-%d.db", n))), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSQLiteRepoMeshData(t *testing.T) {
+	db := openTestDB(t, "mesh.db")
+	ctx := context.Background()
+
+	// The gateway hears a node and a message from it.
+	mesh, err := state.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	heard := time.Date(2026, 3, 1, 12, 30, 0, 0, time.UTC)
+	node := state.NewNode(0xdeadbeef)
+	node.LongName, node.ShortName, node.Hardware = "Base camp", "BC", "HELTEC_V3"
+	node.LastSeen, node.BatteryLevel, node.Lat, node.Lon = heard, 87, 60.17, 24.94
+	if err := mesh.UpsertNode(node); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mesh.RecordMessage(&store.Message{
+		MeshID: "1", FromNode: "!deadbeef", ToNode: "broadcast", Port: 1, Payload: []byte("hi"),
+		ReceivedAt: heard, Direction: store.DirectionIn, Status: store.MessageStatusReceived,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewSQLiteRepo(db)
+	data, err := repo.FetchData(ctx, "!deadbeef")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		NodeID       string    `json:"node_id"`
+		LongName     string    `json:"long_name"`
+		Hardware     string    `json:"hardware"`
+		LastSeen     time.Time `json:"last_seen"`
+		HopsAway     int       `json:"hops_away"`
+		ViaMQTT      bool      `json:"via_mqtt"`
+		BatteryLevel uint32    `json:"battery_level"`
+		Lat          float64   `json:"lat"`
+		Lon          float64   `json:"lon"`
+		MessagesSent int       `json:"messages_sent"`
+	}
+	if err := json.Unmarshal([]byte(data), &got); err != nil {
+		t.Fatalf("node data %s: %v", data, err)
+	}
+	if got.NodeID != "!deadbeef" || got.LongName != "Base camp" || got.Hardware != "HELTEC_V3" ||
+		!got.LastSeen.Equal(heard) || got.HopsAway != -1 || got.ViaMQTT || got.BatteryLevel != 87 ||
+		got.Lat != 60.17 || got.Lon != 24.94 || got.MessagesSent != 1 {
+		t.Errorf("node data = %s", data)
+	}
+
+	// Domain logic runs over it.
+	if err := domain.NewLogicHandler(repo).Execute(ctx, "!deadbeef"); err != nil {
+		t.Errorf("Execute: %v", err)
+	}
+	if _, err := repo.FetchData(ctx, "!0000000a"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("FetchData of an unheard node: got error %v, want domain.ErrNotFound", err)
+	}
+
+	// A domain record replaces the mesh data without touching the node.
+	if err := repo.StoreData(ctx, "!deadbeef", "annotated"); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := repo.FetchData(ctx, "!deadbeef"); err != nil || data != "annotated" {
+		t.Errorf("FetchData after StoreData = %q, %v", data, err)
+	}
+	if n, ok := mesh.GetNode(0xdeadbeef); !ok || n.LongName != "Base camp" {
+		t.Errorf("mesh node changed: %+v", n)
+	}
+}
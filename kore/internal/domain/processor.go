@@ -1,6 +1,13 @@
 package domain
 
-import "context"
+import (
+	"context"
+	"errors"
+)
+
+// ErrNotFound is what every adapter returns (wrapped or not) for an id it
+// has no data for. Check it with errors.Is.
+var ErrNotFound = errors.New("not found")
 
 // DataProvider is an interface this logic NEEDS to work.
 // Notice we define it here, not in the database package.
@@ -8,6 +15,13 @@ type DataProvider interface {
 	FetchData(ctx context.Context, id string) (string, error)
 }
 
+// DataStore is a DataProvider the logic can also write through.
+// StoreData replaces whatever is stored under id.
+type DataStore interface {
+	DataProvider
+	StoreData(ctx context.Context, id, data string) error
+}
+
 // LogicHandler holds the dependencies.
 type LogicHandler struct {
 	provider DataProvider // Dependency Injection
@@ -0,0 +1,183 @@
+package gateway
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	meshproto "github.com/gg-glitch-88/meshigo-kore/ydin/proto"
+	"github.com/gg-glitch-88/meshigo-kore/ydin/store"
+	"github.com/gg-glitch-88/meshigo-kore/ydin/transport"
+)
+
+const (
+	ackTimeout        = 30 * time.Second
+	ackMaxRetransmits = 3
+	ackSweepInterval  = time.Second
+)
+
+// DeliveryEvent is the Data of an EventDelivery: the outcome of a want_ack
+// packet, resolved by a ROUTING_APP response or by giving up.
+type DeliveryEvent struct {
+	ID          int64  `json:"id"`
+	MeshID      string `json:"mesh_id"`
+	Status      string `json:"status"`             // "acked" | "failed"
+	AckFrom     string `json:"ack_from,omitempty"` // node that answered
+	Delivered   bool   `json:"delivered"`          // ack came from the destination itself
+	ErrorReason string `json:"error_reason,omitempty"`
+	Retransmits int    `json:"retransmits"`
+	RTTMillis   int64  `json:"rtt_ms"`
+}
+
+// pendingAck is a sent packet awaiting its routing response.
+type pendingAck struct {
+	item        *outboundItem
+	frame       transport.ProtoFrame
+	firstSent   time.Time
+	deadline    time.Time
+	retransmits int
+}
+
+// ackTracker correlates ROUTING_APP responses with packets we sent.
+// It is safe for concurrent use.
+type ackTracker struct {
+	mu      sync.Mutex
+	pending map[uint32]*pendingAck // keyed by packet ID
+}
+
+func newAckTracker() *ackTracker {
+	return &ackTracker{pending: make(map[uint32]*pendingAck)}
+}
+
+// track starts waiting for an acknowledgement of item.
+func (t *ackTracker) track(item *outboundItem, frame transport.ProtoFrame) {
+	now := time.Now()
+	t.mu.Lock()
+	t.pending[item.pkt.ID] = &pendingAck{
+		item:      item,
+		frame:     frame,
+		firstSent: now,
+		deadline:  now.Add(ackTimeout),
+	}
+	t.mu.Unlock()
+}
+
+// take removes and returns the pending entry for packetID, if any.
+func (t *ackTracker) take(packetID uint32) (*pendingAck, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	p, ok := t.pending[packetID]
+	if ok {
+		delete(t.pending, packetID)
+	}
+	return p, ok
+}
+
+// expired removes and returns every entry past its deadline.
+func (t *ackTracker) expired(now time.Time) []*pendingAck {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var out []*pendingAck
+	for id, p := range t.pending {
+		if now.After(p.deadline) {
+			delete(t.pending, id)
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// restore puts p back with a fresh deadline after a retransmit.
+func (t *ackTracker) restore(p *pendingAck, now time.Time) {
+	p.deadline = now.Add(ackTimeout)
+	t.mu.Lock()
+	t.pending[p.item.pkt.ID] = p
+	t.mu.Unlock()
+}
+
+// ── Gateway integration ───────────────────────────────────────────────────
+
+// handleRouting resolves pending packets from ROUTING_APP ACK/NAK responses.
+func (g *GatewayService) handleRouting(pkt *meshproto.MeshPacket, _ time.Time) error {
+	if pkt.RequestID == 0 {
+		return nil // route discovery traffic, not a response to us
+	}
+	r, err := g.protoHandler.DecodeRouting(pkt.Payload)
+	if err != nil {
+		return err
+	}
+	p, ok := g.acks.take(pkt.RequestID)
+	if !ok {
+		return nil // not ours, or already resolved
+	}
+
+	ev := &DeliveryEvent{
+		AckFrom:     nodeHex(pkt.From),
+		Delivered:   pkt.From == p.item.pkt.To,
+		Retransmits: p.retransmits,
+		RTTMillis:   time.Since(p.firstSent).Milliseconds(),
+	}
+	if r.ErrorReason == meshproto.RoutingNone {
+		g.resolve(p, store.MessageStatusAcked, "", ev)
+	} else {
+		g.resolve(p, store.MessageStatusFailed, r.ErrorReason.String(), ev)
+	}
+	return nil
+}
+
+// ackLoop retransmits packets whose acknowledgement is overdue and fails
+// them once ackMaxRetransmits is exhausted.
+func (g *GatewayService) ackLoop(ctx context.Context) {
+	ticker := time.NewTicker(ackSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			for _, p := range g.acks.expired(now) {
+				g.retransmit(p, now)
+			}
+		}
+	}
+}
+
+func (g *GatewayService) retransmit(p *pendingAck, now time.Time) {
+	if p.retransmits >= ackMaxRetransmits {
+		g.resolve(p, store.MessageStatusFailed, "ACK_TIMEOUT", &DeliveryEvent{
+			Retransmits: p.retransmits,
+			RTTMillis:   now.Sub(p.firstSent).Milliseconds(),
+		})
+		return
+	}
+	p.retransmits++
+	if err := g.transport.Send(p.frame); err != nil {
+		// Count the attempt anyway; the next sweep retries or gives up.
+		g.log.Warn("gateway: retransmit failed",
+			zap.String("mesh_id", p.item.msg.MeshID),
+			zap.Int("retransmit", p.retransmits),
+			zap.Error(err))
+	} else {
+		g.log.Debug("gateway: retransmitted",
+			zap.String("mesh_id", p.item.msg.MeshID),
+			zap.Int("retransmit", p.retransmits))
+	}
+	g.acks.restore(p, now)
+}
+
+// resolve records the final delivery state of p and publishes EventDelivery,
+// unless p's message already has an outcome.
+func (g *GatewayService) resolve(p *pendingAck, status, reason string, ev *DeliveryEvent) {
+	if !g.setStatus(p.item, status, reason) {
+		return
+	}
+
+	ev.ID = p.item.msg.ID
+	ev.MeshID = p.item.msg.MeshID
+	ev.Status = status
+	ev.ErrorReason = reason
+	g.eventBus.PublishDelivery(ev)
+}
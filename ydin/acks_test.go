@@ -0,0 +1,72 @@
+package gateway
+
+import (
+	"testing"
+	"time"
+
+	meshproto "github.com/gg-glitch-88/meshigo-kore/ydin/proto"
+	"github.com/gg-glitch-88/meshigo-kore/ydin/store"
+	"github.com/gg-glitch-88/meshigo-kore/ydin/transport"
+)
+
+func TestFinalStatusSticky(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		first string // outcome that raced ahead
+		late  meshproto.RoutingError
+	}{
+		{"ack after failure", store.MessageStatusFailed, meshproto.RoutingNone},
+		{"nak after ack", store.MessageStatusAcked, meshproto.RoutingNoResponse},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			g := newTestGateway(t, &linkStub{})
+			events, unsubscribe := g.eventBus.Subscribe()
+			defer unsubscribe()
+
+			if _, err := g.Enqueue(&store.Message{ToNode: "!0a000002", Payload: []byte("ping")}); err != nil {
+				t.Fatal(err)
+			}
+			item := <-g.outbound
+			g.acks.track(item, transport.ProtoFrame{})
+			p, _ := g.acks.take(item.pkt.ID)
+			g.resolve(p, tc.first, "", &DeliveryEvent{})
+
+			// The routing response for the same packet shows up afterwards,
+			// e.g. one the device relayed while transmit was giving up.
+			g.acks.track(item, transport.ProtoFrame{})
+			payload, err := meshproto.New().EncodeRouting(&meshproto.Routing{ErrorReason: tc.late})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := g.handleRouting(&meshproto.MeshPacket{
+				From: 0x0a000002, RequestID: item.pkt.ID,
+				PortNum: meshproto.PortRouting, Payload: payload,
+			}, time.Now()); err != nil {
+				t.Fatal(err)
+			}
+			// A late "sent" from transmit is ignored too.
+			g.setStatus(item, store.MessageStatusSent, "")
+
+			var deliveries, statuses int
+			for len(events) > 0 {
+				switch e := <-events; e.Type {
+				case EventDelivery:
+					deliveries++
+				case EventMessageStatus:
+					statuses++
+				}
+			}
+			// queued, then the first outcome.
+			if deliveries != 1 || statuses != 2 {
+				t.Errorf("published %d deliveries, %d status changes; want 1, 2", deliveries, statuses)
+			}
+			msgs, err := g.stateStore.RecentMessages(1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(msgs) != 1 || msgs[0].Status != tc.first {
+				t.Errorf("stored = %+v, want status %q", msgs, tc.first)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package gateway
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/gg-glitch-88/meshigo-kore/ydin/api"
+	meshproto "github.com/gg-glitch-88/meshigo-kore/ydin/proto"
+	"github.com/gg-glitch-88/meshigo-kore/ydin/transport"
+)
+
+// SetChannel writes ch into the device's channel table by sending an
+// ADMIN_APP set_channel message to our own node, then mirrors the change in
+// state. The firmware applies it without a reboot.
+func (g *GatewayService) SetChannel(ch meshproto.Channel) error {
+	if ch.Index < 0 || ch.Index > 7 {
+		return fmt.Errorf("gateway: channel index %d out of range 0–7", ch.Index)
+	}
+	if ch.Index == 0 && ch.Role != meshproto.ChannelPrimary {
+		return fmt.Errorf("gateway: channel 0 must stay PRIMARY")
+	}
+	if err := g.sendAdmin(&meshproto.AdminMessage{SetChannel: &ch}); err != nil {
+		return err
+	}
+	g.stateStore.SetChannel(ch)
+	// The device now encrypts with the new PSK and name: pick up the key
+	// here rather than at the next handshake, or relayed traffic on the
+	// channel is undecryptable until then.
+	dev, _ := g.stateStore.Device()
+	g.loadChannelKeys([]meshproto.Channel{ch}, dev.ModemPreset)
+
+	name := ""
+	if ch.Settings != nil {
+		name = ch.Settings.Name
+	}
+	g.log.Info("gateway: channel updated",
+		zap.Int32("index", ch.Index),
+		zap.String("name", name),
+		zap.Stringer("role", ch.Role))
+	return nil
+}
+
+// sendAdmin delivers an admin message to the locally attached device.
+func (g *GatewayService) sendAdmin(msg *meshproto.AdminMessage) error {
+	me := g.stateStore.MyNodeNum()
+	if me == 0 {
+		return fmt.Errorf("gateway: %w: not configured yet", api.ErrDeviceUnavailable)
+	}
+	if g.transport.GetConnectionState() != transport.StateConnected {
+		return fmt.Errorf("gateway: %w: transport not connected", api.ErrDeviceUnavailable)
+	}
+	payload, err := g.protoHandler.EncodeAdmin(msg)
+	if err != nil {
+		return err
+	}
+	data, err := g.protoHandler.EncodeToRadio(&meshproto.ToRadio{Packet: &meshproto.MeshPacket{
+		ID:           newPacketID(),
+		To:           me,
+		PortNum:      meshproto.PortAdmin,
+		Payload:      payload,
+		WantResponse: true,
+	}})
+	if err != nil {
+		return err
+	}
+	if err := g.transport.Send(transport.ProtoFrame{Data: data, Timestamp: time.Now().UTC()}); err != nil {
+		return fmt.Errorf("gateway: send admin message: %w: %w", api.ErrDeviceUnavailable, err)
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package gateway
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gg-glitch-88/meshigo-kore/ydin/api"
+	meshproto "github.com/gg-glitch-88/meshigo-kore/ydin/proto"
+	"github.com/gg-glitch-88/meshigo-kore/ydin/state"
+)
+
+func TestSetChannelDeviceUnavailable(t *testing.T) {
+	s := &linkStub{}
+	g := newTestGateway(t, s)
+	ch := meshproto.Channel{Index: 1, Role: meshproto.ChannelSecondary}
+
+	// Before the handshake, then while the link is down.
+	if err := g.SetChannel(ch); !errors.Is(err, api.ErrDeviceUnavailable) {
+		t.Errorf("not configured: got %v, want ErrDeviceUnavailable", err)
+	}
+	g.stateStore.SetDevice(state.DeviceInfo{MyNodeNum: 0x0a000001})
+	if err := g.SetChannel(ch); !errors.Is(err, api.ErrDeviceUnavailable) {
+		t.Errorf("not connected: got %v, want ErrDeviceUnavailable", err)
+	}
+}
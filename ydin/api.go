@@ -3,9 +3,15 @@
 // Routes (spec-exact):
 //   GET  /api/v1/nodes              — List all known nodes
 //   GET  /api/v1/nodes/:id          — Single node detail
-//   GET  /api/v1/messages           — Message history (paginated)
+//   GET  /api/v1/nodes/:id/positions — Position track (time range, downsampled)
+//   GET  /api/v1/nodes/:id/telemetry — Telemetry series (metric, range, step)
+//   GET  /api/v1/messages           — Message history (filtered, paginated)
+//   GET  /api/v1/messages/:id       — Single message
 //   POST /api/v1/messages           — Send new message
 //   GET  /api/v1/channels           — Channel list
+//   PUT  /api/v1/channels/:index    — Add / rename / re-key a channel (admin)
+//   DELETE /api/v1/channels/:index  — Disable a channel (admin)
+//   POST /api/v1/admin/reload       — Re-read the configuration (admin)
 //   GET  /api/v1/status             — Gateway health
 //   POST /api/v1/checkin            — User check-in
 //   GET  /api/v1/library/search     — Search library
@@ -16,16 +22,26 @@
 package api
 
 import (
+	"bufio"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
+	"net"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
 	"go.uber.org/zap"
 
+	meshproto "github.com/gg-glitch-88/meshigo-kore/ydin/proto"
 	"github.com/gg-glitch-88/meshigo-kore/ydin/state"
 	"github.com/gg-glitch-88/meshigo-kore/ydin/store"
 )
@@ -49,37 +65,100 @@ var wsUpgrader = websocket.Upgrader{
 // SubscribeFunc is the adapter the API uses to subscribe to any event bus.
 type SubscribeFunc func() (<-chan interface{}, func())
 
-// Server holds handler dependencies.
+// SendFunc hands an outbound message to the gateway's transmit queue.
+// It persists msg and returns a copy of it as queued, with its row ID,
+// mesh ID and status filled in; msg itself belongs to the queue from then
+// on. A full queue is reported as an error wrapping ErrOutboxFull.
+type SendFunc func(msg *store.Message) (store.Message, error)
+
+// ErrOutboxFull means the transmit queue cannot take another message right
+// now; the API answers 503 so that the client retries.
+var ErrOutboxFull = errors.New("outbox full")
+
+// SetChannelFunc writes one channel slot to the attached device. A device
+// that cannot take it right now is reported as an error wrapping
+// ErrDeviceUnavailable.
+type SetChannelFunc func(ch meshproto.Channel) error
+
+// ErrDeviceUnavailable means the attached device is not connected or not
+// configured yet; the API answers 503 so that the client retries.
+var ErrDeviceUnavailable = errors.New("device unavailable")
+
+// StatusFunc reports the gateway's health for GET /api/v1/status; the
+// fields it returns are added to the response.
+type StatusFunc func() map[string]interface{}
+
+// ReloadFunc re-reads the configuration and applies it, returning a
+// JSON-serialisable report of what changed.
+type ReloadFunc func() (interface{}, error)
+
+// Server holds handler dependencies. It is the http.Handler for every
+// route.
 type Server struct {
-	db          *store.DB
-	stateMgr    *state.Manager
-	subscribeFn func() (<-chan interface{}, func())
-	log         *zap.Logger
+	db           *store.DB
+	stateMgr     *state.Manager
+	subscribeFn  func() (<-chan interface{}, func())
+	sendFn       SendFunc
+	setChannelFn SetChannelFunc
+	statusFn     StatusFunc
+	reloadFn     ReloadFunc
+	adminToken   atomic.Value // string
+	limiter      *rateLimiter
+	handler      http.Handler
+	log          *zap.Logger
 }
 
-// NewRouter wires all /api/v1/* routes and returns a http.Handler.
+// NewRouter wires all /api/v1/* routes and returns the Server.
 // subFn is called for each new WebSocket client; it must return a channel
-// of JSON-serialisable events and an unsubscribe function.
+// of JSON-serialisable events and an unsubscribe function. sendFn queues
+// messages posted to /api/v1/messages for transmission. Admin routes
+// require "Authorization: Bearer <adminToken>" and are refused outright
+// when adminToken is empty. Requests are not rate limited until
+// SetRateLimit is called.
 func NewRouter(
 	db *store.DB,
 	stateMgr *state.Manager,
 	subFn func() (<-chan interface{}, func()),
+	sendFn SendFunc,
+	setChannelFn SetChannelFunc,
+	statusFn StatusFunc,
+	reloadFn ReloadFunc,
+	adminToken string,
 	log *zap.Logger,
-) http.Handler {
-	s := &Server{db: db, stateMgr: stateMgr, subscribeFn: subFn, log: log}
+) *Server {
+	s := &Server{
+		db:           db,
+		stateMgr:     stateMgr,
+		subscribeFn:  subFn,
+		sendFn:       sendFn,
+		setChannelFn: setChannelFn,
+		statusFn:     statusFn,
+		reloadFn:     reloadFn,
+		limiter:      newRateLimiter(),
+		log:          log,
+	}
+	s.SetAdminToken(adminToken)
 
 	mux := http.NewServeMux()
 
 	// Nodes
 	mux.HandleFunc("GET /api/v1/nodes", s.listNodes)
 	mux.HandleFunc("GET /api/v1/nodes/{id}", s.getNode)
+	mux.HandleFunc("GET /api/v1/nodes/{id}/positions", s.nodePositions)
+	mux.HandleFunc("GET /api/v1/nodes/{id}/telemetry", s.nodeTelemetry)
 
 	// Messages
 	mux.HandleFunc("GET /api/v1/messages", s.listMessages)
+	mux.HandleFunc("GET /api/v1/messages/{id}", s.getMessage)
 	mux.HandleFunc("POST /api/v1/messages", s.sendMessage)
 
 	// Channels
 	mux.HandleFunc("GET /api/v1/channels", s.listChannels)
+	mux.Handle("PUT /api/v1/channels/{index}", s.requireAdmin(s.updateChannel))
+	mux.Handle("DELETE /api/v1/channels/{index}", s.requireAdmin(s.disableChannel))
+
+	// Admin
+	mux.Handle("POST /api/v1/admin/reload", s.requireAdmin(s.reload))
 
 	// Status / health
 	mux.HandleFunc("GET /api/v1/status", s.status)
@@ -94,7 +173,24 @@ func NewRouter(
 	// WebSocket event stream
 	mux.HandleFunc("GET /api/v1/events", s.eventStream)
 
-	return withLogging(log, mux)
+	s.handler = withLogging(log, s.withRateLimit(mux))
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.handler.ServeHTTP(w, r)
+}
+
+// SetAdminToken replaces the admin bearer token; "" disables the admin
+// routes.
+func (s *Server) SetAdminToken(token string) {
+	s.adminToken.Store(token)
+}
+
+// SetRateLimit limits each client IP to rate requests per second, with
+// bursts of up to burst; rate 0 lifts the limit.
+func (s *Server) SetRateLimit(rate float64, burst int) {
+	s.limiter.set(rate, burst)
 }
 
 // ── Nodes ─────────────────────────────────────────────────────────────────
@@ -108,18 +204,10 @@ func (s *Server) listNodes(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) getNode(w http.ResponseWriter, r *http.Request) {
-	idStr := r.PathValue("id")
-	// Accept both decimal and "!hex" formats.
-	var nodeID uint32
-	if strings.HasPrefix(idStr, "!") {
-		fmt.Sscanf(idStr, "!%x", &nodeID) //nolint:errcheck
-	} else {
-		n, err := strconv.ParseUint(idStr, 10, 32)
-		if err != nil {
-			http.Error(w, "invalid node id", http.StatusBadRequest)
-			return
-		}
-		nodeID = uint32(n)
+	nodeID, err := parseNodeID(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid node id", http.StatusBadRequest)
+		return
 	}
 
 	node, ok := s.stateMgr.GetNode(nodeID)
@@ -130,24 +218,232 @@ func (s *Server) getNode(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, node)
 }
 
+// nodePositions returns a node's track. from and to bound the range, as for
+// nodeTelemetry (RFC 3339 or Unix seconds; default the last 24 hours), and
+// max_points caps the number of points, thinning long ranges evenly over
+// time.
+func (s *Server) nodePositions(w http.ResponseWriter, r *http.Request) {
+	nodeID, err := parseNodeID(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid node id", http.StatusBadRequest)
+		return
+	}
+	to, err := queryTime(r, "to", time.Time{})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	from, err := queryTime(r, "from", time.Now().Add(-24*time.Hour))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !to.IsZero() && !from.Before(to) {
+		http.Error(w, "from must be before to", http.StatusBadRequest)
+		return
+	}
+	maxPoints, err := queryInt(r, "max_points", 1000, 1, 10000)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	track, err := s.stateMgr.Track(nodeID, from, to, maxPoints)
+	if err != nil {
+		s.log.Error("api: node positions", zap.Error(err))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"node_id":   fmt.Sprintf("!%08x", nodeID),
+		"positions": track,
+		"count":     len(track),
+	})
+}
+
+// Telemetry series limits: an omitted step aims at telemetryAutoPoints
+// points, and no query may ask for more than telemetryMaxPoints.
+const (
+	telemetryAutoPoints = 500
+	telemetryMaxPoints  = 10000
+)
+
+// nodeTelemetry returns one telemetry metric of a node as a series. from and
+// to bound the range (RFC 3339 or Unix seconds; default the last 24 hours).
+// step is "raw" for individual samples or a duration such as "5m"; when
+// omitted it is chosen to give about telemetryAutoPoints points. Raw series
+// are cut to their latest telemetryMaxPoints samples.
+func (s *Server) nodeTelemetry(w http.ResponseWriter, r *http.Request) {
+	nodeID, err := parseNodeID(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid node id", http.StatusBadRequest)
+		return
+	}
+	metric := r.URL.Query().Get("metric")
+	if !slices.Contains(store.TelemetryMetrics, metric) {
+		http.Error(w, "metric must be one of "+strings.Join(store.TelemetryMetrics, ", "), http.StatusBadRequest)
+		return
+	}
+	now := time.Now().UTC()
+	to, err := queryTime(r, "to", now)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	from, err := queryTime(r, "from", to.Add(-24*time.Hour))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !from.Before(to) {
+		http.Error(w, "from must be before to", http.StatusBadRequest)
+		return
+	}
+
+	var step time.Duration
+	switch v := r.URL.Query().Get("step"); v {
+	case "raw":
+	case "":
+		step = autoStep(to.Sub(from))
+	default:
+		step, err = time.ParseDuration(v)
+		if err != nil || step < time.Second {
+			http.Error(w, `step must be "raw" or a duration of at least 1s`, http.StatusBadRequest)
+			return
+		}
+		if to.Sub(from)/step > telemetryMaxPoints {
+			http.Error(w, fmt.Sprintf("step too small: at most %d points per query", telemetryMaxPoints), http.StatusBadRequest)
+			return
+		}
+	}
+
+	points, err := s.stateMgr.TelemetrySeries(nodeID, metric, from, to, step, telemetryMaxPoints)
+	if err != nil {
+		s.log.Error("api: node telemetry", zap.Error(err))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	stepName := "raw"
+	if step > 0 {
+		stepName = step.String()
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"node_id": fmt.Sprintf("!%08x", nodeID),
+		"metric":  metric,
+		"from":    from,
+		"to":      to,
+		"step":    stepName,
+		"points":  points,
+		"count":   len(points),
+	})
+}
+
+// autoStep picks a step giving about telemetryAutoPoints points over span,
+// rounded up to whole minutes or hours so the rollups can serve it. Short
+// spans get raw samples.
+func autoStep(span time.Duration) time.Duration {
+	step := span / telemetryAutoPoints
+	switch {
+	case step < time.Minute:
+		return 0
+	case step < time.Hour:
+		return (step + time.Minute - 1).Truncate(time.Minute)
+	default:
+		return (step + time.Hour - 1).Truncate(time.Hour)
+	}
+}
+
 // ── Messages ──────────────────────────────────────────────────────────────
 
+// listMessages returns messages newest first, one page at a time. channel,
+// node (sender or recipient), direction, port, since and until filter them;
+// cursor continues from a previous page's next_cursor.
 func (s *Server) listMessages(w http.ResponseWriter, r *http.Request) {
-	limit, err := queryInt(r, "limit", 50, 1, 500)
+	q, err := messageQuery(r)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	msgs, err := s.db.ListMessages(limit)
+	msgs, next, err := s.db.ListMessages(q)
 	if err != nil {
 		s.log.Error("api: list messages", zap.Error(err))
 		http.Error(w, "internal error", http.StatusInternalServerError)
 		return
 	}
-	writeJSON(w, http.StatusOK, map[string]interface{}{
+	resp := map[string]interface{}{
 		"messages": msgs,
 		"count":    len(msgs),
-	})
+	}
+	if next != 0 {
+		resp["next_cursor"] = strconv.FormatInt(next, 10)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// messageQuery parses the listMessages query parameters.
+func messageQuery(r *http.Request) (store.MessageQuery, error) {
+	var (
+		q   store.MessageQuery
+		err error
+	)
+	if q.Limit, err = queryInt(r, "limit", 50, 1, 500); err != nil {
+		return q, err
+	}
+	if s := r.URL.Query().Get("cursor"); s != "" {
+		if q.Before, err = strconv.ParseInt(s, 10, 64); err != nil || q.Before <= 0 {
+			return q, fmt.Errorf("invalid cursor")
+		}
+	}
+	if r.URL.Query().Has("channel") {
+		ch, err := queryInt(r, "channel", 0, 0, 7)
+		if err != nil {
+			return q, err
+		}
+		q.Channel = &ch
+	}
+	if s := r.URL.Query().Get("node"); s != "" {
+		nodeID, err := parseNodeID(s)
+		if err != nil {
+			return q, fmt.Errorf("invalid node")
+		}
+		q.Node = fmt.Sprintf("!%08x", nodeID)
+	}
+	switch q.Direction = r.URL.Query().Get("direction"); q.Direction {
+	case "", store.DirectionIn, store.DirectionOut:
+	default:
+		return q, fmt.Errorf("direction must be %q or %q", store.DirectionIn, store.DirectionOut)
+	}
+	port, err := queryInt(r, "port", 0, 1, 511)
+	if err != nil {
+		return q, err
+	}
+	q.Port = uint32(port)
+	if q.Since, err = queryTime(r, "since", time.Time{}); err != nil {
+		return q, err
+	}
+	if q.Until, err = queryTime(r, "until", time.Time{}); err != nil {
+		return q, err
+	}
+	return q, nil
+}
+
+func (s *Server) getMessage(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid message id", http.StatusBadRequest)
+		return
+	}
+	msg, err := s.db.GetMessage(id)
+	if errors.Is(err, store.ErrMessageNotFound) {
+		http.Error(w, "message not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.log.Error("api: get message", zap.Error(err))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, msg)
 }
 
 type sendMessageRequest struct {
@@ -166,53 +462,286 @@ func (s *Server) sendMessage(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "text must not be empty", http.StatusBadRequest)
 		return
 	}
-	toNode := req.ToNode
-	if toNode == "" {
-		toNode = "broadcast"
+	if len(req.Text) > meshproto.MaxPayloadSize {
+		http.Error(w, fmt.Sprintf("text must be at most %d bytes", meshproto.MaxPayloadSize), http.StatusBadRequest)
+		return
+	}
+	toNode := "broadcast"
+	if req.ToNode != "" && req.ToNode != "broadcast" {
+		nodeID, err := parseNodeID(req.ToNode)
+		if err != nil {
+			http.Error(w, "invalid to_node", http.StatusBadRequest)
+			return
+		}
+		toNode = fmt.Sprintf("!%08x", nodeID)
+	}
+	if req.Channel < 0 || req.Channel > 7 {
+		http.Error(w, "channel must be 0–7", http.StatusBadRequest)
+		return
 	}
 	msg := &store.Message{
-		MeshID:     fmt.Sprintf("api-%d", time.Now().UnixNano()),
 		FromNode:   "gateway",
 		ToNode:     toNode,
 		Channel:    req.Channel,
 		Payload:    []byte(req.Text),
 		ReceivedAt: time.Now().UTC(),
 	}
-	id, err := s.db.InsertMessage(msg)
+	queued, err := s.sendFn(msg)
+	if errors.Is(err, ErrOutboxFull) {
+		w.Header().Set("Retry-After", "5")
+		http.Error(w, "outbox full, retry later", http.StatusServiceUnavailable)
+		return
+	}
 	if err != nil {
 		s.log.Error("api: send message", zap.Error(err))
 		http.Error(w, "internal error", http.StatusInternalServerError)
 		return
 	}
-	writeJSON(w, http.StatusCreated, map[string]interface{}{"id": id, "status": "queued"})
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"id":      queued.ID,
+		"mesh_id": queued.MeshID,
+		"status":  queued.Status,
+	})
 }
 
 // ── Channels ──────────────────────────────────────────────────────────────
 
 type channel struct {
-	Index int    `json:"index"`
-	Name  string `json:"name"`
-	Role  string `json:"role"` // "PRIMARY" | "SECONDARY" | "DISABLED"
+	Index             int32  `json:"index"`
+	Name              string `json:"name"`
+	Role              string `json:"role"`     // "PRIMARY" | "SECONDARY" | "DISABLED"
+	PSK               string `json:"psk_type"` // "none" | "default" | "simple" | "aes128" | "aes256"
+	Encrypted         bool   `json:"encrypted"`
+	UplinkEnabled     bool   `json:"uplink_enabled"`
+	DownlinkEnabled   bool   `json:"downlink_enabled"`
+	PositionPrecision uint32 `json:"position_precision"`
+	ClientMuted       bool   `json:"client_muted"`
+}
+
+// channelView renders a device channel without exposing its key.
+func (s *Server) channelView(ch meshproto.Channel) channel {
+	out := channel{Index: ch.Index, Role: ch.Role.String(), PSK: "none"}
+	st := ch.Settings
+	if st == nil {
+		return out
+	}
+	out.Name = st.Name
+	if out.Name == "" && ch.Role == meshproto.ChannelPrimary {
+		dev, _ := s.stateMgr.Device()
+		out.Name = meshproto.ModemPresetName(dev.ModemPreset)
+	}
+	switch {
+	case len(st.PSK) == 0 || (len(st.PSK) == 1 && st.PSK[0] == 0):
+		out.PSK = "none"
+	case len(st.PSK) == 1 && st.PSK[0] == 1:
+		out.PSK = "default"
+	case len(st.PSK) == 1:
+		out.PSK = "simple"
+	case len(st.PSK) == 16:
+		out.PSK = "aes128"
+	default:
+		out.PSK = "aes256"
+	}
+	out.Encrypted = out.PSK != "none"
+	out.UplinkEnabled = st.UplinkEnabled
+	out.DownlinkEnabled = st.DownlinkEnabled
+	out.PositionPrecision = st.PositionPrecision
+	out.ClientMuted = st.IsClientMuted
+	return out
 }
 
 func (s *Server) listChannels(w http.ResponseWriter, r *http.Request) {
-	// Static channel list; a future version reads from device config.
-	channels := []channel{
-		{Index: 0, Name: "LongFast", Role: "PRIMARY"},
-		{Index: 1, Name: "Admin", Role: "SECONDARY"},
+	chs := s.stateMgr.Channels()
+	channels := make([]channel, 0, len(chs))
+	for _, ch := range chs {
+		channels = append(channels, s.channelView(ch))
+	}
+	_, configured := s.stateMgr.Device()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"channels":   channels,
+		"configured": configured,
+	})
+}
+
+type updateChannelRequest struct {
+	Name            *string `json:"name"`
+	Role            *string `json:"role"`
+	PSK             *string `json:"psk"` // "none" | "default" | "random" | base64 key
+	UplinkEnabled   *bool   `json:"uplink_enabled"`
+	DownlinkEnabled *bool   `json:"downlink_enabled"`
+}
+
+// updateChannel adds, renames, re-keys or re-roles one channel slot.
+// Fields omitted from the body keep their current value.
+func (s *Server) updateChannel(w http.ResponseWriter, r *http.Request) {
+	index, err := strconv.Atoi(r.PathValue("index"))
+	if err != nil || index < 0 || index > 7 {
+		http.Error(w, "index must be 0–7", http.StatusBadRequest)
+		return
+	}
+	var req updateChannelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON body", http.StatusBadRequest)
+		return
+	}
+
+	ch, ok := s.stateMgr.Channel(int32(index))
+	if !ok || ch.Role == meshproto.ChannelDisabled {
+		// Adding a channel: start from a fresh secondary with the default key.
+		ch = meshproto.Channel{Index: int32(index), Role: meshproto.ChannelSecondary}
+		if index == 0 {
+			ch.Role = meshproto.ChannelPrimary
+		}
+		ch.Settings = &meshproto.ChannelSettings{PSK: []byte{1}}
+	}
+	settings := meshproto.ChannelSettings{}
+	if ch.Settings != nil {
+		settings = *ch.Settings
+	}
+	ch.Settings = &settings
+
+	if req.Name != nil {
+		if len(*req.Name) > 11 {
+			http.Error(w, "name must be at most 11 bytes", http.StatusBadRequest)
+			return
+		}
+		settings.Name = *req.Name
+	}
+	if req.Role != nil {
+		role, ok := parseChannelRole(*req.Role)
+		if !ok {
+			http.Error(w, "role must be PRIMARY, SECONDARY or DISABLED", http.StatusBadRequest)
+			return
+		}
+		// The firmware needs exactly one primary channel, in slot 0.
+		switch {
+		case index == 0 && role != meshproto.ChannelPrimary:
+			http.Error(w, "channel 0 must stay PRIMARY", http.StatusBadRequest)
+			return
+		case index != 0 && role == meshproto.ChannelPrimary:
+			http.Error(w, "only channel 0 can be PRIMARY", http.StatusBadRequest)
+			return
+		}
+		ch.Role = role
+	}
+	if req.PSK != nil {
+		psk, err := parsePSK(*req.PSK)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		settings.PSK = psk
+	}
+	if req.UplinkEnabled != nil {
+		settings.UplinkEnabled = *req.UplinkEnabled
+	}
+	if req.DownlinkEnabled != nil {
+		settings.DownlinkEnabled = *req.DownlinkEnabled
+	}
+
+	s.applyChannel(w, ch)
+}
+
+// disableChannel turns a secondary channel slot off.
+func (s *Server) disableChannel(w http.ResponseWriter, r *http.Request) {
+	index, err := strconv.Atoi(r.PathValue("index"))
+	if err != nil || index < 1 || index > 7 {
+		http.Error(w, "index must be 1–7 (the primary channel cannot be disabled)", http.StatusBadRequest)
+		return
+	}
+	s.applyChannel(w, meshproto.Channel{Index: int32(index), Role: meshproto.ChannelDisabled})
+}
+
+func (s *Server) applyChannel(w http.ResponseWriter, ch meshproto.Channel) {
+	err := s.setChannelFn(ch)
+	if errors.Is(err, ErrDeviceUnavailable) {
+		w.Header().Set("Retry-After", "5")
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil {
+		s.log.Warn("api: set channel", zap.Int32("index", ch.Index), zap.Error(err))
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.channelView(ch))
+}
+
+func parseChannelRole(s string) (meshproto.ChannelRole, bool) {
+	switch strings.ToUpper(s) {
+	case "PRIMARY":
+		return meshproto.ChannelPrimary, true
+	case "SECONDARY":
+		return meshproto.ChannelSecondary, true
+	case "DISABLED":
+		return meshproto.ChannelDisabled, true
 	}
-	writeJSON(w, http.StatusOK, map[string]interface{}{"channels": channels})
+	return 0, false
+}
+
+// parsePSK accepts "none", "default", "random" (a fresh AES-256 key) or a
+// base64-encoded key of 1, 16 or 32 bytes.
+func parsePSK(s string) ([]byte, error) {
+	switch s {
+	case "none":
+		return []byte{0}, nil
+	case "default":
+		return []byte{1}, nil
+	case "random":
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+		return key, nil
+	}
+	key, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("psk must be none, default, random or base64")
+	}
+	switch len(key) {
+	case 1, 16, 32:
+		return key, nil
+	}
+	return nil, fmt.Errorf("psk must decode to 1, 16 or 32 bytes")
+}
+
+// ── Admin ─────────────────────────────────────────────────────────────────
+
+// reload re-reads the configuration and reports which changes were applied
+// and which wait for a restart. An invalid configuration changes nothing.
+func (s *Server) reload(w http.ResponseWriter, r *http.Request) {
+	result, err := s.reloadFn()
+	if err != nil {
+		s.log.Warn("api: reload", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
 }
 
 // ── Status ────────────────────────────────────────────────────────────────
 
+// status reports the gateway's health: the attached device once it is
+// configured, plus whatever statusFn adds, such as link health.
 func (s *Server) status(w http.ResponseWriter, r *http.Request) {
-	writeJSON(w, http.StatusOK, map[string]interface{}{
-		"status":      "ok",
-		"time":        time.Now().UTC().Format(time.RFC3339),
-		"node_count":  s.stateMgr.NodeCount(),
-		"subscribers": 0, // TODO: expose EventBus.Len() via interface
-	})
+	resp := map[string]interface{}{
+		"status":     "ok",
+		"time":       time.Now().UTC().Format(time.RFC3339),
+		"node_count": s.stateMgr.NodeCount(),
+	}
+	if dev, ok := s.stateMgr.Device(); ok {
+		resp["device"] = map[string]interface{}{
+			"node_id":       fmt.Sprintf("!%08x", dev.MyNodeNum),
+			"firmware":      dev.FirmwareVersion,
+			"hardware":      dev.HardwareModel,
+			"role":          dev.Role,
+			"configured_at": dev.ConfiguredAt.UTC().Format(time.RFC3339),
+		}
+	}
+	if s.statusFn != nil {
+		maps.Copy(resp, s.statusFn())
+	}
+	writeJSON(w, http.StatusOK, resp)
 }
 
 // ── Check-in ──────────────────────────────────────────────────────────────
@@ -311,6 +840,24 @@ func withLogging(log *zap.Logger, next http.Handler) http.Handler {
 	})
 }
 
+// requireAdmin guards h with the admin bearer token.
+func (s *Server) requireAdmin(h http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := s.adminToken.Load().(string)
+		if token == "" {
+			http.Error(w, "admin API disabled", http.StatusForbidden)
+			return
+		}
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="meshcommons"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		h(w, r)
+	})
+}
+
 type responseWriter struct {
 	http.ResponseWriter
 	code int
@@ -321,6 +868,14 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
+// Hijack lets WebSocket upgrades through the logging middleware.
+func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	rw.code = http.StatusSwitchingProtocols
+	return http.NewResponseController(rw.ResponseWriter).Hijack()
+}
+
+func (rw *responseWriter) Unwrap() http.ResponseWriter { return rw.ResponseWriter }
+
 // ── helpers ───────────────────────────────────────────────────────────────
 
 func writeJSON(w http.ResponseWriter, code int, v interface{}) {
@@ -329,6 +884,32 @@ func writeJSON(w http.ResponseWriter, code int, v interface{}) {
 	json.NewEncoder(w).Encode(v) //nolint:errcheck
 }
 
+// parseNodeID accepts both decimal and "!hex" node ID formats.
+func parseNodeID(s string) (uint32, error) {
+	if strings.HasPrefix(s, "!") {
+		n, err := strconv.ParseUint(s[1:], 16, 32)
+		return uint32(n), err
+	}
+	n, err := strconv.ParseUint(s, 10, 32)
+	return uint32(n), err
+}
+
+// queryTime parses an RFC 3339 timestamp or Unix seconds.
+func queryTime(r *http.Request, key string, def time.Time) (time.Time, error) {
+	s := r.URL.Query().Get(key)
+	if s == "" {
+		return def, nil
+	}
+	if secs, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(secs, 0).UTC(), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be RFC 3339 or Unix seconds", key)
+	}
+	return t, nil
+}
+
 func queryInt(r *http.Request, key string, def, min, max int) (int, error) {
 	s := r.URL.Query().Get(key)
 	if s == "" {
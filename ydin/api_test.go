@@ -0,0 +1,186 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	meshproto "github.com/gg-glitch-88/meshigo-kore/ydin/proto"
+	"github.com/gg-glitch-88/meshigo-kore/ydin/state"
+	"github.com/gg-glitch-88/meshigo-kore/ydin/store"
+)
+
+const testAdminToken = "s3cret"
+
+// newTestServer builds a Server over a fresh database in the test's temp
+// dir. setChannelFn may be nil when the test does not write channels.
+func newTestServer(t *testing.T, setChannelFn SetChannelFunc) (*Server, *state.Manager) {
+	t.Helper()
+	db, err := store.Open(filepath.Join(t.TempDir(), "api.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := store.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	mgr, err := state.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewRouter(db, mgr, nil, nil, setChannelFn, nil, nil, testAdminToken, zap.NewNop()), mgr
+}
+
+// do serves one request carrying the admin token.
+func do(s *Server, method, target, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+testAdminToken)
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestUpdateChannelRoles(t *testing.T) {
+	var written []meshproto.Channel
+	s, mgr := newTestServer(t, func(ch meshproto.Channel) error {
+		written = append(written, ch)
+		return nil
+	})
+	mgr.SetChannels([]meshproto.Channel{
+		{Index: 0, Role: meshproto.ChannelPrimary, Settings: &meshproto.ChannelSettings{PSK: []byte{1}}},
+		{Index: 1, Role: meshproto.ChannelSecondary, Settings: &meshproto.ChannelSettings{Name: "ops", PSK: []byte{1}}},
+	})
+
+	for _, tc := range []struct {
+		index int
+		body  string
+		code  int
+	}{
+		{0, `{"role":"DISABLED"}`, http.StatusBadRequest},
+		{0, `{"role":"SECONDARY"}`, http.StatusBadRequest},
+		{0, `{"role":"primary","name":"main"}`, http.StatusOK},
+		{1, `{"role":"PRIMARY"}`, http.StatusBadRequest},
+		{2, `{"role":"PRIMARY"}`, http.StatusBadRequest},
+		{1, `{"role":"DISABLED"}`, http.StatusOK},
+		{2, `{"name":"new"}`, http.StatusOK},
+		{3, `{"role":"SECONDARY"}`, http.StatusOK},
+		{0, `{"role":"ADMIN"}`, http.StatusBadRequest},
+	} {
+		rec := do(s, http.MethodPut, fmt.Sprintf("/api/v1/channels/%d", tc.index), tc.body)
+		if rec.Code != tc.code {
+			t.Errorf("PUT channel %d %s: %d %q, want %d", tc.index, tc.body, rec.Code, rec.Body, tc.code)
+		}
+	}
+
+	want := []meshproto.ChannelRole{
+		meshproto.ChannelPrimary, meshproto.ChannelDisabled,
+		meshproto.ChannelSecondary, meshproto.ChannelSecondary,
+	}
+	if len(written) != len(want) {
+		t.Fatalf("wrote %d channels, want %d: %+v", len(written), len(want), written)
+	}
+	for i, ch := range written {
+		if ch.Role != want[i] {
+			t.Errorf("write %d: channel %d role %s, want %s", i, ch.Index, ch.Role, want[i])
+		}
+	}
+}
+
+func TestUpdateChannelErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		err   error
+		code  int
+		retry bool
+	}{
+		{"device unavailable", fmt.Errorf("gateway: %w: transport not connected", ErrDeviceUnavailable), http.StatusServiceUnavailable, true},
+		{"rejected", fmt.Errorf("gateway: channel 0 must stay PRIMARY"), http.StatusConflict, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s, _ := newTestServer(t, func(meshproto.Channel) error { return tc.err })
+			for _, req := range []struct{ method, target string }{
+				{http.MethodPut, "/api/v1/channels/1"},
+				{http.MethodDelete, "/api/v1/channels/1"},
+			} {
+				rec := do(s, req.method, req.target, `{"name":"ops"}`)
+				if rec.Code != tc.code {
+					t.Errorf("%s %s: %d, want %d", req.method, req.target, rec.Code, tc.code)
+				}
+				if got := rec.Header().Get("Retry-After") != ""; got != tc.retry {
+					t.Errorf("%s %s: Retry-After set = %v, want %v", req.method, req.target, got, tc.retry)
+				}
+			}
+		})
+	}
+}
+
+func TestNodePositions(t *testing.T) {
+	s, mgr := newTestServer(t, nil)
+
+	// A report every ten minutes over the last two days, the newest a
+	// minute ago.
+	now := time.Now().UTC().Truncate(time.Second)
+	for i := 0; i < 6*48; i++ {
+		ts := now.Add(-time.Minute - time.Duration(i)*10*time.Minute)
+		if err := mgr.UpdatePosition(0x0a000002, &store.Position{Time: ts, Lat: 60.17, Lon: 24.94, ReceivedAt: ts}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	unix := func(d time.Duration) string { return fmt.Sprint(now.Add(d).Unix()) }
+
+	for _, tc := range []struct {
+		query string
+		code  int
+		count int
+	}{
+		{"", http.StatusOK, 6 * 24},                             // the last 24 hours
+		{"?from=" + unix(-72*time.Hour), http.StatusOK, 6 * 48}, // open end
+		{"?from=" + unix(-time.Hour) + "&to=" + unix(-30*time.Minute), http.StatusOK, 3},
+		{"?from=" + now.Add(-time.Hour).Format(time.RFC3339), http.StatusOK, 6},
+		{"?from=" + unix(-72*time.Hour) + "&max_points=10", http.StatusOK, 10},
+		{"?max_points=1", http.StatusOK, 1},
+		{"?from=" + unix(-time.Hour) + "&to=" + unix(-time.Hour), http.StatusBadRequest, 0},
+		{"?from=" + unix(-time.Hour) + "&to=" + unix(-2*time.Hour), http.StatusBadRequest, 0},
+		{"?from=yesterday", http.StatusBadRequest, 0},
+		{"?to=soon", http.StatusBadRequest, 0},
+		{"?max_points=0", http.StatusBadRequest, 0},
+		{"?max_points=10001", http.StatusBadRequest, 0},
+		{"?max_points=many", http.StatusBadRequest, 0},
+	} {
+		rec := do(s, http.MethodGet, "/api/v1/nodes/!0a000002/positions"+tc.query, "")
+		if rec.Code != tc.code {
+			t.Errorf("%q: %d %q, want %d", tc.query, rec.Code, rec.Body, tc.code)
+			continue
+		}
+		if tc.code != http.StatusOK {
+			continue
+		}
+		var body struct {
+			NodeID    string            `json:"node_id"`
+			Positions []*store.Position `json:"positions"`
+			Count     int               `json:"count"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if body.NodeID != "!0a000002" || body.Count != tc.count || len(body.Positions) != tc.count {
+			t.Errorf("%q: node %s, count %d, %d positions; want %d", tc.query, body.NodeID, body.Count, len(body.Positions), tc.count)
+		}
+	}
+
+	if rec := do(s, http.MethodGet, "/api/v1/nodes/!zz/positions", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid node id: %d, want 400", rec.Code)
+	}
+	// An unknown node has an empty track.
+	rec := do(s, http.MethodGet, "/api/v1/nodes/!0a000009/positions", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"positions":[]`) {
+		t.Errorf("unknown node: %d %s", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,271 @@
+package gateway
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	meshproto "github.com/gg-glitch-88/meshigo-kore/ydin/proto"
+	"github.com/gg-glitch-88/meshigo-kore/ydin/state"
+	"github.com/gg-glitch-88/meshigo-kore/ydin/transport"
+)
+
+const (
+	linkPollInterval = 500 * time.Millisecond
+	configTimeout    = 30 * time.Second
+)
+
+// StatusEvent is the Data of an EventStatus.
+type StatusEvent struct {
+	State     string `json:"state"` // "configuring" | "configured"
+	MyNodeID  string `json:"my_node_id,omitempty"`
+	NodeCount int    `json:"node_count"`
+	Channels  int    `json:"channels"`
+}
+
+// bootstrap tracks one want_config handshake. The device answers with
+// my_info, metadata, its node DB, channels and config sections, then a
+// config_complete_id echoing our request.
+type bootstrap struct {
+	mu        sync.Mutex
+	configID  uint32 // 0 when no handshake is in flight
+	requested time.Time
+	device    state.DeviceInfo
+	channels  []meshproto.Channel
+	nodes     int
+}
+
+// linkWatchLoop runs the want_config handshake every time the link that
+// answers it (re)connects, and repeats it if the device does not complete
+// it in time. If that link drops while another is up, the handshake moves
+// to the other link.
+func (g *GatewayService) linkWatchLoop(ctx context.Context) {
+	ticker := time.NewTicker(linkPollInterval)
+	defer ticker.Stop()
+
+	var (
+		prev         = transport.StateDisconnected
+		prevConnects uint64
+	)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			cur, connects := g.configLink()
+			up := g.transport.GetConnectionState() == transport.StateConnected
+			if up && (cur != transport.StateConnected || prev != transport.StateConnected ||
+				connects != prevConnects || g.configOverdue(now)) {
+				g.requestConfig()
+				cur, connects = g.configLink()
+			}
+			prev, prevConnects = cur, connects
+		}
+	}
+}
+
+// configLink returns the state and connect count of the link the
+// handshake runs over: the whole transport unless it is a ConfigLinker.
+func (g *GatewayService) configLink() (transport.ConnectionState, uint64) {
+	if cl, ok := g.transport.(transport.ConfigLinker); ok {
+		return cl.ConfigLink()
+	}
+	return g.transport.GetConnectionState(), transport.Connects(g.transport)
+}
+
+// requestConfig starts a new handshake, abandoning any in flight.
+func (g *GatewayService) requestConfig() {
+	id := newPacketID()
+
+	g.boot.mu.Lock()
+	g.boot.configID = id
+	g.boot.requested = time.Now()
+	g.boot.device = state.DeviceInfo{}
+	g.boot.channels = nil
+	g.boot.nodes = 0
+	g.boot.mu.Unlock()
+
+	data, err := g.protoHandler.EncodeToRadio(&meshproto.ToRadio{WantConfigID: id})
+	if err == nil {
+		err = g.transport.Send(transport.ProtoFrame{Data: data, Timestamp: time.Now().UTC()})
+	}
+	if err != nil {
+		g.log.Warn("gateway: want_config", zap.Error(err))
+		return
+	}
+	g.log.Info("gateway: requested device config", zap.Uint32("config_id", id))
+	g.eventBus.Publish(Event{Type: EventStatus, Data: &StatusEvent{
+		State:     "configuring",
+		NodeCount: g.stateStore.NodeCount(),
+	}})
+}
+
+func (g *GatewayService) configOverdue(now time.Time) bool {
+	g.boot.mu.Lock()
+	defer g.boot.mu.Unlock()
+	return g.boot.configID != 0 && now.Sub(g.boot.requested) > configTimeout
+}
+
+// handleConfigFrame consumes the non-packet FromRadio variants produced by
+// the handshake. Nodes are applied to state as they arrive; device info and
+// channels are committed together once config_complete_id is seen.
+func (g *GatewayService) handleConfigFrame(fr *meshproto.FromRadio, rxTime time.Time) {
+	switch {
+	case fr.MyInfo != nil:
+		g.boot.mu.Lock()
+		g.boot.device.MyNodeNum = fr.MyInfo.MyNodeNum
+		g.boot.mu.Unlock()
+
+	case fr.Metadata != nil:
+		g.boot.mu.Lock()
+		g.boot.device.FirmwareVersion = fr.Metadata.FirmwareVersion
+		g.boot.device.HardwareModel = fr.Metadata.HardwareModel
+		g.boot.device.Role = fr.Metadata.Role
+		g.boot.mu.Unlock()
+
+	case fr.Config != nil:
+		g.boot.mu.Lock()
+		if d := fr.Config.Device; d != nil {
+			g.boot.device.Role = d.Role
+		}
+		if l := fr.Config.LoRa; l != nil {
+			g.boot.device.Region = l.Region
+			g.boot.device.ModemPreset = l.ModemPreset
+			g.boot.device.HopLimit = l.HopLimit
+		}
+		g.boot.mu.Unlock()
+
+	case fr.Channel != nil:
+		g.boot.mu.Lock()
+		g.boot.channels = append(g.boot.channels, *fr.Channel)
+		g.boot.mu.Unlock()
+
+	case fr.NodeInfo != nil:
+		if err := g.applyNodeInfo(fr.NodeInfo); err != nil {
+			g.log.Warn("gateway: node DB entry",
+				zap.String("node", nodeHex(fr.NodeInfo.NodeID)), zap.Error(err))
+			return
+		}
+		g.boot.mu.Lock()
+		g.boot.nodes++
+		g.boot.mu.Unlock()
+
+	case fr.ConfigCompleteID != 0:
+		g.completeConfig(fr.ConfigCompleteID, rxTime)
+
+	case fr.Rebooted:
+		g.log.Info("gateway: device rebooted – re-requesting config")
+		g.requestConfig()
+	}
+}
+
+// completeConfig commits the handshake if id matches the one in flight.
+func (g *GatewayService) completeConfig(id uint32, rxTime time.Time) {
+	g.boot.mu.Lock()
+	if id != g.boot.configID {
+		g.boot.mu.Unlock()
+		g.log.Debug("gateway: stale config_complete_id", zap.Uint32("config_id", id))
+		return
+	}
+	g.boot.configID = 0
+	dev := g.boot.device
+	dev.ConfiguredAt = rxTime
+	channels := g.boot.channels
+	nodes := g.boot.nodes
+	g.boot.mu.Unlock()
+
+	g.stateStore.SetDevice(dev)
+	g.stateStore.SetChannels(channels)
+	g.loadChannelKeys(channels, dev.ModemPreset)
+	if err := g.applyDeviceNode(dev); err != nil {
+		g.log.Warn("gateway: store own node", zap.Error(err))
+	}
+
+	g.log.Info("gateway: device configured",
+		zap.String("my_node", nodeHex(dev.MyNodeNum)),
+		zap.String("firmware", dev.FirmwareVersion),
+		zap.Int("nodes", nodes),
+		zap.Int("channels", len(channels)))
+	g.eventBus.Publish(Event{Type: EventStatus, Data: &StatusEvent{
+		State:     "configured",
+		MyNodeID:  nodeHex(dev.MyNodeNum),
+		NodeCount: g.stateStore.NodeCount(),
+		Channels:  len(channels),
+	}})
+}
+
+// applyNodeInfo merges a node DB entry from the device into state.
+func (g *GatewayService) applyNodeInfo(info *meshproto.NodeInfo) error {
+	n, ok := g.stateStore.GetNode(info.NodeID)
+	if !ok {
+		n = state.NewNode(info.NodeID)
+	}
+	if info.LongName != "" {
+		n.LongName = info.LongName
+		n.ShortName = info.ShortName
+		n.Hardware = info.HardwareModel
+		n.Role = info.Role
+		n.IsLicensed = info.IsLicensed
+	}
+	if len(info.PublicKey) > 0 {
+		n.PublicKey = info.PublicKey
+	}
+	if p := info.Position; p != nil && (p.LatitudeI != 0 || p.LongitudeI != 0) {
+		n.Lat, n.Lon, n.Alt = p.Lat(), p.Lon(), p.Altitude
+	}
+	if d := info.DeviceMetrics; d != nil {
+		n.BatteryLevel = d.BatteryLevel
+		n.Voltage = d.Voltage
+		n.ChannelUtil = d.ChannelUtil
+	}
+	if info.LastHeard != 0 {
+		heard := time.Unix(int64(info.LastHeard), 0).UTC()
+		if heard.After(n.LastSeen) {
+			n.LastSeen = heard
+			n.SNR = info.SNR
+			n.HopsAway = int(info.HopsAway)
+			n.ViaMQTT = info.ViaMQTT
+		}
+	}
+	return g.stateStore.UpsertNode(n)
+}
+
+// applyDeviceNode records what only the device knows about itself.
+func (g *GatewayService) applyDeviceNode(dev state.DeviceInfo) error {
+	if dev.MyNodeNum == 0 {
+		return nil
+	}
+	n, ok := g.stateStore.GetNode(dev.MyNodeNum)
+	if !ok {
+		n = state.NewNode(dev.MyNodeNum)
+	}
+	n.FirmwareVersion = dev.FirmwareVersion
+	if n.Hardware == "" {
+		n.Hardware = dev.HardwareModel
+	}
+	if n.Role == "" {
+		n.Role = dev.Role
+	}
+	n.HopsAway = 0
+	return g.stateStore.UpsertNode(n)
+}
+
+// loadChannelKeys adds the device's enabled channels to the keyring so that
+// packets relayed to us still encrypted (e.g. via MQTT) can be read.
+func (g *GatewayService) loadChannelKeys(channels []meshproto.Channel, preset uint32) {
+	for _, ch := range channels {
+		if ch.Role == meshproto.ChannelDisabled || ch.Settings == nil {
+			continue
+		}
+		name := ch.Settings.Name
+		if name == "" {
+			name = meshproto.ModemPresetName(preset)
+		}
+		if _, err := g.keys.Add(name, ch.Settings.PSK); err != nil {
+			g.log.Warn("gateway: channel key",
+				zap.Int32("index", ch.Index), zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package gateway
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/gg-glitch-88/meshigo-kore/ydin/config"
+	meshproto "github.com/gg-glitch-88/meshigo-kore/ydin/proto"
+	"github.com/gg-glitch-88/meshigo-kore/ydin/store"
+	"github.com/gg-glitch-88/meshigo-kore/ydin/transport"
+)
+
+// newTestGateway builds a GatewayService over tr with a fresh database in
+// the test's temp dir, serving its API on a free local port. Nothing is
+// started.
+func newTestGateway(t *testing.T, tr transport.TransportManager) *GatewayService {
+	t.Helper()
+	db, err := store.Open(filepath.Join(t.TempDir(), "gateway.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := store.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.Default()
+	cfg.Gateway.ListenAddr = "127.0.0.1:0"
+	g, err := NewWithTransport(cfg, db, tr, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+// linkStub is a transport whose overall and config-link state the test
+// sets directly, as a MultiTransport would report them. It records the
+// want_config IDs sent to it.
+type linkStub struct {
+	overall  atomic.Int32 // transport.ConnectionState
+	link     atomic.Int32 // config link's transport.ConnectionState
+	connects atomic.Uint64
+
+	mu      sync.Mutex
+	configs []uint32
+}
+
+func (s *linkStub) Connect() error    { return nil }
+func (s *linkStub) Disconnect() error { return nil }
+func (s *linkStub) Send(f transport.ProtoFrame) error {
+	tr, err := meshproto.New().DecodeToRadio(f.Data)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if tr.WantConfigID != 0 {
+		s.configs = append(s.configs, tr.WantConfigID)
+		// As MultiTransport does, the handshake moves to a connected link.
+		s.link.Store(int32(transport.StateConnected))
+	}
+	return nil
+}
+func (s *linkStub) Receive() <-chan transport.ProtoFrame { return nil }
+func (s *linkStub) GetConnectionState() transport.ConnectionState {
+	return transport.ConnectionState(s.overall.Load())
+}
+func (s *linkStub) ConfigLink() (transport.ConnectionState, uint64) {
+	return transport.ConnectionState(s.link.Load()), s.connects.Load()
+}
+
+func (s *linkStub) handshakes() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.configs)
+}
+
+// waitHandshakes waits for the count to reach want, then checks that it
+// stays there for another poll.
+func waitHandshakes(t *testing.T, s *linkStub, want int) {
+	t.Helper()
+	deadline := time.Now().Add(3 * linkPollInterval)
+	for s.handshakes() < want && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(linkPollInterval + 100*time.Millisecond)
+	if got := s.handshakes(); got != want {
+		t.Fatalf("handshakes = %d, want %d", got, want)
+	}
+}
+
+func TestLinkWatchLoop(t *testing.T) {
+	s := &linkStub{}
+	g := newTestGateway(t, s)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go g.linkWatchLoop(ctx)
+
+	// First connect.
+	s.overall.Store(int32(transport.StateConnected))
+	s.link.Store(int32(transport.StateConnected))
+	s.connects.Store(1)
+	waitHandshakes(t, s, 1)
+
+	// The config link reconnects between two polls while another link
+	// keeps the overall state connected: only the count moves.
+	s.connects.Store(2)
+	waitHandshakes(t, s, 2)
+
+	// The config link drops and stays down; the handshake fails over.
+	s.link.Store(int32(transport.StateConnecting))
+	waitHandshakes(t, s, 3)
+
+	// Everything down: nothing to handshake with.
+	s.overall.Store(int32(transport.StateDisconnected))
+	s.link.Store(int32(transport.StateDisconnected))
+	waitHandshakes(t, s, 3)
+}
@@ -0,0 +1,133 @@
+package transport
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Capture files hold raw ProtoFrames, one JSON object per line:
+//
+//	{"time":"2026-05-01T12:00:00.123Z","dir":"in","transport":"heltec","data":"CgQ..."}
+//
+// data is the frame payload (FromRadio for "in", ToRadio for "out") in
+// base64, without the stream header. The format is line-oriented so that
+// captures can be trimmed, grepped and concatenated with standard tools.
+
+// Capture directions.
+const (
+	CaptureIn  = "in"  // device → gateway
+	CaptureOut = "out" // gateway → device
+)
+
+// CaptureRecord is one line of a capture file.
+type CaptureRecord struct {
+	Time      time.Time `json:"time"`
+	Direction string    `json:"dir"`
+	Transport string    `json:"transport"`
+	Data      []byte    `json:"data"`
+}
+
+// CaptureWriter appends CaptureRecords to a file. It is safe for concurrent
+// use; each record is written with a single Write so that a crash loses at
+// most the record in flight.
+type CaptureWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+	c  io.Closer
+}
+
+// NewCaptureWriter writes records to w.
+func NewCaptureWriter(w io.Writer) *CaptureWriter {
+	cw := &CaptureWriter{w: w}
+	if c, ok := w.(io.Closer); ok {
+		cw.c = c
+	}
+	return cw
+}
+
+// OpenCapture opens path for appending, creating it if needed.
+func OpenCapture(path string) (*CaptureWriter, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o640)
+	if err != nil {
+		return nil, fmt.Errorf("capture: open %s: %w", path, err)
+	}
+	return NewCaptureWriter(f), nil
+}
+
+// Record writes one frame.
+func (cw *CaptureWriter) Record(dir, transport string, frame ProtoFrame) error {
+	ts := frame.Timestamp
+	if ts.IsZero() {
+		ts = time.Now().UTC()
+	}
+	line, err := json.Marshal(CaptureRecord{
+		Time:      ts,
+		Direction: dir,
+		Transport: transport,
+		Data:      frame.Data,
+	})
+	if err != nil {
+		return fmt.Errorf("capture: encode: %w", err)
+	}
+	line = append(line, '\n')
+
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+	if cw.w == nil {
+		return fmt.Errorf("capture: closed")
+	}
+	if _, err := cw.w.Write(line); err != nil {
+		return fmt.Errorf("capture: write: %w", err)
+	}
+	return nil
+}
+
+// Close closes the underlying file, if any. Later Records fail.
+func (cw *CaptureWriter) Close() error {
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+	cw.w = nil
+	if cw.c == nil {
+		return nil
+	}
+	return cw.c.Close()
+}
+
+// CaptureReader reads CaptureRecords back in file order.
+type CaptureReader struct {
+	sc   *bufio.Scanner
+	line int
+}
+
+// NewCaptureReader reads records from r.
+func NewCaptureReader(r io.Reader) *CaptureReader {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 4096), 64*1024)
+	return &CaptureReader{sc: sc}
+}
+
+// Next returns the next record, or io.EOF at the end of the capture. Blank
+// lines are skipped.
+func (cr *CaptureReader) Next() (CaptureRecord, error) {
+	for cr.sc.Scan() {
+		cr.line++
+		b := cr.sc.Bytes()
+		if len(b) == 0 {
+			continue
+		}
+		var rec CaptureRecord
+		if err := json.Unmarshal(b, &rec); err != nil {
+			return rec, fmt.Errorf("capture: line %d: %w", cr.line, err)
+		}
+		return rec, nil
+	}
+	if err := cr.sc.Err(); err != nil {
+		return CaptureRecord{}, fmt.Errorf("capture: read: %w", err)
+	}
+	return CaptureRecord{}, io.EOF
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Change is one setting that differs between two configurations. Old and
+// New are formatted as in -set values, with secrets redacted; a setting
+// missing on one side, such as a field of a removed link, is "".
+type Change struct {
+	Key string `json:"key"`
+	Old string `json:"old"`
+	New string `json:"new"`
+}
+
+func (c Change) String() string { return fmt.Sprintf("%s: %q → %q", c.Key, c.Old, c.New) }
+
+// Diff lists the settings that differ between old and new, in the order
+// they appear in Config; settings only new has, such as the fields of an
+// added link, come last.
+func Diff(old, new *Config) []Change {
+	rawOld, rawNew := flatten(old), flatten(new)
+	shownOld, shownNew := flatten(old.Redacted()), flatten(new.Redacted())
+
+	var (
+		out  []Change
+		seen = make(map[string]bool)
+	)
+	add := func(key string) {
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		if rawOld.get(key) != rawNew.get(key) {
+			out = append(out, Change{Key: key, Old: shownOld.get(key), New: shownNew.get(key)})
+		}
+	}
+	for _, s := range rawOld {
+		add(s.key)
+	}
+	for _, s := range rawNew {
+		add(s.key)
+	}
+	return out
+}
+
+// ── internal ──────────────────────────────────────────────────────────────
+
+type setting struct{ key, value string }
+
+type settings []setting
+
+func (ss settings) get(key string) string {
+	for _, s := range ss {
+		if s.key == key {
+			return s.value
+		}
+	}
+	return ""
+}
+
+// flatten lists every setting of c under its dotted key.
+func flatten(c *Config) settings {
+	var out settings
+	flattenValue(reflect.ValueOf(c).Elem(), "", &out)
+	return out
+}
+
+func flattenValue(v reflect.Value, prefix string, out *settings) {
+	switch {
+	case v.Kind() == reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+			key := name
+			if prefix != "" {
+				key = prefix + "." + name
+			}
+			flattenValue(v.Field(i), key, out)
+		}
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Struct:
+		for i := 0; i < v.Len(); i++ {
+			flattenValue(v.Index(i), fmt.Sprintf("%s.%d", prefix, i), out)
+		}
+	default:
+		*out = append(*out, setting{prefix, formatValue(v)})
+	}
+}
+
+// formatValue renders a leaf setting the way Set parses it.
+func formatValue(v reflect.Value) string {
+	if v.Type() == durationType {
+		return time.Duration(v.Int()).String()
+	}
+	if v.Kind() == reflect.Slice {
+		parts := make([]string, v.Len())
+		for i := range parts {
+			parts[i] = formatValue(v.Index(i))
+		}
+		return strings.Join(parts, ",")
+	}
+	return fmt.Sprint(v.Interface())
+}
@@ -0,0 +1,251 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix starts every environment variable the config reads.
+const EnvPrefix = "MESHKORE_"
+
+// envConfigFile names the config file when no -config flag is given.
+const envConfigFile = EnvPrefix + "CONFIG"
+
+// Options select the config file and the command-line overrides.
+type Options struct {
+	Path string   // config file; "" = $MESHKORE_CONFIG, or defaults only
+	Set  []string // "key=value" overrides, applied in order
+}
+
+// Register binds o to -config and the repeatable -set flag.
+func (o *Options) Register(fs *flag.FlagSet) {
+	fs.StringVar(&o.Path, "config", o.Path, "config file (.toml, .yaml or .yml)")
+	fs.Func("set", "override a setting, e.g. -set gateway.listen_addr=:9090 (repeatable)", func(kv string) error {
+		if !strings.Contains(kv, "=") {
+			return fmt.Errorf("want key=value, got %q", kv)
+		}
+		o.Set = append(o.Set, kv)
+		return nil
+	})
+}
+
+// Load builds the effective configuration: defaults, then the config file,
+// then MESHKORE_* variables from environ (as from os.Environ), then
+// opts.Set. The result is validated.
+func Load(opts Options, environ []string) (*Config, error) {
+	cfg := Default()
+
+	path := opts.Path
+	if path == "" {
+		path = lookupEnv(environ, envConfigFile)
+	}
+	if path != "" {
+		if err := cfg.LoadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.ApplyEnv(environ); err != nil {
+		return nil, err
+	}
+	for _, kv := range opts.Set {
+		key, value, _ := strings.Cut(kv, "=")
+		if err := cfg.Set(key, value); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("config: invalid settings:\n%w", err)
+	}
+	return cfg, nil
+}
+
+// LoadFile reads the TOML (.toml) or YAML (.yaml, .yml) file at path over
+// c. Settings the file omits keep their current value; a list in the file
+// replaces the whole list. Unknown keys are an error.
+func (c *Config) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	defer f.Close()
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".toml":
+		md, err := toml.NewDecoder(f).Decode(c)
+		if err != nil {
+			return fmt.Errorf("config: %s: %w", path, err)
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("config: %s: unknown setting %s", path, undecoded[0])
+		}
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(f)
+		dec.KnownFields(true)
+		if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("config: %s: %w", path, err)
+		}
+	default:
+		return fmt.Errorf("config: %s: unknown format %q (want .toml, .yaml or .yml)", path, ext)
+	}
+	return nil
+}
+
+// ApplyEnv applies every MESHKORE_* variable in environ as an override of
+// the setting it names: MESHKORE_GATEWAY_LISTEN_ADDR sets
+// gateway.listen_addr. An unknown variable is an error, so that a typo does
+// not go unnoticed.
+func (c *Config) ApplyEnv(environ []string) error {
+	for _, kv := range environ {
+		name, value, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(name, EnvPrefix) || name == envConfigFile {
+			continue
+		}
+		key := strings.ToLower(strings.TrimPrefix(name, EnvPrefix))
+		if err := set(reflect.ValueOf(c).Elem(), key, '_', value); err != nil {
+			return fmt.Errorf("config: %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Set overrides the setting with dotted key, parsing value as its type.
+// Durations use time.ParseDuration syntax and lists are comma-separated.
+// Setting the element just past the end of a list appends one.
+func (c *Config) Set(key, value string) error {
+	if err := set(reflect.ValueOf(c).Elem(), key, '.', value); err != nil {
+		return fmt.Errorf("config: %s: %w", key, err)
+	}
+	return nil
+}
+
+// Write prints c as YAML, with secrets redacted.
+func (c *Config) Write(w io.Writer) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(c.Redacted()); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	return enc.Close()
+}
+
+// ── internal ──────────────────────────────────────────────────────────────
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// set assigns value to the setting named by key below v, whose path
+// elements are separated by sep. Keys are matched against yaml tags; the
+// longest matching one wins, since env keys use '_' both between and
+// within names.
+func set(v reflect.Value, key string, sep byte, value string) error {
+	switch {
+	case v.Kind() == reflect.Struct:
+		var (
+			field reflect.Value
+			name  string
+		)
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			n, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+			if len(n) > len(name) && (key == n || strings.HasPrefix(key, n+string(sep))) {
+				field, name = v.Field(i), n
+			}
+		}
+		if name == "" {
+			return errors.New("unknown setting")
+		}
+		if key == name {
+			return setLeaf(field, value)
+		}
+		return set(field, key[len(name)+1:], sep, value)
+
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Struct:
+		idx, rest, ok := strings.Cut(key, string(sep))
+		i, err := strconv.Atoi(idx)
+		if !ok || err != nil || i < 0 {
+			return errors.New("unknown setting")
+		}
+		switch {
+		case i == v.Len():
+			v.Set(reflect.Append(v, reflect.New(v.Type().Elem()).Elem()))
+		case i > v.Len():
+			return fmt.Errorf("index %d is past the end of the list (length %d)", i, v.Len())
+		}
+		return set(v.Index(i), rest, sep, value)
+	}
+	return errors.New("unknown setting")
+}
+
+// setLeaf parses value into the scalar or scalar list v.
+func setLeaf(v reflect.Value, value string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", value)
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		v.SetInt(n)
+	case reflect.Uint32:
+		n, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		v.SetUint(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		var parts []string
+		if value = strings.TrimSpace(value); value != "" {
+			parts = strings.Split(value, ",")
+		}
+		list := reflect.MakeSlice(v.Type(), len(parts), len(parts))
+		for i, p := range parts {
+			if err := setLeaf(list.Index(i), strings.TrimSpace(p)); err != nil {
+				return err
+			}
+		}
+		v.Set(list)
+	default:
+		return errors.New("not a single setting")
+	}
+	return nil
+}
+
+func lookupEnv(environ []string, name string) string {
+	for _, kv := range environ {
+		if k, v, ok := strings.Cut(kv, "="); ok && k == name {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,209 @@
+package proto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"sync"
+)
+
+// ── Channel encryption ────────────────────────────────────────────────────
+//
+// Packets that leave a radio (over LoRa, MQTT or a promiscuous receiver)
+// carry the Data submessage encrypted with the channel PSK:
+//
+//	AES-CTR, key = expanded PSK (128 or 256 bit)
+//	nonce = packet ID (uint64 LE) ‖ sender node number (uint32 LE) ‖ 0u32
+//
+// MeshPacket.Channel then holds an 8-bit channel hash instead of an index.
+// crypto_test.go checks these against reference values from firmware 2.x.
+
+// DefaultPSK is the well-known key the firmware uses for PSK "AQ==" (0x01).
+var DefaultPSK = []byte{
+	0xd4, 0xf1, 0xbb, 0x3a, 0x20, 0x29, 0x07, 0x59,
+	0xf0, 0xbc, 0xff, 0xab, 0xcf, 0x4e, 0x69, 0x01,
+}
+
+// ExpandPSK turns a channel PSK as stored in ChannelSettings into an AES key.
+// An empty or 0x00 PSK means "no encryption" and yields a nil key. A single
+// byte n selects the default key with its last byte bumped by n-1. Other
+// short keys are zero-padded to AES-128 or AES-256 length.
+func ExpandPSK(psk []byte) ([]byte, error) {
+	switch {
+	case len(psk) == 0 || (len(psk) == 1 && psk[0] == 0):
+		return nil, nil
+	case len(psk) == 1:
+		key := append([]byte(nil), DefaultPSK...)
+		key[len(key)-1] += psk[0] - 1
+		return key, nil
+	case len(psk) <= 16:
+		key := make([]byte, 16)
+		copy(key, psk)
+		return key, nil
+	case len(psk) <= 32:
+		key := make([]byte, 32)
+		copy(key, psk)
+		return key, nil
+	default:
+		return nil, fmt.Errorf("proto: PSK too long (%d bytes)", len(psk))
+	}
+}
+
+// ChannelHash computes the 8-bit hash the firmware puts in
+// MeshPacket.Channel: XOR of the channel name bytes, XOR'd with the XOR of
+// the expanded key bytes.
+func ChannelHash(name string, key []byte) uint8 {
+	var h uint8
+	for i := 0; i < len(name); i++ {
+		h ^= name[i]
+	}
+	for _, b := range key {
+		h ^= b
+	}
+	return h
+}
+
+// ChannelKey is one channel's expanded key and hash.
+type ChannelKey struct {
+	Name string
+	Key  []byte // nil for unencrypted channels
+	Hash uint8
+}
+
+// Keyring holds the channel keys used to decrypt and encrypt packets seen
+// off-device. It is safe for concurrent use.
+type Keyring struct {
+	mu   sync.RWMutex
+	keys []ChannelKey
+}
+
+// NewKeyring returns an empty Keyring.
+func NewKeyring() *Keyring {
+	return &Keyring{}
+}
+
+// Add registers a channel by name and PSK, replacing any channel with the
+// same name. For an unnamed primary channel pass the modem preset name
+// (see ModemPresetName), as the firmware does.
+func (k *Keyring) Add(name string, psk []byte) (ChannelKey, error) {
+	key, err := ExpandPSK(psk)
+	if err != nil {
+		return ChannelKey{}, err
+	}
+	ck := ChannelKey{Name: name, Key: key, Hash: ChannelHash(name, key)}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	for i := range k.keys {
+		if k.keys[i].Name == name {
+			k.keys[i] = ck
+			return ck, nil
+		}
+	}
+	k.keys = append(k.keys, ck)
+	return ck, nil
+}
+
+// AddBase64 is Add for a base64 PSK such as "AQ==" from the Meshtastic apps.
+func (k *Keyring) AddBase64(name, psk string) (ChannelKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(psk)
+	if err != nil {
+		return ChannelKey{}, fmt.Errorf("proto: PSK for %q: %w", name, err)
+	}
+	return k.Add(name, raw)
+}
+
+// Lookup returns the key registered under name.
+func (k *Keyring) Lookup(name string) (ChannelKey, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	for _, ck := range k.keys {
+		if ck.Name == name {
+			return ck, true
+		}
+	}
+	return ChannelKey{}, false
+}
+
+// Decrypt decrypts p.Encrypted in place, filling in the Data fields and
+// clearing Encrypted. Every key whose hash matches p.Channel is tried; a key
+// is accepted when the plaintext parses as a Data message with a port set.
+// It returns the channel name that worked.
+func (k *Keyring) Decrypt(p *MeshPacket) (string, error) {
+	if p.Encrypted == nil {
+		return "", fmt.Errorf("proto: packet %d is not encrypted", p.ID)
+	}
+
+	k.mu.RLock()
+	candidates := make([]ChannelKey, 0, 1)
+	for _, ck := range k.keys {
+		if uint32(ck.Hash) == p.Channel {
+			candidates = append(candidates, ck)
+		}
+	}
+	k.mu.RUnlock()
+
+	for _, ck := range candidates {
+		plain, err := ctrXOR(ck.Key, p.ID, p.From, p.Encrypted)
+		if err != nil {
+			return "", err
+		}
+		var d MeshPacket
+		if err := d.unmarshalData(plain); err != nil || d.PortNum == PortUnknown {
+			continue
+		}
+		p.PortNum = d.PortNum
+		p.Payload = d.Payload
+		p.WantResponse = d.WantResponse
+		p.Dest = d.Dest
+		p.Source = d.Source
+		p.RequestID = d.RequestID
+		p.ReplyID = d.ReplyID
+		p.Emoji = d.Emoji
+		p.Encrypted = nil
+		return ck.Name, nil
+	}
+	return "", fmt.Errorf("proto: no key for channel hash %d decrypts packet %d", p.Channel, p.ID)
+}
+
+// Encrypt encrypts the Data fields of p with the key registered under
+// channel, storing the ciphertext in p.Encrypted and the channel hash in
+// p.Channel. p.ID and p.From must already be set: they form the nonce.
+func (k *Keyring) Encrypt(p *MeshPacket, channel string) error {
+	ck, ok := k.Lookup(channel)
+	if !ok {
+		return fmt.Errorf("proto: no key for channel %q", channel)
+	}
+	if p.ID == 0 || p.From == 0 {
+		return fmt.Errorf("proto: packet ID and From are required to encrypt")
+	}
+	cipherText, err := ctrXOR(ck.Key, p.ID, p.From, p.marshalData(nil))
+	if err != nil {
+		return err
+	}
+	p.Encrypted = cipherText
+	p.Channel = uint32(ck.Hash)
+	return nil
+}
+
+// ctrXOR applies AES-CTR with the Meshtastic nonce layout. With a nil key
+// (unencrypted channel) it returns a copy of in.
+func ctrXOR(key []byte, packetID, from uint32, in []byte) ([]byte, error) {
+	out := make([]byte, len(in))
+	if key == nil {
+		copy(out, in)
+		return out, nil
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("proto: channel key: %w", err)
+	}
+	var nonce [aes.BlockSize]byte
+	binary.LittleEndian.PutUint64(nonce[0:8], uint64(packetID))
+	binary.LittleEndian.PutUint32(nonce[8:12], from)
+	// nonce[12:16] is the block counter, starting at zero.
+	cipher.NewCTR(block, nonce[:]).XORKeyStream(out, in)
+	return out, nil
+}
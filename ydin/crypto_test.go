@@ -0,0 +1,101 @@
+package proto
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+// Reference values as produced by firmware 2.x.
+
+func TestExpandPSK(t *testing.T) {
+	tests := []struct {
+		psk  []byte
+		want string
+	}{
+		{nil, ""},
+		{[]byte{0x00}, ""},
+		{[]byte{0x01}, "d4f1bb3a20290759f0bcffabcf4e6901"},
+		{[]byte{0x02}, "d4f1bb3a20290759f0bcffabcf4e6902"},
+		{[]byte{0xAA, 0xBB}, "aabb0000000000000000000000000000"},
+		{bytes.Repeat([]byte{0x11}, 20), "1111111111111111111111111111111111111111000000000000000000000000"},
+	}
+	for _, tc := range tests {
+		key, err := ExpandPSK(tc.psk)
+		if err != nil {
+			t.Errorf("ExpandPSK(%x): %v", tc.psk, err)
+			continue
+		}
+		if got := hex.EncodeToString(key); got != tc.want {
+			t.Errorf("ExpandPSK(%x) = %s, want %s", tc.psk, got, tc.want)
+		}
+	}
+	if _, err := ExpandPSK(make([]byte, 33)); err == nil {
+		t.Error("ExpandPSK of 33 bytes: want an error")
+	}
+}
+
+func TestChannelHash(t *testing.T) {
+	for name, want := range map[string]uint8{
+		"LongFast":   8,
+		"MediumFast": 31,
+		"ShortFast":  112,
+	} {
+		if got := ChannelHash(name, DefaultPSK); got != want {
+			t.Errorf("ChannelHash(%q) = %d, want %d", name, got, want)
+		}
+	}
+}
+
+func TestKeyringEncryptDecrypt(t *testing.T) {
+	k := NewKeyring()
+	if _, err := k.AddBase64("LongFast", "AQ=="); err != nil {
+		t.Fatal(err)
+	}
+
+	p := &MeshPacket{
+		ID: 0x11223344, From: 0xdeadbeef, To: BroadcastAddr,
+		PortNum: PortTextMessage, Payload: []byte("hello mesh"),
+	}
+	if err := k.Encrypt(p, "LongFast"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := hex.EncodeToString(p.Encrypted), "507710ddc8d2297f424dc5990536"; got != want {
+		t.Errorf("ciphertext = %s, want %s", got, want)
+	}
+	if p.Channel != 8 {
+		t.Errorf("channel hash = %d, want 8", p.Channel)
+	}
+
+	rx := &MeshPacket{ID: p.ID, From: p.From, To: p.To, Channel: p.Channel, Encrypted: p.Encrypted}
+	name, err := k.Decrypt(rx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "LongFast" || rx.PortNum != PortTextMessage || string(rx.Payload) != "hello mesh" || rx.Encrypted != nil {
+		t.Errorf("Decrypt = %q, %+v", name, rx)
+	}
+}
+
+func TestKeyringReplacesByName(t *testing.T) {
+	k := NewKeyring()
+	if _, err := k.Add("ops", []byte{0x01}); err != nil {
+		t.Fatal(err)
+	}
+	p := &MeshPacket{ID: 1, From: 2, PortNum: PortTextMessage, Payload: []byte("x")}
+	if err := k.Encrypt(p, "ops"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Re-keying the channel replaces its key: the old ciphertext no longer
+	// decrypts.
+	if _, err := k.Add("ops", []byte{0x05}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := k.Decrypt(&MeshPacket{ID: 1, From: 2, Channel: p.Channel, Encrypted: p.Encrypted}); err == nil {
+		t.Error("Decrypt with the old key: want an error")
+	}
+	if err := k.Encrypt(&MeshPacket{ID: 1, From: 2, PortNum: PortTextMessage}, "missing"); err == nil {
+		t.Error("Encrypt on an unknown channel: want an error")
+	}
+}
@@ -0,0 +1,124 @@
+package gateway
+
+import (
+	"sync"
+	"time"
+
+	meshproto "github.com/gg-glitch-88/meshigo-kore/ydin/proto"
+	"github.com/gg-glitch-88/meshigo-kore/ydin/store"
+)
+
+// dedupWindow matches the firmware's packet history (FLOOD_EXPIRE_TIME):
+// a rebroadcast older than this is treated as a new packet.
+const dedupWindow = 10 * time.Minute
+
+type dedupKey struct {
+	from, id uint32
+}
+
+// dedupEntry summarises every copy of one packet seen inside the window.
+type dedupEntry struct {
+	seen  time.Time
+	rx    store.Reception
+	rowID int64 // messages row for TEXT_MESSAGE_APP packets, once stored
+}
+
+// dedupCache drops the extra copies of a packet that flooding, relays and
+// multiple transports produce. Packets are keyed on (From, ID), which the
+// originating node keeps unique.
+type dedupCache struct {
+	mu        sync.Mutex
+	window    time.Duration
+	entries   map[dedupKey]*dedupEntry
+	lastSweep time.Time
+}
+
+func newDedupCache(window time.Duration) *dedupCache {
+	return &dedupCache{
+		window:  window,
+		entries: make(map[dedupKey]*dedupEntry),
+	}
+}
+
+// repeat folds one more copy of pkt, received at now, into its entry if
+// the packet has already been seen inside the window. It reports whether
+// it had, and returns the reception summary including this copy along with
+// the stored row (if any) the summary belongs to.
+func (c *dedupCache) repeat(pkt *meshproto.MeshPacket, now time.Time) (rx store.Reception, rowID int64, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.sweep(now)
+	e, ok := c.entries[dedupKey{pkt.From, pkt.ID}]
+	if !ok || now.Sub(e.seen) > c.window {
+		return store.Reception{}, 0, false
+	}
+	mergeReception(&e.rx, pkt)
+	return e.rx, e.rowID, true
+}
+
+// add marks pkt, first received at now, as seen. Only packets that were
+// actually processed are added: a copy that could not be decrypted must
+// not hide a later copy that can.
+func (c *dedupCache) add(pkt *meshproto.MeshPacket, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := &dedupEntry{seen: now, rx: store.Reception{HopsAway: -1}}
+	mergeReception(&e.rx, pkt)
+	c.entries[dedupKey{pkt.From, pkt.ID}] = e
+}
+
+// remember marks a packet we originated as seen, so its echo from a second
+// radio or the MQTT broker is not ingested as a new message.
+func (c *dedupCache) remember(from, id uint32, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[dedupKey{from, id}] = &dedupEntry{seen: now, rx: store.Reception{HopsAway: -1}}
+}
+
+// setRow links the stored message row to pkt's entry.
+func (c *dedupCache) setRow(pkt *meshproto.MeshPacket, rowID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[dedupKey{pkt.From, pkt.ID}]; ok {
+		e.rowID = rowID
+	}
+}
+
+// sweep drops expired entries, at most twice per window. c.mu must be held.
+func (c *dedupCache) sweep(now time.Time) {
+	if now.Sub(c.lastSweep) < c.window/2 {
+		return
+	}
+	c.lastSweep = now
+	for k, e := range c.entries {
+		if now.Sub(e.seen) > c.window {
+			delete(c.entries, k)
+		}
+	}
+}
+
+// receptionOf summarises a single copy of pkt.
+func receptionOf(pkt *meshproto.MeshPacket) store.Reception {
+	rx := store.Reception{HopsAway: -1}
+	mergeReception(&rx, pkt)
+	return rx
+}
+
+// mergeReception folds one more copy of a packet into rx, keeping the best
+// signal and the shortest path. Copies without radio metadata (RSSI 0, as
+// relayed over MQTT) only add to the count.
+func mergeReception(rx *store.Reception, pkt *meshproto.MeshPacket) {
+	rx.Copies++
+	if pkt.RxRSSI != 0 {
+		if rx.RxRSSI == 0 || pkt.RxSNR > rx.RxSNR {
+			rx.RxSNR = pkt.RxSNR
+		}
+		if rx.RxRSSI == 0 || pkt.RxRSSI > rx.RxRSSI {
+			rx.RxRSSI = pkt.RxRSSI
+		}
+	}
+	if hops, ok := pkt.HopsAway(); ok && (rx.HopsAway < 0 || int(hops) < rx.HopsAway) {
+		rx.HopsAway = int(hops)
+	}
+}
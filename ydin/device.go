@@ -0,0 +1,83 @@
+package state
+
+import (
+	"time"
+
+	meshproto "github.com/gg-glitch-88/meshigo-kore/ydin/proto"
+)
+
+// DeviceInfo is what the connected radio reported about itself during the
+// want_config handshake.
+type DeviceInfo struct {
+	MyNodeNum       uint32
+	FirmwareVersion string
+	HardwareModel   string
+	Role            string
+	Region          uint32 // Config.LoRaConfig.RegionCode
+	ModemPreset     uint32 // Config.LoRaConfig.ModemPreset
+	HopLimit        uint32
+	ConfiguredAt    time.Time // when config_complete_id was received
+}
+
+// SetDevice replaces the connected device's identity and config.
+func (m *Manager) SetDevice(d DeviceInfo) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.device = d
+}
+
+// Device returns the connected device's identity and config. ok is false
+// until the first want_config handshake has completed.
+func (m *Manager) Device() (d DeviceInfo, ok bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.device, !m.device.ConfiguredAt.IsZero()
+}
+
+// MyNodeNum returns our own node number, or 0 if not yet known.
+func (m *Manager) MyNodeNum() uint32 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.device.MyNodeNum
+}
+
+// SetChannels replaces the device channel table.
+func (m *Manager) SetChannels(chs []meshproto.Channel) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.channels = append([]meshproto.Channel(nil), chs...)
+}
+
+// SetChannel replaces the channel with ch.Index, or appends it if the table
+// has no such slot yet.
+func (m *Manager) SetChannel(ch meshproto.Channel) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.channels {
+		if m.channels[i].Index == ch.Index {
+			m.channels[i] = ch
+			return
+		}
+	}
+	m.channels = append(m.channels, ch)
+}
+
+// Channel returns the channel in slot index.
+func (m *Manager) Channel(index int32) (meshproto.Channel, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, ch := range m.channels {
+		if ch.Index == index {
+			return ch, true
+		}
+	}
+	return meshproto.Channel{}, false
+}
+
+// Channels returns a snapshot of the device channel table, ordered as the
+// device reported it.
+func (m *Manager) Channels() []meshproto.Channel {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]meshproto.Channel(nil), m.channels...)
+}
@@ -13,7 +13,10 @@ const (
 	EventNodeUpdate     EventType = "node_update"
 	EventPositionUpdate EventType = "position_update"
 	EventTelemetry      EventType = "telemetry"
+	EventMessageStatus  EventType = "message_status"
+	EventDelivery       EventType = "delivery"
 	EventStatus         EventType = "status"
+	EventConfigReload   EventType = "config_reload"
 )
 
 // Event is the JSON-serialisable envelope broadcast to WebSocket clients.
@@ -24,8 +27,12 @@ type Event struct {
 }
 
 // subscriber holds a buffered channel for one WebSocket connection.
+// A blocking subscriber is never skipped: Publish waits for room in ch
+// until the subscriber leaves (gone is closed).
 type subscriber struct {
-	ch chan Event
+	ch    chan Event
+	block bool
+	gone  chan struct{}
 }
 
 // EventBus fans mesh events out to all registered WebSocket clients.
@@ -54,12 +61,26 @@ func NewEventBus() *EventBus {
 // Returns a receive channel and an unsubscribe function that must be
 // called when the client disconnects (it closes the channel).
 func (b *EventBus) Subscribe() (<-chan Event, func()) {
-	s := &subscriber{ch: make(chan Event, 64)}
+	return b.subscribe(false)
+}
+
+// SubscribeBlocking is Subscribe for a consumer that must see every event,
+// such as a capture replay running faster than real time. Publish waits
+// for it instead of dropping events, so it must keep reading until it
+// unsubscribes.
+func (b *EventBus) SubscribeBlocking() (<-chan Event, func()) {
+	return b.subscribe(true)
+}
+
+func (b *EventBus) subscribe(block bool) (<-chan Event, func()) {
+	s := &subscriber{ch: make(chan Event, 64), block: block, gone: make(chan struct{})}
 	b.mu.Lock()
 	b.subs[s] = struct{}{}
 	b.mu.Unlock()
 
 	unsub := func() {
+		// Release a Publish waiting on s before taking the write lock.
+		close(s.gone)
 		b.mu.Lock()
 		delete(b.subs, s)
 		b.mu.Unlock()
@@ -71,6 +92,7 @@ func (b *EventBus) Subscribe() (<-chan Event, func()) {
 // Publish sends an Event to all current subscribers.
 // Slow consumers are skipped (their buffer is full) to avoid stalling
 // the ingest loop. They can catch up via the REST history endpoint.
+// Blocking subscribers are waited for.
 func (b *EventBus) Publish(e Event) {
 	if e.Timestamp.IsZero() {
 		e.Timestamp = time.Now().UTC()
@@ -78,6 +100,13 @@ func (b *EventBus) Publish(e Event) {
 	b.mu.RLock()
 	defer b.mu.RUnlock()
 	for s := range b.subs {
+		if s.block {
+			select {
+			case s.ch <- e:
+			case <-s.gone:
+			}
+			continue
+		}
 		select {
 		case s.ch <- e:
 		default:
@@ -106,6 +135,16 @@ func (b *EventBus) PublishTelemetry(data interface{}) {
 	b.Publish(Event{Type: EventTelemetry, Data: data})
 }
 
+// PublishMessageStatus is a convenience wrapper for EventMessageStatus events.
+func (b *EventBus) PublishMessageStatus(data interface{}) {
+	b.Publish(Event{Type: EventMessageStatus, Data: data})
+}
+
+// PublishDelivery is a convenience wrapper for EventDelivery events.
+func (b *EventBus) PublishDelivery(data interface{}) {
+	b.Publish(Event{Type: EventDelivery, Data: data})
+}
+
 // Len returns the current subscriber count (useful for metrics/tests).
 func (b *EventBus) Len() int {
 	b.mu.RLock()
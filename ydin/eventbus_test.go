@@ -0,0 +1,67 @@
+package gateway
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEventBusBlockingSubscriber(t *testing.T) {
+	const n = 1000 // far more than a subscriber buffers
+	b := NewEventBus()
+	all, unsubAll := b.SubscribeBlocking()
+	defer unsubAll()
+	lossy, unsubLossy := b.Subscribe()
+	defer unsubLossy()
+
+	go func() {
+		for i := 0; i < n; i++ {
+			b.PublishMessage(i)
+		}
+	}()
+	for i := 0; i < n; i++ {
+		select {
+		case e := <-all:
+			if e.Data != i {
+				t.Fatalf("event %d carries %v", i, e.Data)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("blocking subscriber got %d of %d events", i, n)
+		}
+	}
+	if got := len(lossy); got != cap(lossy) {
+		t.Errorf("lossy subscriber holds %d events, want a full buffer of %d", got, cap(lossy))
+	}
+}
+
+func TestEventBusBlockingUnsubscribe(t *testing.T) {
+	b := NewEventBus()
+	ch, unsub := b.SubscribeBlocking()
+
+	// Fill the buffer, then block a publisher on the next event.
+	for i := 0; i < cap(ch); i++ {
+		b.PublishMessage(i)
+	}
+	published := make(chan struct{})
+	go func() {
+		b.PublishMessage("stuck")
+		close(published)
+	}()
+
+	// Leaving must not deadlock against the waiting publisher, and must
+	// release it.
+	left := make(chan struct{})
+	go func() {
+		unsub()
+		close(left)
+	}()
+	for _, c := range []chan struct{}{left, published} {
+		select {
+		case <-c:
+		case <-time.After(2 * time.Second):
+			t.Fatal("unsubscribe deadlocked with a blocked Publish")
+		}
+	}
+	if b.Len() != 0 {
+		t.Errorf("Len = %d after unsubscribe", b.Len())
+	}
+}
@@ -13,6 +13,8 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"go.uber.org/zap"
@@ -20,6 +22,7 @@ import (
 	"github.com/gg-glitch-88/meshigo-kore/ydin/api"
 	"github.com/gg-glitch-88/meshigo-kore/ydin/config"
 	meshproto "github.com/gg-glitch-88/meshigo-kore/ydin/proto"
+	"github.com/gg-glitch-88/meshigo-kore/ydin/replication"
 	"github.com/gg-glitch-88/meshigo-kore/ydin/state"
 	"github.com/gg-glitch-88/meshigo-kore/ydin/store"
 	"github.com/gg-glitch-88/meshigo-kore/ydin/transport"
@@ -42,12 +45,36 @@ type GatewayService struct {
 	eventBus     *EventBus
 	stateStore   *state.Manager
 	apiServer    *http.Server
-	config       *config.Config
+	router       *api.Server
+	config       atomic.Pointer[config.Config] // replaced whole on reload
 	log          *zap.Logger
+	handlers     map[meshproto.PortNum]packetHandler
+	outbound     chan *outboundItem
+	acks         *ackTracker
+	dedup        *dedupCache
+	keys         *meshproto.Keyring
+	boot         bootstrap
+	started      time.Time
+
+	reloadMu    sync.Mutex // serialises reloads
+	loadConfig  func() (*config.Config, error)
+	logLevel    *zap.AtomicLevel
+	replication *replication.Manager
 }
 
-// New constructs a GatewayService but does not start it.
+// New constructs a GatewayService, with the transport described by
+// cfg.Transport, but does not start it.
 func New(cfg *config.Config, db *store.DB, log *zap.Logger) (*GatewayService, error) {
+	tr, err := transport.New(cfg, log)
+	if err != nil {
+		return nil, fmt.Errorf("gateway: transport: %w", err)
+	}
+	return NewWithTransport(cfg, db, tr, log)
+}
+
+// NewWithTransport is New with a caller-supplied transport, e.g. a
+// transport.ReplayTransport when reproducing a capture offline.
+func NewWithTransport(cfg *config.Config, db *store.DB, tr transport.TransportManager, log *zap.Logger) (*GatewayService, error) {
 	stateMgr, err := state.New(db)
 	if err != nil {
 		return nil, fmt.Errorf("gateway: state manager: %w", err)
@@ -68,7 +95,18 @@ func New(cfg *config.Config, db *store.DB, log *zap.Logger) (*GatewayService, er
 		return ch, unsub
 	}
 
-	router := api.NewRouter(db, stateMgr, subFn, log)
+	// The router is built before the service, so bind gateway actions late.
+	var g *GatewayService
+	sendFn := func(msg *store.Message) (store.Message, error) { return g.Enqueue(msg) }
+
+	setChannelFn := func(ch meshproto.Channel) error { return g.SetChannel(ch) }
+
+	statusFn := func() map[string]interface{} { return g.health() }
+
+	reloadFn := func() (interface{}, error) { return g.Reload() }
+
+	router := api.NewRouter(db, stateMgr, subFn, sendFn, setChannelFn, statusFn, reloadFn, cfg.API.AdminToken, log)
+	router.SetRateLimit(cfg.API.RateLimit, cfg.API.RateBurst)
 
 	srv := &http.Server{
 		Addr:              cfg.Gateway.ListenAddr,
@@ -78,20 +116,31 @@ func New(cfg *config.Config, db *store.DB, log *zap.Logger) (*GatewayService, er
 		IdleTimeout:       60 * time.Second,
 	}
 
-	tr := transport.New(cfg, log)
-
-	return &GatewayService{
+	g = &GatewayService{
 		transport:    tr,
 		protoHandler: meshproto.New(),
 		eventBus:     bus,
 		stateStore:   stateMgr,
 		apiServer:    srv,
-		config:       cfg,
+		router:       router,
 		log:          log,
-	}, nil
+		handlers:     make(map[meshproto.PortNum]packetHandler),
+		outbound:     make(chan *outboundItem, outboxQueueSize),
+		acks:         newAckTracker(),
+		dedup:        newDedupCache(dedupWindow),
+		keys:         meshproto.NewKeyring(),
+	}
+	g.config.Store(cfg)
+	// The default public channel, until the device tells us otherwise.
+	if _, err := g.keys.Add(meshproto.ModemPresetName(0), []byte{1}); err != nil {
+		return nil, fmt.Errorf("gateway: default channel key: %w", err)
+	}
+	g.registerHandlers()
+	return g, nil
 }
 
-// Start launches all subsystems and blocks until ctx is cancelled.
+// Start launches all subsystems and blocks until ctx is cancelled. It
+// returns only once every goroutine it started has finished.
 func (g *GatewayService) Start(ctx context.Context) error {
 	// Connect transport (non-fatal – will retry in background).
 	if err := g.transport.Connect(); err != nil {
@@ -99,16 +148,45 @@ func (g *GatewayService) Start(ctx context.Context) error {
 			zap.Error(err))
 	}
 
-	go g.ingestLoop(ctx)
+	loopCtx, stopLoops := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	run := func(loop func(context.Context)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			loop(loopCtx)
+		}()
+	}
+	// stop ends the loops and waits for them. The transport goes first so
+	// that no loop is left blocked on it.
+	stop := func() {
+		stopLoops()
+		g.transport.Disconnect() //nolint:errcheck
+		wg.Wait()
+	}
 
-	ln, err := net.Listen("tcp", g.config.Gateway.ListenAddr)
+	g.started = time.Now()
+	run(g.ingestLoop)
+	run(g.sendLoop)
+	run(g.ackLoop)
+	run(g.linkWatchLoop)
+	run(g.retentionLoop)
+	if g.loadConfig != nil {
+		run(g.reloadLoop)
+	}
+
+	addr := g.config.Load().Gateway.ListenAddr
+	ln, err := net.Listen("tcp", addr)
 	if err != nil {
-		return fmt.Errorf("gateway: listen %s: %w", g.config.Gateway.ListenAddr, err)
+		stop()
+		return fmt.Errorf("gateway: listen %s: %w", addr, err)
 	}
 	g.log.Info("HTTP gateway listening", zap.String("addr", ln.Addr().String()))
 
 	srvErr := make(chan error, 1)
+	wg.Add(1)
 	go func() {
+		defer wg.Done()
 		if err := g.apiServer.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			srvErr <- err
 		}
@@ -117,17 +195,20 @@ func (g *GatewayService) Start(ctx context.Context) error {
 	select {
 	case <-ctx.Done():
 		g.log.Info("gateway: shutting down")
-		g.transport.Disconnect() //nolint:errcheck
 		shutCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
-		return g.apiServer.Shutdown(shutCtx)
+		err := g.apiServer.Shutdown(shutCtx)
+		stop()
+		return err
 	case err := <-srvErr:
+		stop()
 		return err
 	}
 }
 
-// ingestLoop reads decoded frames from the transport, stores them,
-// and publishes them to the event bus.
+// ingestLoop reads decoded frames from the transport and hands each
+// MeshPacket to the handler registered for its PortNum. Everything else
+// belongs to the want_config handshake.
 func (g *GatewayService) ingestLoop(ctx context.Context) {
 	for {
 		select {
@@ -137,34 +218,54 @@ func (g *GatewayService) ingestLoop(ctx context.Context) {
 			if !ok {
 				return
 			}
-			fr, err := g.protoHandler.DecodeFromRadio(
-				append([]byte{0, 0, 0, 0}, frame.Data...), // re-add length prefix stripped by TCP
-			)
+			fr, err := g.protoHandler.DecodeFromRadio(frame.Data)
 			if err != nil {
 				g.log.Warn("gateway: decode frame", zap.Error(err))
 				continue
 			}
 			if fr.Packet == nil {
+				g.handleConfigFrame(fr, frame.Timestamp)
 				continue
 			}
+			g.dispatch(fr.Packet, frame.Timestamp)
+		}
+	}
+}
 
-			msg := &store.Message{
-				MeshID:     fmt.Sprintf("%d", fr.Packet.ID),
-				FromNode:   fmt.Sprintf("!%08x", fr.Packet.From),
-				ToNode:     fmt.Sprintf("!%08x", fr.Packet.To),
-				Channel:    int(fr.Packet.Channel),
-				Payload:    fr.Packet.Payload,
-				ReceivedAt: frame.Timestamp,
-			}
-			id, err := g.stateStore.RecordMessage(msg)
-			if err != nil {
-				g.log.Warn("gateway: store message", zap.Error(err))
-				continue
-			}
-			msg.ID = id
+// Subscribe registers an in-process event consumer, with the same semantics
+// as a WebSocket client of /api/v1/events.
+func (g *GatewayService) Subscribe() (<-chan Event, func()) { return g.eventBus.Subscribe() }
 
-			g.eventBus.PublishMessage(msg)
-		}
+// SubscribeBlocking registers an in-process consumer that receives every
+// event: the gateway waits for it rather than dropping events.
+func (g *GatewayService) SubscribeBlocking() (<-chan Event, func()) {
+	return g.eventBus.SubscribeBlocking()
+}
+
+// Links reports the health of each link when the transport is a
+// MultiTransport; other transports report none.
+func (g *GatewayService) Links() []transport.LinkHealth {
+	if mt, ok := g.transport.(*transport.MultiTransport); ok {
+		return mt.Links()
+	}
+	return nil
+}
+
+// health is the gateway's part of GET /api/v1/status. The status is
+// "degraded" while the transport is not connected.
+func (g *GatewayService) health() map[string]interface{} {
+	conn := g.transport.GetConnectionState()
+	status := "ok"
+	if conn != transport.StateConnected {
+		status = "degraded"
+	}
+	return map[string]interface{}{
+		"status":         status,
+		"connection":     conn.String(),
+		"links":          g.Links(),
+		"subscribers":    g.eventBus.Len(),
+		"outbox":         len(g.outbound),
+		"uptime_seconds": int64(time.Since(g.started).Seconds()),
 	}
 }
 
@@ -0,0 +1,264 @@
+package gateway
+
+import (
+	"context"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	meshproto "github.com/gg-glitch-88/meshigo-kore/ydin/proto"
+	"github.com/gg-glitch-88/meshigo-kore/ydin/sim"
+	"github.com/gg-glitch-88/meshigo-kore/ydin/store"
+	"github.com/gg-glitch-88/meshigo-kore/ydin/transport"
+)
+
+// Simulated nodes a couple of kilometres apart: well inside radio range,
+// so the default model loses essentially nothing between them.
+const (
+	simGateway = 0x0a000001
+	simPeer    = 0x0a000002
+	simLat     = 60.17
+	simLon     = 24.94
+)
+
+// simMesh is a started GatewayService attached to simGateway's radio, with
+// simPeer in range.
+type simMesh struct {
+	g      *GatewayService
+	peer   *sim.Node
+	events <-chan Event
+}
+
+func startSimMesh(t *testing.T) *simMesh {
+	t.Helper()
+	mesh := sim.New(sim.Config{Seed: 1, RebroadcastDelay: 50 * time.Millisecond}, zap.NewNop())
+	gw, err := mesh.AddNode(sim.NodeConfig{Num: simGateway, LongName: "Gateway", Lat: simLat, Lon: simLon})
+	if err != nil {
+		t.Fatal(err)
+	}
+	peer, err := mesh.AddNode(sim.NodeConfig{Num: simPeer, LongName: "Peer", Lat: simLat + 0.02, Lon: simLon})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g := newTestGateway(t, gw.Radio())
+	events, unsub := g.SubscribeBlocking()
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() { errc <- g.Start(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		// Keep reading: a blocked Publish must not hold up the shutdown.
+		for {
+			select {
+			case <-events:
+				continue
+			case err := <-errc:
+				unsub()
+				if err != nil {
+					t.Errorf("Start: %v", err)
+				}
+				return
+			}
+		}
+	})
+
+	if err := peer.Radio().Connect(); err != nil {
+		t.Fatal(err)
+	}
+	m := &simMesh{g: g, peer: peer, events: events}
+	m.waitEvent(t, EventStatus, func(d any) bool { return d.(*StatusEvent).State == "configured" })
+	return m
+}
+
+// waitEvent returns the Data of the first event of type typ that match
+// accepts, skipping others.
+func (m *simMesh) waitEvent(t *testing.T, typ EventType, match func(any) bool) any {
+	t.Helper()
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case e := <-m.events:
+			if e.Type == typ && match(e.Data) {
+				return e.Data
+			}
+		case <-timeout:
+			t.Fatalf("no matching %s event", typ)
+			return nil
+		}
+	}
+}
+
+// peerSend hands pkt to the peer node's phone API.
+func (m *simMesh) peerSend(t *testing.T, pkt *meshproto.MeshPacket) {
+	t.Helper()
+	data, err := meshproto.New().EncodeToRadio(&meshproto.ToRadio{Packet: pkt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.peer.Radio().Send(transport.ProtoFrame{Data: data}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGatewaySimHandshake(t *testing.T) {
+	m := startSimMesh(t)
+
+	dev, ok := m.g.stateStore.Device()
+	if !ok || dev.MyNodeNum != simGateway || dev.FirmwareVersion == "" {
+		t.Errorf("device = %+v, %v", dev, ok)
+	}
+	if _, ok := m.g.keys.Lookup("LongFast"); !ok {
+		t.Error("primary channel key not loaded")
+	}
+	if n, ok := m.g.stateStore.GetNode(simGateway); !ok || n.LongName != "Gateway" {
+		t.Errorf("own node = %+v, %v", n, ok)
+	}
+}
+
+func TestGatewaySimInbound(t *testing.T) {
+	m := startSimMesh(t)
+
+	m.peerSend(t, &meshproto.MeshPacket{
+		To: meshproto.BroadcastAddr, PortNum: meshproto.PortTextMessage, Payload: []byte("hello gateway"),
+	})
+	msg := m.waitEvent(t, EventMessage, func(any) bool { return true }).(*store.Message)
+	if msg.FromNode != "!0a000002" || string(msg.Payload) != "hello gateway" || msg.Direction != store.DirectionIn {
+		t.Errorf("message = %+v", msg)
+	}
+	if msg.RxRSSI == 0 || msg.HopsAway != 0 || msg.Copies != 1 {
+		t.Errorf("reception = rssi %d, hops %d, copies %d", msg.RxRSSI, msg.HopsAway, msg.Copies)
+	}
+
+	stored, err := m.g.stateStore.RecentMessages(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 1 || stored[0].ID != msg.ID {
+		t.Errorf("stored = %+v", stored)
+	}
+}
+
+func TestGatewaySimPosition(t *testing.T) {
+	m := startSimMesh(t)
+
+	payload, err := meshproto.New().EncodePosition(&meshproto.Position{
+		LatitudeI: 601900000, LongitudeI: 249400000, Altitude: 12,
+		Time: uint32(time.Now().Unix()), PrecisionBits: 32,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.peerSend(t, &meshproto.MeshPacket{
+		To: meshproto.BroadcastAddr, PortNum: meshproto.PortPosition, Payload: payload,
+	})
+	near := func(a, b float64) bool { return math.Abs(a-b) < 1e-7 }
+	ev := m.waitEvent(t, EventPositionUpdate, func(any) bool { return true }).(*PositionEvent)
+	if ev.NodeID != "!0a000002" || !near(ev.Lat, 60.19) || !near(ev.Lon, 24.94) {
+		t.Errorf("position event = %+v", ev)
+	}
+	n, ok := m.g.stateStore.GetNode(simPeer)
+	if !ok || !near(n.Lat, 60.19) || !near(n.Lon, 24.94) {
+		t.Errorf("peer node = %+v, %v", n, ok)
+	}
+}
+
+func TestGatewaySimOutboundAcked(t *testing.T) {
+	m := startSimMesh(t)
+
+	queued, err := m.g.Enqueue(&store.Message{ToNode: "!0a000002", Payload: []byte("ping")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if queued.Status != store.MessageStatusQueued || queued.FromNode != "!0a000001" {
+		t.Errorf("queued = %+v", queued)
+	}
+
+	ev := m.waitEvent(t, EventDelivery, func(d any) bool { return d.(*DeliveryEvent).ID == queued.ID }).(*DeliveryEvent)
+	if ev.Status != store.MessageStatusAcked || !ev.Delivered || ev.AckFrom != "!0a000002" {
+		t.Errorf("delivery = %+v", ev)
+	}
+	msg, err := m.g.stateStore.RecentMessages(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msg) != 1 || msg[0].Status != store.MessageStatusAcked {
+		t.Errorf("stored = %+v", msg)
+	}
+}
+
+// frameStub is a linkStub whose frames the test hands over one at a time:
+// a frame is taken only while the ingest loop is running.
+type frameStub struct {
+	linkStub
+	frames chan transport.ProtoFrame
+}
+
+func (s *frameStub) Receive() <-chan transport.ProtoFrame { return s.frames }
+
+// loopsStopped reports whether nothing reads tr's frames or sends it
+// handshakes any more.
+func loopsStopped(t *testing.T, tr *frameStub) {
+	t.Helper()
+	// A running link watch loop would redo the handshake.
+	tr.link.Store(int32(transport.StateDisconnected))
+	before := tr.handshakes()
+	select {
+	case tr.frames <- transport.ProtoFrame{}:
+		t.Error("ingest loop still running")
+	case <-time.After(3 * linkPollInterval):
+	}
+	if n := tr.handshakes(); n != before {
+		t.Errorf("link watch loop still running: %d handshakes, was %d", n, before)
+	}
+}
+
+func TestStartWaitsForLoops(t *testing.T) {
+	tr := &frameStub{frames: make(chan transport.ProtoFrame)}
+	tr.overall.Store(int32(transport.StateConnected))
+	g := newTestGateway(t, tr)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() { errc <- g.Start(ctx) }()
+	waitHandshakes(t, &tr.linkStub, 1)
+	select {
+	case tr.frames <- transport.ProtoFrame{}:
+	case <-time.After(5 * time.Second):
+		t.Fatal("ingest loop not running")
+	}
+
+	cancel()
+	select {
+	case err := <-errc:
+		if err != nil {
+			t.Fatalf("Start: %v", err)
+		}
+	case <-time.After(15 * time.Second):
+		t.Fatal("Start did not return")
+	}
+	loopsStopped(t, tr)
+}
+
+func TestStartListenFailureStopsLoops(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	tr := &frameStub{frames: make(chan transport.ProtoFrame)}
+	tr.overall.Store(int32(transport.StateConnected))
+	g := newTestGateway(t, tr)
+	cfg := *g.config.Load()
+	cfg.Gateway.ListenAddr = ln.Addr().String()
+	g.config.Store(&cfg)
+
+	if err := g.Start(context.Background()); err == nil {
+		t.Fatal("Start succeeded on a port in use")
+	}
+	loopsStopped(t, tr)
+}
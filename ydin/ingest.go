@@ -0,0 +1,280 @@
+package gateway
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	meshproto "github.com/gg-glitch-88/meshigo-kore/ydin/proto"
+	"github.com/gg-glitch-88/meshigo-kore/ydin/state"
+	"github.com/gg-glitch-88/meshigo-kore/ydin/store"
+)
+
+// packetHandler processes one decrypted MeshPacket. rxTime is when the
+// transport received the enclosing frame.
+type packetHandler func(pkt *meshproto.MeshPacket, rxTime time.Time) error
+
+// Handle registers h for packets on port, replacing any previous handler.
+// It must be called before Start.
+func (g *GatewayService) Handle(port meshproto.PortNum, h packetHandler) {
+	g.handlers[port] = h
+}
+
+// registerHandlers installs the built-in per-port handlers.
+func (g *GatewayService) registerHandlers() {
+	g.Handle(meshproto.PortTextMessage, g.handleText)
+	g.Handle(meshproto.PortPosition, g.handlePosition)
+	g.Handle(meshproto.PortNodeInfo, g.handleNodeInfo)
+	g.Handle(meshproto.PortTelemetry, g.handleTelemetry)
+	g.Handle(meshproto.PortRouting, g.handleRouting)
+}
+
+// dispatch routes pkt to the handler registered for its PortNum.
+// Repeat copies of a packet only update its reception summary. Encrypted
+// packets are decrypted with the channel keyring first; packets no known key
+// opens, and packets on unhandled ports, are dropped. A packet counts as
+// seen only once decrypted, so a later copy still gets its chance after the
+// missing key is added.
+func (g *GatewayService) dispatch(pkt *meshproto.MeshPacket, rxTime time.Time) {
+	if pkt.ID != 0 {
+		if rx, rowID, ok := g.dedup.repeat(pkt, rxTime); ok {
+			g.recordDuplicate(pkt, rx, rowID)
+			return
+		}
+	}
+	if pkt.Encrypted != nil {
+		if _, err := g.keys.Decrypt(pkt); err != nil {
+			g.log.Debug("gateway: dropping encrypted packet",
+				zap.Uint32("id", pkt.ID),
+				zap.String("from", nodeHex(pkt.From)),
+				zap.Error(err))
+			return
+		}
+	}
+	if pkt.ID != 0 {
+		g.dedup.add(pkt, rxTime)
+	}
+	defer g.noteHeard(pkt, rxTime)
+
+	h, ok := g.handlers[pkt.PortNum]
+	if !ok {
+		g.log.Debug("gateway: no handler for port",
+			zap.String("port", meshproto.MessageTypeLabel(pkt.PortNum)),
+			zap.String("from", nodeHex(pkt.From)))
+		return
+	}
+	if err := h(pkt, rxTime); err != nil {
+		g.log.Warn("gateway: handle packet",
+			zap.String("port", meshproto.MessageTypeLabel(pkt.PortNum)),
+			zap.String("from", nodeHex(pkt.From)),
+			zap.Uint32("id", pkt.ID),
+			zap.Error(err))
+	}
+}
+
+// noteHeard records the sender's link quality once its packet has been
+// handled, so that nodes the handler just created are included.
+func (g *GatewayService) noteHeard(pkt *meshproto.MeshPacket, rxTime time.Time) {
+	hops := -1
+	if h, ok := pkt.HopsAway(); ok {
+		hops = int(h)
+	}
+	if err := g.stateStore.Heard(pkt.From, rxTime, pkt.RxSNR, hops, pkt.ViaMQTT); err != nil {
+		g.log.Warn("gateway: note heard", zap.String("from", nodeHex(pkt.From)), zap.Error(err))
+	}
+}
+
+// recordDuplicate persists the updated reception summary of a text message
+// that has been heard again.
+func (g *GatewayService) recordDuplicate(pkt *meshproto.MeshPacket, rx store.Reception, rowID int64) {
+	g.log.Debug("gateway: duplicate packet",
+		zap.Uint32("id", pkt.ID),
+		zap.String("from", nodeHex(pkt.From)),
+		zap.Int("copies", rx.Copies))
+	if rowID == 0 {
+		return
+	}
+	if err := g.stateStore.SetMessageReception(rowID, rx); err != nil {
+		g.log.Warn("gateway: persist reception",
+			zap.Int64("id", rowID), zap.Error(err))
+	}
+}
+
+// ── Event payloads ────────────────────────────────────────────────────────
+
+// PositionEvent is the Data of an EventPositionUpdate.
+type PositionEvent struct {
+	NodeID    string    `json:"node_id"`
+	Lat       float64   `json:"lat"`
+	Lon       float64   `json:"lon"`
+	Alt       int32     `json:"alt"`
+	Time      time.Time `json:"time"`
+	Precision uint32    `json:"precision_bits,omitempty"`
+}
+
+// TelemetryEvent is the Data of an EventTelemetry. Exactly one of the
+// metric groups is set, mirroring the TELEMETRY_APP payload.
+type TelemetryEvent struct {
+	NodeID      string                        `json:"node_id"`
+	Time        time.Time                     `json:"time"`
+	Device      *meshproto.DeviceMetrics      `json:"device,omitempty"`
+	Environment *meshproto.EnvironmentMetrics `json:"environment,omitempty"`
+	Power       *meshproto.PowerMetrics       `json:"power,omitempty"`
+}
+
+// ── Built-in handlers ─────────────────────────────────────────────────────
+
+// handleText records TEXT_MESSAGE_APP packets and publishes EventMessage.
+func (g *GatewayService) handleText(pkt *meshproto.MeshPacket, rxTime time.Time) error {
+	rx := receptionOf(pkt)
+	msg := &store.Message{
+		MeshID:     fmt.Sprintf("%d", pkt.ID),
+		FromNode:   nodeHex(pkt.From),
+		ToNode:     nodeHex(pkt.To),
+		Channel:    int(pkt.Channel),
+		Port:       uint32(pkt.PortNum),
+		Payload:    pkt.Payload,
+		ReceivedAt: rxTime,
+		Direction:  store.DirectionIn,
+		Status:     store.MessageStatusReceived,
+		Copies:     rx.Copies,
+		RxSNR:      rx.RxSNR,
+		RxRSSI:     rx.RxRSSI,
+		HopsAway:   rx.HopsAway,
+	}
+	id, err := g.stateStore.RecordMessage(msg)
+	if err != nil {
+		return fmt.Errorf("store message: %w", err)
+	}
+	msg.ID = id
+	g.dedup.setRow(pkt, id)
+
+	g.eventBus.PublishMessage(msg)
+	return nil
+}
+
+// handlePosition updates the sender's coordinates, appends the report to
+// its track and publishes EventPositionUpdate.
+func (g *GatewayService) handlePosition(pkt *meshproto.MeshPacket, rxTime time.Time) error {
+	pos, err := g.protoHandler.DecodePosition(pkt.Payload)
+	if err != nil {
+		return err
+	}
+	// Nodes without a GPS fix still send position packets with no coordinates.
+	if pos.LatitudeI == 0 && pos.LongitudeI == 0 {
+		return nil
+	}
+	if err := g.ensureNode(pkt.From, rxTime); err != nil {
+		return err
+	}
+
+	fixTime := rxTime
+	switch {
+	case pos.Timestamp != 0:
+		fixTime = time.Unix(int64(pos.Timestamp), 0).UTC()
+	case pos.Time != 0:
+		fixTime = time.Unix(int64(pos.Time), 0).UTC()
+	}
+	err = g.stateStore.UpdatePosition(pkt.From, &store.Position{
+		Time:          fixTime,
+		Lat:           pos.Lat(),
+		Lon:           pos.Lon(),
+		Alt:           pos.Altitude,
+		PrecisionBits: pos.PrecisionBits,
+		PDOP:          float32(pos.PDOP) / 100,
+		GroundSpeed:   pos.GroundSpeed,
+		Heading:       float64(pos.GroundTrack) * 1e-5,
+		SatsInView:    pos.SatsInView,
+		PacketID:      pkt.ID,
+		ReceivedAt:    rxTime,
+	})
+	if err != nil {
+		return fmt.Errorf("store position: %w", err)
+	}
+
+	g.eventBus.PublishPosition(&PositionEvent{
+		NodeID:    nodeHex(pkt.From),
+		Lat:       pos.Lat(),
+		Lon:       pos.Lon(),
+		Alt:       pos.Altitude,
+		Time:      fixTime,
+		Precision: pos.PrecisionBits,
+	})
+	return nil
+}
+
+// handleTelemetry stores the report in the sender's time series and publishes
+// EventTelemetry for every metric variant.
+func (g *GatewayService) handleTelemetry(pkt *meshproto.MeshPacket, rxTime time.Time) error {
+	t, err := g.protoHandler.DecodeTelemetry(pkt.Payload)
+	if err != nil {
+		return err
+	}
+	if err := g.ensureNode(pkt.From, rxTime); err != nil {
+		return err
+	}
+
+	ts := rxTime
+	if t.Time != 0 {
+		ts = time.Unix(int64(t.Time), 0).UTC()
+	}
+	if err := g.stateStore.UpdateTelemetry(pkt.From, ts, t); err != nil {
+		return fmt.Errorf("store telemetry: %w", err)
+	}
+	g.eventBus.PublishTelemetry(&TelemetryEvent{
+		NodeID:      nodeHex(pkt.From),
+		Time:        ts,
+		Device:      t.Device,
+		Environment: t.Environment,
+		Power:       t.Power,
+	})
+	return nil
+}
+
+// handleNodeInfo upserts the sender's identity and publishes
+// EventNodeUpdate with the merged node record.
+func (g *GatewayService) handleNodeInfo(pkt *meshproto.MeshPacket, rxTime time.Time) error {
+	info, err := g.protoHandler.DecodeNodeInfo(pkt.Payload)
+	if err != nil {
+		return err
+	}
+
+	// Keep what we already know (position, telemetry) and overlay identity.
+	n, ok := g.stateStore.GetNode(pkt.From)
+	if !ok {
+		n = state.NewNode(pkt.From)
+	}
+	n.LongName = info.LongName
+	n.ShortName = info.ShortName
+	n.Hardware = info.HardwareModel
+	n.Role = info.Role
+	n.IsLicensed = info.IsLicensed
+	if len(info.PublicKey) > 0 {
+		n.PublicKey = info.PublicKey
+	}
+	n.LastSeen = rxTime
+	if err := g.stateStore.UpsertNode(n); err != nil {
+		return fmt.Errorf("upsert node: %w", err)
+	}
+
+	g.eventBus.PublishNodeUpdate(n)
+	return nil
+}
+
+// ── helpers ───────────────────────────────────────────────────────────────
+
+// ensureNode makes sure nodeID is tracked so that position and telemetry
+// updates from nodes we have not yet seen a NODEINFO_APP for are kept.
+// A new node is first seen at rxTime.
+func (g *GatewayService) ensureNode(nodeID uint32, rxTime time.Time) error {
+	if _, ok := g.stateStore.GetNode(nodeID); ok {
+		return nil
+	}
+	n := state.NewNode(nodeID)
+	n.LastSeen = rxTime
+	return g.stateStore.UpsertNode(n)
+}
+
+// nodeHex formats a node number the way Meshtastic does ("!deadbeef").
+func nodeHex(n uint32) string { return fmt.Sprintf("!%08x", n) }
@@ -0,0 +1,107 @@
+package gateway
+
+import (
+	"testing"
+	"time"
+
+	meshproto "github.com/gg-glitch-88/meshigo-kore/ydin/proto"
+)
+
+func TestDispatchDedupAfterDecrypt(t *testing.T) {
+	g := newTestGateway(t, &linkStub{})
+
+	// A copy on a channel whose key the gateway does not have yet.
+	sender := meshproto.NewKeyring()
+	if _, err := sender.Add("ops", []byte("0123456789abcdef")); err != nil {
+		t.Fatal(err)
+	}
+	encrypted := func(snr float32) *meshproto.MeshPacket {
+		p := &meshproto.MeshPacket{
+			ID: 0x1001, From: 0x1234abcd, To: meshproto.BroadcastAddr,
+			RxSNR: snr, RxRSSI: -90, HopLimit: 3, HopStart: 3,
+			PortNum: meshproto.PortTextMessage, Payload: []byte("hello ops"),
+		}
+		if err := sender.Encrypt(p, "ops"); err != nil {
+			t.Fatal(err)
+		}
+		p.PortNum, p.Payload = meshproto.PortUnknown, nil
+		return p
+	}
+	now := time.Now().UTC()
+
+	g.dispatch(encrypted(1), now)
+	if msgs, _ := g.stateStore.RecentMessages(10); len(msgs) != 0 {
+		t.Fatalf("undecryptable packet stored: %+v", msgs[0])
+	}
+
+	// The key arrives (e.g. SetChannel); the next copy is a first copy.
+	if _, err := g.keys.Add("ops", []byte("0123456789abcdef")); err != nil {
+		t.Fatal(err)
+	}
+	g.dispatch(encrypted(2), now.Add(time.Second))
+	msgs, err := g.stateStore.RecentMessages(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 1 || string(msgs[0].Payload) != "hello ops" || msgs[0].Copies != 1 {
+		t.Fatalf("messages = %+v, want one decrypted copy", msgs)
+	}
+
+	// Later copies are duplicates of the stored row.
+	g.dispatch(encrypted(5), now.Add(2*time.Second))
+	msgs, err = g.stateStore.RecentMessages(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 1 || msgs[0].Copies != 2 || msgs[0].RxSNR != 5 {
+		t.Errorf("after a repeat: %+v, want 2 copies, best SNR 5", msgs[0])
+	}
+}
+
+func TestDispatchPositionLastSeen(t *testing.T) {
+	g := newTestGateway(t, &linkStub{})
+
+	payload, err := meshproto.New().EncodePosition(&meshproto.Position{LatitudeI: 601700000, LongitudeI: 249400000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A replayed packet: it was heard an hour ago.
+	rx := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+	g.dispatch(&meshproto.MeshPacket{
+		ID: 0x2001, From: 0x1234abcd, To: meshproto.BroadcastAddr, HopLimit: 3, HopStart: 3,
+		PortNum: meshproto.PortPosition, Payload: payload,
+	}, rx)
+
+	n, ok := g.stateStore.GetNode(0x1234abcd)
+	if !ok || n.Lat == 0 {
+		t.Fatalf("node = %+v, %v", n, ok)
+	}
+	if !n.LastSeen.Equal(rx) {
+		t.Errorf("LastSeen = %v, want the packet's %v", n.LastSeen, rx)
+	}
+}
+
+func TestDispatchTelemetryLastSeen(t *testing.T) {
+	g := newTestGateway(t, &linkStub{})
+
+	rx := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+	payload, err := meshproto.New().EncodeTelemetry(&meshproto.Telemetry{
+		Time:   uint32(rx.Unix()),
+		Device: &meshproto.DeviceMetrics{BatteryLevel: 80, Voltage: 3.9},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.dispatch(&meshproto.MeshPacket{
+		ID: 0x3001, From: 0x1234abcd, To: meshproto.BroadcastAddr, HopLimit: 3, HopStart: 3,
+		PortNum: meshproto.PortTelemetry, Payload: payload,
+	}, rx)
+
+	n, ok := g.stateStore.GetNode(0x1234abcd)
+	if !ok || n.BatteryLevel != 80 {
+		t.Fatalf("node = %+v, %v", n, ok)
+	}
+	if !n.LastSeen.Equal(rx) {
+		t.Errorf("LastSeen = %v, want the packet's %v", n.LastSeen, rx)
+	}
+}
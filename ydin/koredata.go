@@ -0,0 +1,70 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrDataNotFound is returned by GetData and NodeData for an unknown ID.
+var ErrDataNotFound = errors.New("data not found")
+
+// GetData returns the kore domain record stored under id.
+func (db *DB) GetData(ctx context.Context, id string) (string, error) {
+	var data string
+	err := db.QueryRowContext(ctx, `SELECT data FROM kore_data WHERE id = ?`, id).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("store: get data %q: %w", id, ErrDataNotFound)
+	}
+	if err != nil {
+		return "", fmt.Errorf("store: get data %q: %w", id, err)
+	}
+	return data, nil
+}
+
+// PutData stores data under id, replacing any earlier record.
+func (db *DB) PutData(ctx context.Context, id, data string) error {
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO kore_data (id, data, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET data = excluded.data, updated_at = excluded.updated_at`,
+		id, data, time.Now().UnixMilli())
+	if err != nil {
+		return fmt.Errorf("store: put data %q: %w", id, err)
+	}
+	return nil
+}
+
+// NodeData returns what the mesh knows of nodeID ("!deadbeef") as a JSON
+// object: its identity, the last packet heard from it, its latest
+// telemetry and position, and how many messages it has sent.
+func (db *DB) NodeData(ctx context.Context, nodeID string) (string, error) {
+	var data string
+	err := db.QueryRowContext(ctx, `
+		SELECT json_object(
+		    'node_id',       node_id,
+		    'long_name',     long_name,
+		    'short_name',    short_name,
+		    'hardware',      hardware,
+		    'role',          role,
+		    'last_seen',     strftime('%Y-%m-%dT%H:%M:%fZ', last_seen / 1000.0, 'unixepoch'),
+		    'snr',           snr,
+		    'hops_away',     hops_away,
+		    'via_mqtt',      json(CASE WHEN via_mqtt THEN 'true' ELSE 'false' END),
+		    'battery_level', battery_level,
+		    'voltage',       voltage,
+		    'channel_util',  channel_util,
+		    'lat',           lat,
+		    'lon',           lon,
+		    'alt',           alt,
+		    'messages_sent', (SELECT COUNT(*) FROM messages m WHERE m.from_node = nodes.node_id))
+		FROM nodes WHERE node_id = ?`, nodeID).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("store: node data %q: %w", nodeID, ErrDataNotFound)
+	}
+	if err != nil {
+		return "", fmt.Errorf("store: node data %q: %w", nodeID, err)
+	}
+	return data, nil
+}
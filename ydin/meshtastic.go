@@ -4,134 +4,445 @@
 package proto
 
 import (
-	"encoding/binary"
 	"fmt"
-
-	goproto "google.golang.org/protobuf/proto"
 )
 
 // PortNum mirrors the Meshtastic PortNum enum.
 type PortNum uint32
 
 const (
-	PortUnknown      PortNum = 0
-	PortTextMessage  PortNum = 1  // TEXT_MESSAGE_APP
-	PortPosition     PortNum = 3  // POSITION_APP
-	PortNodeInfo     PortNum = 4  // NODEINFO_APP
-	PortRouting      PortNum = 5  // ROUTING_APP
-	PortTelemetry    PortNum = 67 // TELEMETRY_APP
+	PortUnknown     PortNum = 0
+	PortTextMessage PortNum = 1  // TEXT_MESSAGE_APP
+	PortPosition    PortNum = 3  // POSITION_APP
+	PortNodeInfo    PortNum = 4  // NODEINFO_APP
+	PortRouting     PortNum = 5  // ROUTING_APP
+	PortAdmin       PortNum = 6  // ADMIN_APP
+	PortTelemetry   PortNum = 67 // TELEMETRY_APP
 )
 
 // FromRadio is the top-level wrapper for data coming FROM the radio device.
-// (Simplified: real Meshtastic uses a oneof; we use a union struct.)
+// Real Meshtastic uses a oneof; we use a union struct in which exactly one
+// variant is set (ConfigCompleteID and Rebooted count as variants).
 type FromRadio struct {
-	Packet    *MeshPacket
-	NodeInfo  *NodeInfo
-	MyInfo    *MyNodeInfo
-	Telemetry *Telemetry
+	ID               uint32
+	Packet           *MeshPacket
+	MyInfo           *MyNodeInfo
+	NodeInfo         *NodeInfo
+	Config           *Config
+	Channel          *Channel
+	Metadata         *DeviceMetadata
+	ConfigCompleteID uint32
+	Rebooted         bool
 }
 
 // ToRadio is the top-level wrapper for data going TO the radio device.
+// Exactly one of Packet or WantConfigID is set.
 type ToRadio struct {
 	Packet *MeshPacket
+	// WantConfigID asks the device to stream its node DB, channels and
+	// config, terminated by a FromRadio carrying the same ConfigCompleteID.
+	WantConfigID uint32
+}
+
+// ServiceEnvelope wraps a MeshPacket published to MQTT by a gateway node.
+// The packet is normally still encrypted with the channel PSK.
+type ServiceEnvelope struct {
+	Packet    *MeshPacket
+	ChannelID string // channel name, e.g. "LongFast"
+	GatewayID string // publishing node, e.g. "!deadbeef"
 }
 
+// MaxFrameSize is the largest FromRadio/ToRadio protobuf the firmware
+// accepts (MAX_TO_FROM_RADIO_SIZE).
+const MaxFrameSize = 512
+
+// MaxPayloadSize is the largest Data.payload the firmware will send
+// (Constants.DATA_PAYLOAD_LEN); longer text is rejected by the device.
+const MaxPayloadSize = 233
+
+// BroadcastAddr is the MeshPacket.To value addressing every node.
+const BroadcastAddr uint32 = 0xFFFFFFFF
+
 // MeshPacket is a routed Meshtastic packet.
+// The fields of the decoded Data submessage (PortNum … Emoji) are flattened
+// into the packet. When the packet could not be decrypted by the device,
+// Encrypted holds the ciphertext and the Data fields are zero.
 type MeshPacket struct {
-	ID       uint32
-	From     uint32 // Source node number
-	To       uint32 // Destination node number (0xFFFFFFFF = broadcast)
-	Channel  uint32
-	PortNum  PortNum
-	Payload  []byte
-	HopLimit uint32
-	WantAck  bool
+	ID        uint32
+	From      uint32 // Source node number
+	To        uint32 // Destination node number (0xFFFFFFFF = broadcast)
+	Channel   uint32 // Channel index, or channel hash when Encrypted is set
+	Encrypted []byte
+	RxTime    uint32  // unix seconds, stamped by the receiving radio
+	RxSNR     float32 // dB
+	RxRSSI    int32   // dBm
+	HopLimit  uint32
+	HopStart  uint32
+	WantAck   bool
+	ViaMQTT   bool
+	Priority  uint32
+
+	// Data
+	PortNum      PortNum
+	Payload      []byte
+	WantResponse bool
+	Dest         uint32
+	Source       uint32
+	RequestID    uint32 // packet ID this one responds to (ROUTING_APP acks)
+	ReplyID      uint32 // packet ID this text message replies to
+	Emoji        uint32
+}
+
+// HopsAway returns how many hops the packet travelled, if the sender
+// populated HopStart (firmware ≥ 2.3). ok is false otherwise.
+func (p *MeshPacket) HopsAway() (hops uint32, ok bool) {
+	if p.HopStart == 0 || p.HopStart < p.HopLimit {
+		return 0, false
+	}
+	return p.HopStart - p.HopLimit, true
 }
 
 // NodeInfo carries metadata about a known mesh node.
 type NodeInfo struct {
-	NodeID      uint32
-	LongName    string
-	ShortName   string
+	NodeID        uint32
+	UserID        string // e.g. "!deadbeef"
+	LongName      string
+	ShortName     string
 	HardwareModel string
-	Role        string
+	Role          string
+	IsLicensed    bool
+	PublicKey     []byte
+
+	// Populated only for node DB entries sent by the device (FromRadio.node_info).
+	Position      *Position
+	DeviceMetrics *DeviceMetrics
+	SNR           float32
+	LastHeard     uint32 // unix seconds
+	Channel       uint32
+	ViaMQTT       bool
+	HopsAway      uint32
+	IsFavorite    bool
 }
 
 // MyNodeInfo carries this device's own identity.
 type MyNodeInfo struct {
-	MyNodeNum uint32
+	MyNodeNum     uint32
+	RebootCount   uint32
+	MinAppVersion uint32
+	DeviceID      []byte
+	PioEnv        string
+}
+
+// DeviceMetadata describes the connected device's firmware and capabilities.
+type DeviceMetadata struct {
+	FirmwareVersion    string
+	DeviceStateVersion uint32
+	CanShutdown        bool
+	HasWifi            bool
+	HasBluetooth       bool
+	HasEthernet        bool
+	Role               string
+	HardwareModel      string
+	HasPKC             bool
 }
 
-// Telemetry carries battery + signal metrics.
+// DeviceMetrics carries battery + airtime metrics.
+type DeviceMetrics struct {
+	BatteryLevel  uint32  // percent 0–100 (101 = powered)
+	Voltage       float32 // volts
+	ChannelUtil   float32 // percent
+	AirUtil       float32 // percent
+	UptimeSeconds uint32
+}
+
+// Telemetry is a TELEMETRY_APP payload. Exactly one variant is set.
 type Telemetry struct {
-	BatteryLevel uint32  // percent 0–100
-	Voltage      float32 // volts
-	ChannelUtil  float32 // percent
-	AirUtil      float32 // percent
+	Time        uint32 // unix seconds
+	Device      *DeviceMetrics
+	Environment *EnvironmentMetrics
+	Power       *PowerMetrics
+}
+
+// EnvironmentMetrics carries readings from attached environment sensors.
+// Fields are optional on the wire, so a nil pointer means "not reported"
+// rather than zero.
+type EnvironmentMetrics struct {
+	Temperature        *float32 // °C
+	RelativeHumidity   *float32 // percent
+	BarometricPressure *float32 // hPa
+	GasResistance      *float32 // MΩ
+	Voltage            *float32 // volts
+	Current            *float32 // mA
+	IAQ                *uint32  // indoor air quality index 0–500
+	Lux                *float32
+}
+
+// PowerMetrics carries readings from an INA-style power monitor.
+type PowerMetrics struct {
+	Ch1Voltage float32 // volts
+	Ch1Current float32 // mA
+	Ch2Voltage float32
+	Ch2Current float32
+	Ch3Voltage float32
+	Ch3Current float32
 }
 
 // Position holds GPS coordinates from POSITION_APP packets.
 type Position struct {
-	LatitudeI  int32   // degrees × 1e-7
-	LongitudeI int32   // degrees × 1e-7
-	Altitude   int32   // metres
-	Time       uint32  // unix seconds
-	PDOP       uint32  // position dilution of precision
+	LatitudeI     int32  // degrees × 1e-7
+	LongitudeI    int32  // degrees × 1e-7
+	Altitude      int32  // metres
+	Time          uint32 // unix seconds
+	Timestamp     uint32 // unix seconds of the GPS fix, if different from Time
+	PDOP          uint32 // position dilution of precision × 100
+	GroundSpeed   uint32 // m/s
+	GroundTrack   uint32 // heading, degrees × 1e-5
+	SatsInView    uint32
+	PrecisionBits uint32 // 32 = full precision; fewer bits = deliberately coarsened
+}
+
+// Lat returns the latitude in degrees.
+func (p *Position) Lat() float64 { return float64(p.LatitudeI) * 1e-7 }
+
+// Lon returns the longitude in degrees.
+func (p *Position) Lon() float64 { return float64(p.LongitudeI) * 1e-7 }
+
+// RoutingError mirrors Meshtastic's Routing.Error enum.
+type RoutingError uint32
+
+const (
+	RoutingNone             RoutingError = 0 // ACK
+	RoutingNoRoute          RoutingError = 1
+	RoutingGotNak           RoutingError = 2
+	RoutingTimeout          RoutingError = 3
+	RoutingNoInterface      RoutingError = 4
+	RoutingMaxRetransmit    RoutingError = 5
+	RoutingNoChannel        RoutingError = 6
+	RoutingTooLarge         RoutingError = 7
+	RoutingNoResponse       RoutingError = 8
+	RoutingDutyCycleLimit   RoutingError = 9
+	RoutingBadRequest       RoutingError = 32
+	RoutingNotAuthorized    RoutingError = 33
+	RoutingPKIFailed        RoutingError = 34
+	RoutingPKIUnknownPubkey RoutingError = 35
+)
+
+func (e RoutingError) String() string {
+	switch e {
+	case RoutingNone:
+		return "NONE"
+	case RoutingNoRoute:
+		return "NO_ROUTE"
+	case RoutingGotNak:
+		return "GOT_NAK"
+	case RoutingTimeout:
+		return "TIMEOUT"
+	case RoutingNoInterface:
+		return "NO_INTERFACE"
+	case RoutingMaxRetransmit:
+		return "MAX_RETRANSMIT"
+	case RoutingNoChannel:
+		return "NO_CHANNEL"
+	case RoutingTooLarge:
+		return "TOO_LARGE"
+	case RoutingNoResponse:
+		return "NO_RESPONSE"
+	case RoutingDutyCycleLimit:
+		return "DUTY_CYCLE_LIMIT"
+	case RoutingBadRequest:
+		return "BAD_REQUEST"
+	case RoutingNotAuthorized:
+		return "NOT_AUTHORIZED"
+	case RoutingPKIFailed:
+		return "PKI_FAILED"
+	case RoutingPKIUnknownPubkey:
+		return "PKI_UNKNOWN_PUBKEY"
+	default:
+		return fmt.Sprintf("ERROR_%d", uint32(e))
+	}
+}
+
+// Routing is a ROUTING_APP payload: either a route discovery message or,
+// far more commonly, an ACK/NAK carrying ErrorReason for MeshPacket.RequestID.
+type Routing struct {
+	RouteRequest *RouteDiscovery
+	RouteReply   *RouteDiscovery
+	ErrorReason  RoutingError
+}
+
+// IsAck reports whether r is a plain delivery acknowledgement.
+func (r *Routing) IsAck() bool {
+	return r.RouteRequest == nil && r.RouteReply == nil && r.ErrorReason == RoutingNone
+}
+
+// RouteDiscovery lists the node numbers (and per-hop SNR × 4) a traceroute took.
+type RouteDiscovery struct {
+	Route      []uint32
+	SNRTowards []int32
+	RouteBack  []uint32
+	SNRBack    []int32
+}
+
+// ChannelRole mirrors Meshtastic's Channel.Role enum.
+type ChannelRole int32
+
+const (
+	ChannelDisabled  ChannelRole = 0
+	ChannelPrimary   ChannelRole = 1
+	ChannelSecondary ChannelRole = 2
+)
+
+func (r ChannelRole) String() string {
+	switch r {
+	case ChannelPrimary:
+		return "PRIMARY"
+	case ChannelSecondary:
+		return "SECONDARY"
+	default:
+		return "DISABLED"
+	}
+}
+
+// Channel is one slot of the device's channel table.
+type Channel struct {
+	Index    int32
+	Role     ChannelRole
+	Settings *ChannelSettings
+}
+
+// ChannelSettings holds the per-channel radio and crypto settings.
+type ChannelSettings struct {
+	PSK               []byte
+	Name              string
+	ID                uint32
+	UplinkEnabled     bool
+	DownlinkEnabled   bool
+	PositionPrecision uint32 // ModuleSettings.position_precision
+	IsClientMuted     bool   // ModuleSettings.is_client_muted
+}
+
+// AdminMessage is an ADMIN_APP payload. Only the variants the gateway sends
+// are modelled; exactly one is set.
+type AdminMessage struct {
+	SetChannel         *Channel
+	BeginEditSettings  bool
+	CommitEditSettings bool
+}
+
+// Config carries the device configuration sections the gateway cares about.
+// Sections we do not interpret are left nil.
+type Config struct {
+	Device *DeviceConfig
+	LoRa   *LoRaConfig
+}
+
+// DeviceConfig is the subset of Config.DeviceConfig we interpret.
+type DeviceConfig struct {
+	Role string
+}
+
+// LoRaConfig is the subset of Config.LoRaConfig we interpret.
+type LoRaConfig struct {
+	UsePreset   bool
+	ModemPreset uint32
+	Region      uint32
+	HopLimit    uint32
+	TxEnabled   bool
+	TxPower     int32
+	ChannelNum  uint32
 }
 
 // ── Handler ───────────────────────────────────────────────────────────────
 
 // MeshtasticProtobuf handles encode/decode of Meshtastic wire frames.
-type MeshtasticProtobuf struct {
-	opts goproto.MarshalOptions
-}
+// It is stateless and safe for concurrent use.
+type MeshtasticProtobuf struct{}
 
 // New returns a ready MeshtasticProtobuf handler.
 func New() *MeshtasticProtobuf {
-	return &MeshtasticProtobuf{
-		opts: goproto.MarshalOptions{Deterministic: true},
-	}
+	return &MeshtasticProtobuf{}
 }
 
-// DecodeFromRadio parses a raw 4-byte-prefixed frame received from the device.
-// It validates structure and returns a typed FromRadio or an error.
+// DecodeFromRadio parses one FromRadio protobuf received from the device.
+// data is a frame payload as delivered by the transport, i.e. without the
+// 0x94 0xC3 stream header. It validates structure and returns a typed
+// FromRadio or an error.
 func (m *MeshtasticProtobuf) DecodeFromRadio(data []byte) (*FromRadio, error) {
-	if len(data) < 4 {
-		return nil, fmt.Errorf("proto: frame too short (%d bytes)", len(data))
-	}
-	length := binary.BigEndian.Uint32(data[:4])
-	payload := data[4:]
-	if uint32(len(payload)) < length {
-		return nil, fmt.Errorf("proto: frame truncated (want %d, got %d bytes)", length, len(payload))
+	if len(data) == 0 {
+		return nil, fmt.Errorf("proto: empty frame")
 	}
 
-	// TODO: unmarshal real Meshtastic proto once github.com/meshtastic/go is wired.
-	// For now, return a stub decoded packet so higher layers can be tested.
-	fr := &FromRadio{
-		Packet: &MeshPacket{
-			Payload: append([]byte(nil), payload[:length]...),
-			To:      0xFFFFFFFF, // broadcast
-		},
+	fr := &FromRadio{}
+	if err := fr.unmarshal(data); err != nil {
+		return nil, fmt.Errorf("proto: decode FromRadio: %w", err)
 	}
 	return fr, nil
 }
 
-// EncodeToRadio serialises a ToRadio message into a framed byte slice.
+// EncodeToRadio serialises a ToRadio message into a frame payload. The
+// transport adds the stream header when it writes the frame.
 func (m *MeshtasticProtobuf) EncodeToRadio(msg *ToRadio) ([]byte, error) {
 	if msg == nil {
 		return nil, fmt.Errorf("proto: cannot encode nil ToRadio")
 	}
-	if msg.Packet == nil {
-		return nil, fmt.Errorf("proto: ToRadio.Packet must not be nil")
+	if (msg.Packet == nil) == (msg.WantConfigID == 0) {
+		return nil, fmt.Errorf("proto: ToRadio must set exactly one of Packet or WantConfigID")
 	}
 
-	// TODO: marshal to real Meshtastic protobuf once github.com/meshtastic/go is wired.
-	// For now, use the raw Payload bytes directly.
-	payload := msg.Packet.Payload
+	payload := msg.marshal(nil)
+	if len(payload) > MaxFrameSize {
+		return nil, fmt.Errorf("proto: ToRadio too large (%d > %d bytes)", len(payload), MaxFrameSize)
+	}
+	return payload, nil
+}
+
+// EncodeFromRadio serialises a FromRadio message. Real devices produce these;
+// transports that stand in for a device (e.g. MQTT) use it to hand the
+// gateway frames in the form it expects.
+func (m *MeshtasticProtobuf) EncodeFromRadio(msg *FromRadio) ([]byte, error) {
+	if msg == nil {
+		return nil, fmt.Errorf("proto: cannot encode nil FromRadio")
+	}
+	payload := msg.marshal(nil)
+	if len(payload) == 0 {
+		return nil, fmt.Errorf("proto: FromRadio has no variant set")
+	}
+	if len(payload) > MaxFrameSize {
+		return nil, fmt.Errorf("proto: FromRadio too large (%d > %d bytes)", len(payload), MaxFrameSize)
+	}
+	return payload, nil
+}
 
-	hdr := make([]byte, 4)
-	binary.BigEndian.PutUint32(hdr, uint32(len(payload)))
-	return append(hdr, payload...), nil
+// DecodeToRadio parses a frame payload produced by EncodeToRadio.
+func (m *MeshtasticProtobuf) DecodeToRadio(data []byte) (*ToRadio, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("proto: empty frame")
+	}
+	tr := &ToRadio{}
+	if err := tr.unmarshal(data); err != nil {
+		return nil, fmt.Errorf("proto: decode ToRadio: %w", err)
+	}
+	return tr, nil
+}
+
+// DecodeServiceEnvelope parses an MQTT message published under msh/.../2/e/.
+func (m *MeshtasticProtobuf) DecodeServiceEnvelope(data []byte) (*ServiceEnvelope, error) {
+	env := &ServiceEnvelope{}
+	if err := env.unmarshal(data); err != nil {
+		return nil, fmt.Errorf("proto: decode ServiceEnvelope: %w", err)
+	}
+	if env.Packet == nil {
+		return nil, fmt.Errorf("proto: ServiceEnvelope without packet")
+	}
+	return env, nil
+}
+
+// EncodeServiceEnvelope serialises env for publishing to MQTT.
+func (m *MeshtasticProtobuf) EncodeServiceEnvelope(env *ServiceEnvelope) ([]byte, error) {
+	if env == nil || env.Packet == nil {
+		return nil, fmt.Errorf("proto: ServiceEnvelope requires a packet")
+	}
+	return env.marshal(nil), nil
 }
 
 // MessageTypeLabel returns a human-readable label for a PortNum.
@@ -147,6 +458,8 @@ func MessageTypeLabel(p PortNum) string {
 		return "TELEMETRY_APP"
 	case PortRouting:
 		return "ROUTING_APP"
+	case PortAdmin:
+		return "ADMIN_APP"
 	default:
 		return fmt.Sprintf("UNKNOWN(%d)", p)
 	}
@@ -0,0 +1,328 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrMessageNotFound is returned by GetMessage for an unknown ID.
+var ErrMessageNotFound = errors.New("message not found")
+
+// Message is one text message, received or sent.
+type Message struct {
+	ID          int64     `json:"id"`
+	MeshID      string    `json:"mesh_id"`   // Meshtastic packet ID, decimal
+	FromNode    string    `json:"from_node"` // "!deadbeef"
+	ToNode      string    `json:"to_node"`   // "!deadbeef" or "broadcast"
+	Channel     int       `json:"channel"`
+	Port        uint32    `json:"port"` // Meshtastic PortNum; 0 for rows older than the port column
+	Payload     []byte    `json:"payload"`
+	ReceivedAt  time.Time `json:"received_at"`
+	Synced      bool      `json:"synced"`
+	Direction   string    `json:"direction"`
+	Status      string    `json:"status"`
+	ErrorReason string    `json:"error_reason,omitempty"`
+	Copies      int       `json:"copies"`
+	RxSNR       float32   `json:"rx_snr"`
+	RxRSSI      int32     `json:"rx_rssi"`
+	HopsAway    int       `json:"hops_away"`
+}
+
+// MessageQuery selects messages for ListMessages. Zero fields match every
+// message.
+type MessageQuery struct {
+	Channel   *int      // nil = any channel
+	Node      string    // sender or recipient, "!deadbeef"
+	Direction string    // DirectionIn or DirectionOut
+	Port      uint32    // Meshtastic PortNum
+	Since     time.Time // received_at >= Since
+	Until     time.Time // received_at < Until
+	Unsynced  bool      // only messages not yet replicated
+	Before    int64     // cursor: only messages with ID < Before
+	Limit     int       // page size; <= 0 means defaultMessageLimit
+}
+
+const (
+	defaultMessageLimit = 100
+
+	// idChunk bounds the placeholders in one bulk statement, well under
+	// SQLite's variable limit.
+	idChunk = 500
+)
+
+// messageColumns is the column list shared by GetMessage and ListMessages,
+// in scanMessage order.
+const messageColumns = `id, mesh_id, from_node, to_node, channel, port, payload,
+	received_at, synced, direction, status, error_reason,
+	copies, rx_snr, rx_rssi, hops_away`
+
+// InsertMessage stores m and returns its row ID. A message is identified by
+// its sender and packet ID: if that pair is already stored, the stored row
+// is left as is and its ID returned.
+func (db *DB) InsertMessage(m *Message) (int64, error) {
+	res, err := db.Exec(`
+		INSERT INTO messages (mesh_id, from_node, to_node, channel, port, payload,
+		                      received_at, synced, direction, status, error_reason,
+		                      copies, rx_snr, rx_rssi, hops_away)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (from_node, mesh_id) DO NOTHING`,
+		m.MeshID, m.FromNode, m.ToNode, m.Channel, m.Port, m.Payload,
+		m.ReceivedAt.UnixMilli(), m.Synced, m.Direction, m.Status, m.ErrorReason,
+		m.Copies, m.RxSNR, m.RxRSSI, m.HopsAway,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("store: insert message: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		return res.LastInsertId()
+	}
+	var id int64
+	if err := db.QueryRow(
+		`SELECT id FROM messages WHERE from_node = ? AND mesh_id = ?`, m.FromNode, m.MeshID,
+	).Scan(&id); err != nil {
+		return 0, fmt.Errorf("store: insert message: %w", err)
+	}
+	return id, nil
+}
+
+// GetMessage returns the message with row id, or ErrMessageNotFound.
+func (db *DB) GetMessage(id int64) (*Message, error) {
+	m, err := scanMessage(db.QueryRow(`SELECT `+messageColumns+` FROM messages WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("store: get message %d: %w", id, ErrMessageNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("store: get message %d: %w", id, err)
+	}
+	return m, nil
+}
+
+// ListMessages returns one page of the messages matching q, newest first,
+// and the cursor for the next page: pass it as q.Before to continue. The
+// cursor is 0 on the last page.
+func (db *DB) ListMessages(q MessageQuery) ([]*Message, int64, error) {
+	limit := q.Limit
+	if limit <= 0 {
+		limit = defaultMessageLimit
+	}
+
+	var (
+		where []string
+		args  []any
+	)
+	if q.Channel != nil {
+		where, args = append(where, "channel = ?"), append(args, *q.Channel)
+	}
+	if q.Node != "" {
+		where, args = append(where, "(from_node = ? OR to_node = ?)"), append(args, q.Node, q.Node)
+	}
+	if q.Direction != "" {
+		where, args = append(where, "direction = ?"), append(args, q.Direction)
+	}
+	if q.Port != 0 {
+		where, args = append(where, "port = ?"), append(args, q.Port)
+	}
+	if !q.Since.IsZero() {
+		where, args = append(where, "received_at >= ?"), append(args, q.Since.UnixMilli())
+	}
+	if !q.Until.IsZero() {
+		where, args = append(where, "received_at < ?"), append(args, q.Until.UnixMilli())
+	}
+	if q.Unsynced {
+		where = append(where, "synced = 0")
+	}
+	if q.Before > 0 {
+		where, args = append(where, "id < ?"), append(args, q.Before)
+	}
+
+	query := `SELECT ` + messageColumns + ` FROM messages`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	// One extra row tells whether there is a next page.
+	query += ` ORDER BY id DESC LIMIT ?`
+	args = append(args, limit+1)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("store: list messages: %w", err)
+	}
+	defer rows.Close()
+
+	out := []*Message{}
+	for rows.Next() {
+		m, err := scanMessage(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("store: list messages: %w", err)
+		}
+		out = append(out, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("store: list messages: %w", err)
+	}
+
+	var next int64
+	if len(out) > limit {
+		out = out[:limit]
+		next = out[limit-1].ID
+	}
+	return out, next, nil
+}
+
+// MarkSynced flags the messages with the given row IDs as replicated.
+// Unknown IDs are ignored.
+func (db *DB) MarkSynced(ids ...int64) error {
+	if _, err := db.execIDs(`UPDATE messages SET synced = 1 WHERE id IN `, ids); err != nil {
+		return fmt.Errorf("store: mark synced: %w", err)
+	}
+	return nil
+}
+
+// DeleteMessages deletes the messages with the given row IDs and returns
+// how many existed.
+func (db *DB) DeleteMessages(ids ...int64) (int64, error) {
+	n, err := db.execIDs(`DELETE FROM messages WHERE id IN `, ids)
+	if err != nil {
+		return 0, fmt.Errorf("store: delete messages: %w", err)
+	}
+	return n, nil
+}
+
+// Message directions.
+const (
+	DirectionIn  = "in"  // received from the mesh
+	DirectionOut = "out" // originated by this gateway
+)
+
+// Message delivery states. Inbound messages are always MessageStatusReceived;
+// outbound messages move queued → sent → acked, or end in failed. A message
+// handed to a link that cannot report mesh ACKs (an MQTT broker) ends in
+// relayed instead.
+const (
+	MessageStatusReceived = "received"
+	MessageStatusQueued   = "queued"
+	MessageStatusSent     = "sent"
+	MessageStatusAcked    = "acked"
+	MessageStatusFailed   = "failed"
+	MessageStatusRelayed  = "relayed"
+)
+
+// SetMessageStatus updates the delivery state of the message with row id.
+// reason explains a failure (e.g. a Routing.Error name) and may be empty.
+func (db *DB) SetMessageStatus(id int64, status, reason string) error {
+	res, err := db.Exec(
+		`UPDATE messages SET status = ?, error_reason = ? WHERE id = ?`,
+		status, reason, id)
+	if err != nil {
+		return fmt.Errorf("store: set message status: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("store: set message status: message %d not found", id)
+	}
+	return nil
+}
+
+// PendingMessages returns the outbound messages still queued or sent, i.e.
+// without a final acked or failed state, oldest first.
+func (db *DB) PendingMessages() ([]*Message, error) {
+	rows, err := db.Query(`SELECT `+messageColumns+` FROM messages
+		WHERE direction = ? AND status IN (?, ?) ORDER BY id`,
+		DirectionOut, MessageStatusQueued, MessageStatusSent)
+	if err != nil {
+		return nil, fmt.Errorf("store: pending messages: %w", err)
+	}
+	defer rows.Close()
+
+	var out []*Message
+	for rows.Next() {
+		m, err := scanMessage(rows)
+		if err != nil {
+			return nil, fmt.Errorf("store: pending messages: %w", err)
+		}
+		out = append(out, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: pending messages: %w", err)
+	}
+	return out, nil
+}
+
+// Reception summarises every copy of an inbound packet heard so far.
+type Reception struct {
+	Copies   int     // copies heard, across rebroadcasts and transports
+	RxSNR    float32 // best SNR, dB
+	RxRSSI   int32   // best RSSI, dBm; 0 when no copy carried radio metadata
+	HopsAway int     // fewest hops travelled; -1 when unknown
+}
+
+// SetMessageReception updates the reception summary of the message with
+// row id.
+func (db *DB) SetMessageReception(id int64, rx Reception) error {
+	res, err := db.Exec(
+		`UPDATE messages SET copies = ?, rx_snr = ?, rx_rssi = ?, hops_away = ? WHERE id = ?`,
+		rx.Copies, rx.RxSNR, rx.RxRSSI, rx.HopsAway, id)
+	if err != nil {
+		return fmt.Errorf("store: set message reception: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("store: set message reception: message %d not found", id)
+	}
+	return nil
+}
+
+// ── internal ──────────────────────────────────────────────────────────────
+
+// execIDs runs prefix followed by a parenthesised list of ids, in chunks of
+// idChunk within one transaction, and returns the rows affected.
+func (db *DB) execIDs(prefix string, ids []int64) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	var total int64
+	for len(ids) > 0 {
+		chunk := ids[:min(len(ids), idChunk)]
+		ids = ids[len(chunk):]
+
+		args := make([]any, len(chunk))
+		for i, id := range chunk {
+			args[i] = id
+		}
+		marks := strings.Repeat("?, ", len(chunk)-1) + "?"
+		res, err := tx.Exec(prefix+`(`+marks+`)`, args...)
+		if err != nil {
+			return 0, err
+		}
+		n, _ := res.RowsAffected()
+		total += n
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// scanMessage reads one row selected with messageColumns.
+func scanMessage(row interface{ Scan(...any) error }) (*Message, error) {
+	var (
+		m  Message
+		at int64
+	)
+	if err := row.Scan(
+		&m.ID, &m.MeshID, &m.FromNode, &m.ToNode, &m.Channel, &m.Port, &m.Payload,
+		&at, &m.Synced, &m.Direction, &m.Status, &m.ErrorReason,
+		&m.Copies, &m.RxSNR, &m.RxRSSI, &m.HopsAway,
+	); err != nil {
+		return nil, err
+	}
+	m.ReceivedAt = time.UnixMilli(at).UTC()
+	return &m, nil
+}
@@ -0,0 +1,188 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+var msgEpoch = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// insertMessages stores the messages in order and returns their row IDs.
+func insertMessages(t *testing.T, db *DB, msgs ...*Message) []int64 {
+	t.Helper()
+	ids := make([]int64, len(msgs))
+	for i, m := range msgs {
+		id, err := db.InsertMessage(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[i] = id
+	}
+	return ids
+}
+
+func testMessage(meshID int, from string, mod func(*Message)) *Message {
+	m := &Message{
+		MeshID: fmt.Sprint(meshID), FromNode: from, ToNode: "broadcast",
+		Port: 1, Payload: []byte("hi"), ReceivedAt: msgEpoch.Add(time.Duration(meshID) * time.Minute),
+		Direction: DirectionIn, Status: MessageStatusReceived, Copies: 1, HopsAway: -1,
+	}
+	if mod != nil {
+		mod(m)
+	}
+	return m
+}
+
+func TestListMessagesFilters(t *testing.T) {
+	db := openTestDB(t)
+	ch2 := 2
+	ids := insertMessages(t, db,
+		testMessage(1, "!00000001", nil),
+		testMessage(2, "!00000002", func(m *Message) { m.Channel = 2 }),
+		testMessage(3, "!00000001", func(m *Message) { m.ToNode = "!00000003"; m.Port = 67 }),
+		testMessage(4, "!0000000a", func(m *Message) {
+			m.ToNode, m.Direction, m.Status = "!00000002", DirectionOut, MessageStatusQueued
+		}),
+		testMessage(5, "!00000003", func(m *Message) { m.Synced = true }),
+	)
+
+	tests := []struct {
+		name string
+		q    MessageQuery
+		want []int // indexes into ids, newest first
+	}{
+		{"all", MessageQuery{}, []int{4, 3, 2, 1, 0}},
+		{"channel", MessageQuery{Channel: &ch2}, []int{1}},
+		{"node as sender or recipient", MessageQuery{Node: "!00000002"}, []int{3, 1}},
+		{"direction", MessageQuery{Direction: DirectionOut}, []int{3}},
+		{"port", MessageQuery{Port: 67}, []int{2}},
+		{"since", MessageQuery{Since: msgEpoch.Add(4 * time.Minute)}, []int{4, 3}},
+		{"until", MessageQuery{Until: msgEpoch.Add(2 * time.Minute)}, []int{0}},
+		{"unsynced", MessageQuery{Unsynced: true}, []int{3, 2, 1, 0}},
+		{"before", MessageQuery{Before: ids[2]}, []int{1, 0}},
+		{"combined", MessageQuery{Node: "!00000001", Port: 1}, []int{0}},
+		{"no match", MessageQuery{Node: "!ffffffff"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, next, err := db.ListMessages(tt.q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if next != 0 {
+				t.Errorf("next = %d, want 0", next)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d messages, want %d", len(got), len(tt.want))
+			}
+			for i, w := range tt.want {
+				if got[i].ID != ids[w] {
+					t.Errorf("message %d: ID %d, want %d", i, got[i].ID, ids[w])
+				}
+			}
+		})
+	}
+}
+
+func TestListMessagesCursor(t *testing.T) {
+	tests := []struct {
+		name     string
+		stored   int
+		limit    int
+		wantLen  int
+		wantNext bool
+	}{
+		{"fewer than limit", 2, 3, 2, false},
+		{"exactly limit", 3, 3, 3, false},
+		{"one past limit", 4, 3, 3, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openTestDB(t)
+			for i := 1; i <= tt.stored; i++ {
+				insertMessages(t, db, testMessage(i, "!00000001", nil))
+			}
+			page, next, err := db.ListMessages(MessageQuery{Limit: tt.limit})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(page) != tt.wantLen || (next != 0) != tt.wantNext {
+				t.Fatalf("page of %d, next %d; want %d, next %v", len(page), next, tt.wantLen, tt.wantNext)
+			}
+			if !tt.wantNext {
+				return
+			}
+			if next != page[len(page)-1].ID {
+				t.Errorf("next = %d, want the last ID on the page, %d", next, page[len(page)-1].ID)
+			}
+			rest, next, err := db.ListMessages(MessageQuery{Limit: tt.limit, Before: next})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rest) != tt.stored-tt.limit || next != 0 {
+				t.Errorf("second page of %d, next %d; want %d, 0", len(rest), next, tt.stored-tt.limit)
+			}
+		})
+	}
+}
+
+func TestInsertMessageConflict(t *testing.T) {
+	db := openTestDB(t)
+	first := insertMessages(t, db, testMessage(7, "!00000001", nil))[0]
+	insertMessages(t, db, testMessage(8, "!00000001", nil))
+
+	// The same packet again, as relayed by another node.
+	dup := testMessage(7, "!00000001", func(m *Message) { m.Payload, m.Copies = []byte("changed"), 2 })
+	id, err := db.InsertMessage(dup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != first {
+		t.Errorf("duplicate got ID %d, want the stored %d", id, first)
+	}
+	m, err := db.GetMessage(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(m.Payload) != "hi" || m.Copies != 1 {
+		t.Errorf("stored row changed: %+v", m)
+	}
+
+	// The same packet ID from another sender is a different message.
+	if id, _ := db.InsertMessage(testMessage(7, "!00000002", nil)); id == first {
+		t.Error("packet ID of another sender matched")
+	}
+	if _, err := db.GetMessage(999); !errors.Is(err, ErrMessageNotFound) {
+		t.Errorf("GetMessage(999) = %v, want ErrMessageNotFound", err)
+	}
+}
+
+func TestMessageIDChunks(t *testing.T) {
+	db := openTestDB(t)
+	n := 2*idChunk + 3
+	var ids []int64
+	for i := 1; i <= n; i++ {
+		ids = append(ids, insertMessages(t, db, testMessage(i, "!00000001", nil))...)
+	}
+	// Unknown IDs are ignored.
+	ids = append(ids, 1_000_000)
+
+	if err := db.MarkSynced(ids...); err != nil {
+		t.Fatal(err)
+	}
+	if unsynced, _, err := db.ListMessages(MessageQuery{Unsynced: true}); err != nil || len(unsynced) != 0 {
+		t.Errorf("unsynced after MarkSynced = %d, %v", len(unsynced), err)
+	}
+	deleted, err := db.DeleteMessages(ids...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != int64(n) {
+		t.Errorf("deleted %d, want %d", deleted, n)
+	}
+	if left, _, err := db.ListMessages(MessageQuery{}); err != nil || len(left) != 0 {
+		t.Errorf("left after DeleteMessages = %d, %v", len(left), err)
+	}
+}
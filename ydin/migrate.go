@@ -0,0 +1,271 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrSchemaTooNew means the database was migrated by a newer build. Running
+// an older build against it could silently lose data, so it is refused.
+var ErrSchemaTooNew = errors.New("database schema is newer than this build")
+
+// Migration is one numbered schema change. Up and Down may hold several
+// statements; each direction runs in a single transaction together with
+// the schema_migrations bookkeeping, so a step applies entirely or not at
+// all.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus reports whether one migration has been applied.
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt time.Time // zero if not applied
+}
+
+const ddlSchemaMigrations = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version    INTEGER PRIMARY KEY,
+    name       TEXT    NOT NULL,
+    applied_at INTEGER NOT NULL  -- Unix milliseconds
+);
+`
+
+// LatestVersion is the schema version this build migrates to.
+func LatestVersion() int {
+	return migrations[len(migrations)-1].Version
+}
+
+// SchemaVersion returns the version of db's schema: the highest applied
+// migration, or for a database that predates versioned migrations, the
+// version its tables correspond to. It does not modify db.
+func SchemaVersion(db *DB) (int, error) {
+	tracked, err := tableExists(db, "schema_migrations")
+	if err != nil {
+		return 0, fmt.Errorf("store: schema version: %w", err)
+	}
+	if !tracked {
+		return legacyVersion(db)
+	}
+	var v int
+	if err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&v); err != nil {
+		return 0, fmt.Errorf("store: schema version: %w", err)
+	}
+	return v, nil
+}
+
+// Migrate brings db up to LatestVersion.
+func Migrate(db *DB) error {
+	return MigrateTo(db, LatestVersion())
+}
+
+// MigrateTo applies every pending migration up to and including target.
+func MigrateTo(db *DB, target int) error {
+	if target < 0 || target > LatestVersion() {
+		return fmt.Errorf("store: migrate: no version %d (latest is %d)", target, LatestVersion())
+	}
+	current, err := initMigrations(db)
+	if err != nil {
+		return err
+	}
+	if current > LatestVersion() {
+		return fmt.Errorf("store: migrate: %w (database %d, build %d)", ErrSchemaTooNew, current, LatestVersion())
+	}
+	if target < current {
+		return fmt.Errorf("store: migrate: database is at version %d; roll back to reach %d", current, target)
+	}
+	for _, m := range migrations {
+		if m.Version <= current || m.Version > target {
+			continue
+		}
+		if err := runMigration(db, m, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rollback reverts applied migrations, newest first, until db is at target.
+func Rollback(db *DB, target int) error {
+	current, err := initMigrations(db)
+	if err != nil {
+		return err
+	}
+	if current > LatestVersion() {
+		return fmt.Errorf("store: rollback: %w (database %d, build %d)", ErrSchemaTooNew, current, LatestVersion())
+	}
+	if target < 0 || target > current {
+		return fmt.Errorf("store: rollback: database is at version %d; cannot roll back to %d", current, target)
+	}
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+		if m.Version <= target || m.Version > current {
+			continue
+		}
+		if err := runMigration(db, m, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Status lists every migration this build knows and whether db has it.
+func Status(db *DB) ([]MigrationStatus, error) {
+	if _, err := initMigrations(db); err != nil {
+		return nil, err
+	}
+	applied := make(map[int]time.Time)
+	rows, err := db.Query(`SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("store: migration status: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var v int
+		var at int64
+		if err := rows.Scan(&v, &at); err != nil {
+			return nil, fmt.Errorf("store: migration status: %w", err)
+		}
+		applied[v] = time.UnixMilli(at).UTC()
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: migration status: %w", err)
+	}
+
+	out := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		at, ok := applied[m.Version]
+		out = append(out, MigrationStatus{Version: m.Version, Name: m.Name, Applied: ok, AppliedAt: at})
+	}
+	return out, nil
+}
+
+// ── internal ──────────────────────────────────────────────────────────────
+
+// initMigrations creates schema_migrations if needed, recording the
+// migrations a pre-versioning database already reflects, and returns the
+// current version. Builds that tracked the nodes table in PRAGMA
+// user_version left it set; it is cleared so that schema_migrations is the
+// only record of the schema.
+func initMigrations(db *DB) (int, error) {
+	tracked, err := tableExists(db, "schema_migrations")
+	if err != nil {
+		return 0, fmt.Errorf("store: migrate: %w", err)
+	}
+	if tracked {
+		return SchemaVersion(db)
+	}
+	legacy, err := legacyVersion(db)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("store: migrate: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if _, err := tx.Exec(ddlSchemaMigrations); err != nil {
+		return 0, fmt.Errorf("store: migrate: %w", err)
+	}
+	now := time.Now().UnixMilli()
+	for _, m := range migrations[:legacy] {
+		if _, err := tx.Exec(
+			`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+			m.Version, m.Name, now,
+		); err != nil {
+			return 0, fmt.Errorf("store: migrate: %w", err)
+		}
+	}
+	if _, err := tx.Exec(`PRAGMA user_version = 0`); err != nil {
+		return 0, fmt.Errorf("store: migrate: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("store: migrate: %w", err)
+	}
+	return legacy, nil
+}
+
+func runMigration(db *DB, m Migration, up bool) error {
+	verb, stmt := "up", m.Up
+	if !up {
+		verb, stmt = "down", m.Down
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("store: migration %d %s: %w", m.Version, verb, err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if _, err := tx.Exec(stmt); err != nil {
+		return fmt.Errorf("store: migration %d (%s) %s: %w", m.Version, m.Name, verb, err)
+	}
+	if up {
+		_, err = tx.Exec(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+			m.Version, m.Name, time.Now().UnixMilli())
+	} else {
+		_, err = tx.Exec(`DELETE FROM schema_migrations WHERE version = ?`, m.Version)
+	}
+	if err != nil {
+		return fmt.Errorf("store: migration %d %s: %w", m.Version, verb, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("store: migration %d %s: %w", m.Version, verb, err)
+	}
+	return nil
+}
+
+// legacyVersion infers the version of a database created before
+// schema_migrations existed, when each build ran its whole schema with
+// IF NOT EXISTS. Each probe recognises one migration's change; the version
+// is the number of probes that pass in order. PRAGMA user_version, which
+// some of those builds set, is not consulted. Migrations from version 8 on
+// postdate versioning and have no probe.
+func legacyVersion(db *DB) (int, error) {
+	probes := []func() (bool, error){
+		func() (bool, error) { return tableExists(db, "messages") },
+		func() (bool, error) { return columnExists(db, "messages", "direction") },
+		func() (bool, error) { return columnExists(db, "messages", "error_reason") },
+		func() (bool, error) { return columnExists(db, "messages", "copies") },
+		func() (bool, error) { return tableExists(db, "positions") },
+		func() (bool, error) { return tableExists(db, "telemetry") },
+		func() (bool, error) { return tableExists(db, "nodes") },
+	}
+	for i, probe := range probes {
+		ok, err := probe()
+		if err != nil {
+			return 0, fmt.Errorf("store: inspect legacy schema: %w", err)
+		}
+		if !ok {
+			return i, nil
+		}
+	}
+	return len(probes), nil
+}
+
+func tableExists(db *DB, name string) (bool, error) {
+	var n int
+	err := db.QueryRow(
+		`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, name,
+	).Scan(&n)
+	return n > 0, err
+}
+
+func columnExists(db *DB, table, column string) (bool, error) {
+	var n int
+	err := db.QueryRow(
+		`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, table, column,
+	).Scan(&n)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return n > 0, err
+}
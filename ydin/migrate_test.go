@@ -0,0 +1,327 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// openRaw opens a database without migrating it.
+func openRaw(t *testing.T) *DB {
+	t.Helper()
+	db, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// legacyDB builds the schema of a pre-versioning build at version v: the
+// first v migrations, with no schema_migrations table.
+func legacyDB(t *testing.T, v int) *DB {
+	t.Helper()
+	db := openRaw(t)
+	for _, m := range migrations[:v] {
+		if _, err := db.Exec(m.Up); err != nil {
+			t.Fatalf("legacy schema %d: %v", m.Version, err)
+		}
+	}
+	return db
+}
+
+func userVersion(t *testing.T, db *DB) int {
+	t.Helper()
+	var v int
+	if err := db.QueryRow(`PRAGMA user_version`).Scan(&v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestMigrateClearsUserVersion(t *testing.T) {
+	// The builds that added the nodes table counted it in user_version.
+	db := legacyDB(t, 7)
+	if _, err := db.Exec(`PRAGMA user_version = 1`); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	if v := userVersion(t, db); v != 0 {
+		t.Errorf("user_version = %d, want 0", v)
+	}
+	status, err := Status(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range status {
+		if !s.Applied {
+			t.Errorf("migration %d (%s) not applied", s.Version, s.Name)
+		}
+	}
+}
+
+// schemaOf describes db's tables as "table: column type, ..." lines, plus
+// its index names, for comparing two databases.
+func schemaOf(t *testing.T, db *DB) []string {
+	t.Helper()
+	rows, err := db.Query(`SELECT type, name FROM sqlite_master
+		WHERE name NOT LIKE 'sqlite_%' AND name != 'schema_migrations' ORDER BY type, name`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var objects [][2]string
+	for rows.Next() {
+		var typ, name string
+		if err := rows.Scan(&typ, &name); err != nil {
+			t.Fatal(err)
+		}
+		objects = append(objects, [2]string{typ, name})
+	}
+	rows.Close()
+
+	var out []string
+	for _, o := range objects {
+		if o[0] != "table" {
+			out = append(out, o[0]+" "+o[1])
+			continue
+		}
+		cols, err := db.Query(`SELECT name, type, "notnull", COALESCE(dflt_value, '') FROM pragma_table_info(?) ORDER BY name`, o[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		line := "table " + o[1] + ":"
+		for cols.Next() {
+			var name, typ, dflt string
+			var notNull bool
+			if err := cols.Scan(&name, &typ, &notNull, &dflt); err != nil {
+				t.Fatal(err)
+			}
+			line += fmt.Sprintf(" %s %s %v %s,", name, typ, notNull, dflt)
+		}
+		cols.Close()
+		out = append(out, line)
+	}
+	return out
+}
+
+func TestMigrateBlank(t *testing.T) {
+	db := openRaw(t)
+	if v, err := SchemaVersion(db); err != nil || v != 0 {
+		t.Fatalf("blank SchemaVersion = %d, %v", v, err)
+	}
+	if err := Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := SchemaVersion(db); err != nil || v != LatestVersion() {
+		t.Errorf("SchemaVersion = %d, %v; want %d", v, err, LatestVersion())
+	}
+	// Migrating an up-to-date database is a no-op.
+	if err := Migrate(db); err != nil {
+		t.Errorf("second Migrate: %v", err)
+	}
+}
+
+func TestMigrateFromEveryVersion(t *testing.T) {
+	want := schemaOf(t, func() *DB {
+		db := openRaw(t)
+		if err := Migrate(db); err != nil {
+			t.Fatal(err)
+		}
+		return db
+	}())
+
+	check := func(t *testing.T, db *DB) {
+		t.Helper()
+		if err := Migrate(db); err != nil {
+			t.Fatal(err)
+		}
+		if v, err := SchemaVersion(db); err != nil || v != LatestVersion() {
+			t.Errorf("SchemaVersion = %d, %v; want %d", v, err, LatestVersion())
+		}
+		if got := schemaOf(t, db); !slices.Equal(got, want) {
+			t.Errorf("schema differs from a fresh database:\n got %q\nwant %q", got, want)
+		}
+	}
+
+	// Pre-versioning builds, up to version 7: the version is inferred from
+	// the tables.
+	for v := 1; v <= 7; v++ {
+		t.Run(fmt.Sprintf("legacy %d", v), func(t *testing.T) {
+			db := legacyDB(t, v)
+			if got, err := SchemaVersion(db); err != nil || got != v {
+				t.Fatalf("inferred version = %d, %v; want %d", got, err, v)
+			}
+			check(t, db)
+		})
+	}
+	for _, m := range migrations[:len(migrations)-1] {
+		t.Run(fmt.Sprintf("version %d", m.Version), func(t *testing.T) {
+			db := openRaw(t)
+			if err := MigrateTo(db, m.Version); err != nil {
+				t.Fatal(err)
+			}
+			if got, err := SchemaVersion(db); err != nil || got != m.Version {
+				t.Fatalf("SchemaVersion = %d, %v; want %d", got, err, m.Version)
+			}
+			check(t, db)
+		})
+	}
+}
+
+func TestMigrateRollbackRoundTrip(t *testing.T) {
+	db := openRaw(t)
+	if err := Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	want := schemaOf(t, db)
+
+	// Step down one version at a time, then all the way back up.
+	for v := LatestVersion() - 1; v >= 0; v-- {
+		if err := Rollback(db, v); err != nil {
+			t.Fatalf("Rollback to %d: %v", v, err)
+		}
+		if got, err := SchemaVersion(db); err != nil || got != v {
+			t.Fatalf("after Rollback to %d: SchemaVersion = %d, %v", v, got, err)
+		}
+	}
+	if got := schemaOf(t, db); len(got) != 0 {
+		t.Errorf("schema at version 0 = %q, want empty", got)
+	}
+	if err := Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	if got := schemaOf(t, db); !slices.Equal(got, want) {
+		t.Errorf("schema after round trip:\n got %q\nwant %q", got, want)
+	}
+
+	if err := Rollback(db, LatestVersion()+1); err == nil {
+		t.Error("Rollback past the current version succeeded")
+	}
+	if err := MigrateTo(db, 1); err == nil {
+		t.Error("MigrateTo an older version succeeded")
+	}
+}
+
+func TestLegacyVersionProbes(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		want   int
+	}{
+		{"empty", ``, 0},
+		{"unrelated table", `CREATE TABLE other (x INTEGER)`, 0},
+		{"messages only", `CREATE TABLE messages (id INTEGER)`, 1},
+		{"direction", `CREATE TABLE messages (id INTEGER, direction TEXT)`, 2},
+		{"error reason", `CREATE TABLE messages (id INTEGER, direction TEXT, error_reason TEXT)`, 3},
+		// Probes pass in order: a later table without the earlier columns
+		// does not count.
+		{"gap", `CREATE TABLE messages (id INTEGER); CREATE TABLE positions (id INTEGER)`, 1},
+		{"copies", `CREATE TABLE messages (id INTEGER, direction TEXT, error_reason TEXT, copies INTEGER)`, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openRaw(t)
+			if tt.schema != "" {
+				if _, err := db.Exec(tt.schema); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got, err := legacyVersion(db); err != nil || got != tt.want {
+				t.Errorf("legacyVersion = %d, %v; want %d", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestMigrationDropsDuplicatePackets(t *testing.T) {
+	db := openRaw(t)
+	if err := MigrateTo(db, 7); err != nil {
+		t.Fatal(err)
+	}
+	insert := func(from, meshID string) error {
+		_, err := db.Exec(`INSERT INTO messages (mesh_id, from_node, payload, received_at) VALUES (?, ?, x'', 0)`, meshID, from)
+		return err
+	}
+	for _, r := range [][2]string{
+		{"!00000001", "10"}, {"!00000001", "10"}, {"!00000002", "10"}, {"!00000001", "10"}, {"!00000001", "11"},
+	} {
+		if err := insert(r[0], r[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := MigrateTo(db, 8); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := db.Query(`SELECT id FROM messages ORDER BY id`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	// The first copy of each packet survives.
+	if want := []int64{1, 3, 5}; !slices.Equal(ids, want) {
+		t.Errorf("ids = %v, want %v", ids, want)
+	}
+	if err := insert("!00000001", "10"); err == nil {
+		t.Error("duplicate packet inserted after migration 8")
+	}
+}
+
+func TestSchemaTooNew(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, 'future', 0)`, LatestVersion()+1); err != nil {
+		t.Fatal(err)
+	}
+	if err := Migrate(db); !errors.Is(err, ErrSchemaTooNew) {
+		t.Errorf("Migrate = %v, want ErrSchemaTooNew", err)
+	}
+	db.Close()
+
+	if db, err := Open(path); !errors.Is(err, ErrSchemaTooNew) {
+		if db != nil {
+			db.Close()
+		}
+		t.Errorf("Open = %v, want ErrSchemaTooNew", err)
+	}
+}
+
+func TestMigrationMessagePortUnknown(t *testing.T) {
+	db := openRaw(t)
+	if err := MigrateTo(db, 8); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO messages (mesh_id, from_node, payload, received_at) VALUES ('1', '!00000001', x'', 0)`); err != nil {
+		t.Fatal(err)
+	}
+	if err := MigrateTo(db, 9); err != nil {
+		t.Fatal(err)
+	}
+	// Nothing recorded the port of an earlier row.
+	var port int
+	if err := db.QueryRow(`SELECT port FROM messages`).Scan(&port); err != nil {
+		t.Fatal(err)
+	}
+	if port != 0 {
+		t.Errorf("port = %d, want 0 (unknown)", port)
+	}
+}
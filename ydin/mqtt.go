@@ -0,0 +1,327 @@
+package transport
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"go.uber.org/zap"
+
+	meshproto "github.com/gg-glitch-88/meshigo-kore/ydin/proto"
+)
+
+const (
+	mqttFrameChanSize   = 256
+	mqttQoS             = 1
+	mqttConnectTimeout  = 10 * time.Second
+	mqttMaxBackoff      = 60 * time.Second
+	mqttPublishTimeout  = 10 * time.Second
+	mqttDisconnectQuiet = 250 // ms
+	mqttDefaultRoot     = "msh"
+)
+
+// MQTTChannel is a channel bridged over MQTT. PSK is the raw channel key as
+// shown in the Meshtastic apps once base64-decoded ({0x01} for "AQ==").
+type MQTTChannel struct {
+	Name string
+	PSK  []byte
+}
+
+// MQTTOptions configures an MQTTTransport.
+type MQTTOptions struct {
+	Broker   string // e.g. "tcp://localhost:1883"
+	Username string
+	Password string
+	ClientID string // defaults to "meshkore-<gateway id>"
+
+	Root   string // topic root, defaults to "msh"
+	Region string // e.g. "EU_868"; topics are <root>/<region>/2/e/<channel>/...
+
+	// Channels are subscribed to, and outbound packets on channel index i are
+	// published to Channels[i]. Defaults to LongFast with the default key.
+	Channels []MQTTChannel
+
+	// GatewayID is the node number this gateway publishes as. It also stands
+	// in for the device's own node number during the config handshake.
+	GatewayID uint32
+
+	// Dial, when set, replaces the network dial to Broker. Tests use it to
+	// attach the transport to an in-process broker over net.Pipe.
+	Dial func() (net.Conn, error)
+}
+
+// MQTTTransport bridges a mesh through an MQTT broker (typically a site's
+// Mosquitto) instead of a locally attached radio. It subscribes to the
+// Meshtastic ServiceEnvelope topics and presents every packet to the gateway
+// as a FromRadio frame, exactly as a device would. Outbound ToRadio packets
+// are encrypted with the channel key and published as ServiceEnvelopes.
+//
+// There is no device behind the broker, so the transport answers the
+// want_config handshake itself with GatewayID and the configured channels.
+// The broker accepting a packet says nothing about delivery on the mesh, so
+// MQTTTransport does not report ACKs (see AckReporter).
+type MQTTTransport struct {
+	opts     MQTTOptions
+	log      *zap.Logger
+	proto    *meshproto.MeshtasticProtobuf
+	keys     *meshproto.Keyring
+	frames   chan ProtoFrame
+	state    atomic.Int32 // ConnectionState
+	connects atomic.Uint64
+	mu       sync.Mutex
+	client   mqtt.Client
+}
+
+// NewMQTTTransport validates opts and constructs an MQTTTransport. It does
+// not contact the broker until Connect.
+func NewMQTTTransport(opts MQTTOptions, log *zap.Logger) (*MQTTTransport, error) {
+	if opts.Broker == "" && opts.Dial == nil {
+		return nil, fmt.Errorf("mqtt: broker address is required")
+	}
+	if opts.Region == "" {
+		return nil, fmt.Errorf("mqtt: region is required")
+	}
+	if opts.GatewayID == 0 || opts.GatewayID == meshproto.BroadcastAddr {
+		return nil, fmt.Errorf("mqtt: gateway ID must be a node number")
+	}
+	if opts.Root == "" {
+		opts.Root = mqttDefaultRoot
+	}
+	if opts.ClientID == "" {
+		opts.ClientID = fmt.Sprintf("meshkore-%08x", opts.GatewayID)
+	}
+	if len(opts.Channels) == 0 {
+		opts.Channels = []MQTTChannel{{Name: meshproto.ModemPresetName(0), PSK: []byte{1}}}
+	}
+
+	keys := meshproto.NewKeyring()
+	for _, ch := range opts.Channels {
+		if ch.Name == "" || strings.ContainsAny(ch.Name, "/+#") {
+			return nil, fmt.Errorf("mqtt: invalid channel name %q", ch.Name)
+		}
+		if _, err := keys.Add(ch.Name, ch.PSK); err != nil {
+			return nil, fmt.Errorf("mqtt: channel %q: %w", ch.Name, err)
+		}
+	}
+
+	t := &MQTTTransport{
+		opts:   opts,
+		log:    log,
+		proto:  meshproto.New(),
+		keys:   keys,
+		frames: make(chan ProtoFrame, mqttFrameChanSize),
+	}
+	t.state.Store(int32(StateDisconnected))
+	return t, nil
+}
+
+func (t *MQTTTransport) Connect() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.client != nil {
+		return nil // client is already (re)connecting on its own
+	}
+
+	o := mqtt.NewClientOptions().
+		SetClientID(t.opts.ClientID).
+		SetUsername(t.opts.Username).
+		SetPassword(t.opts.Password).
+		SetCleanSession(true).
+		SetOrderMatters(false).
+		SetConnectTimeout(mqttConnectTimeout).
+		SetConnectRetry(true).
+		SetAutoReconnect(true).
+		SetMaxReconnectInterval(mqttMaxBackoff).
+		SetOnConnectHandler(t.onConnect).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			t.state.Store(int32(StateConnecting))
+			t.log.Warn("mqtt: connection lost, reconnecting", zap.Error(err))
+		}).
+		SetReconnectingHandler(func(mqtt.Client, *mqtt.ClientOptions) {
+			t.state.Store(int32(StateConnecting))
+		})
+	if t.opts.Broker != "" {
+		o.AddBroker(t.opts.Broker)
+	} else {
+		o.AddBroker("tcp://in-process:1883")
+	}
+	if t.opts.Dial != nil {
+		dial := t.opts.Dial
+		o.SetCustomOpenConnectionFn(func(*url.URL, mqtt.ClientOptions) (net.Conn, error) {
+			return dial()
+		})
+	}
+
+	t.client = mqtt.NewClient(o)
+	t.state.Store(int32(StateConnecting))
+	// With ConnectRetry the token only completes once connected, so we do
+	// not wait on it; onConnect flips the state.
+	t.client.Connect()
+	return nil
+}
+
+func (t *MQTTTransport) Disconnect() error {
+	t.mu.Lock()
+	client := t.client
+	t.client = nil
+	t.mu.Unlock()
+
+	if client != nil {
+		client.Disconnect(mqttDisconnectQuiet)
+	}
+	t.state.Store(int32(StateDisconnected))
+	return nil
+}
+
+// Send publishes a ToRadio packet, or answers a want_config request locally.
+func (t *MQTTTransport) Send(frame ProtoFrame) error {
+	t.mu.Lock()
+	client := t.client
+	t.mu.Unlock()
+
+	if client == nil || !client.IsConnectionOpen() {
+		return fmt.Errorf("mqtt: not connected")
+	}
+
+	msg, err := t.proto.DecodeToRadio(frame.Data)
+	if err != nil {
+		return fmt.Errorf("mqtt: send: %w", err)
+	}
+	if msg.WantConfigID != 0 {
+		t.answerConfig(msg.WantConfigID)
+		return nil
+	}
+	if msg.Packet == nil {
+		return fmt.Errorf("mqtt: send: empty ToRadio")
+	}
+	return t.publish(client, msg.Packet)
+}
+
+func (t *MQTTTransport) Receive() <-chan ProtoFrame { return t.frames }
+
+func (t *MQTTTransport) GetConnectionState() ConnectionState {
+	return ConnectionState(t.state.Load())
+}
+
+func (t *MQTTTransport) Connects() uint64 { return t.connects.Load() }
+
+// ReportsAcks is always false: no radio stands behind the broker to relay
+// a ROUTING_APP response.
+func (t *MQTTTransport) ReportsAcks(ProtoFrame) bool { return false }
+
+// ── internal ──────────────────────────────────────────────────────────────
+
+// topic returns the ServiceEnvelope topic prefix for channel.
+func (t *MQTTTransport) topic(channel string) string {
+	return fmt.Sprintf("%s/%s/2/e/%s", t.opts.Root, t.opts.Region, channel)
+}
+
+func (t *MQTTTransport) gatewayID() string {
+	return fmt.Sprintf("!%08x", t.opts.GatewayID)
+}
+
+// onConnect (re)subscribes on every connect: the session is clean, so the
+// broker forgets subscriptions when the connection drops.
+func (t *MQTTTransport) onConnect(c mqtt.Client) {
+	filters := make(map[string]byte, len(t.opts.Channels))
+	for _, ch := range t.opts.Channels {
+		filters[t.topic(ch.Name)+"/#"] = mqttQoS
+	}
+	tok := c.SubscribeMultiple(filters, t.onMessage)
+	if !tok.WaitTimeout(mqttConnectTimeout) || tok.Error() != nil {
+		t.log.Warn("mqtt: subscribe failed", zap.Error(tok.Error()))
+		t.state.Store(int32(StateFailed))
+		return
+	}
+	t.connects.Add(1)
+	t.state.Store(int32(StateConnected))
+	t.log.Info("mqtt: connected",
+		zap.String("broker", t.opts.Broker),
+		zap.Int("channels", len(filters)))
+}
+
+// onMessage unwraps one ServiceEnvelope into a FromRadio frame. The packet
+// is passed on still encrypted; the gateway decrypts it with its keyring.
+func (t *MQTTTransport) onMessage(_ mqtt.Client, m mqtt.Message) {
+	env, err := t.proto.DecodeServiceEnvelope(m.Payload())
+	if err != nil {
+		t.log.Debug("mqtt: bad envelope", zap.String("topic", m.Topic()), zap.Error(err))
+		return
+	}
+	if env.GatewayID == t.gatewayID() {
+		return // our own uplink echoed back
+	}
+	env.Packet.ViaMQTT = true
+	t.deliver(&meshproto.FromRadio{Packet: env.Packet})
+}
+
+// publish encrypts pkt with its channel key and publishes it.
+func (t *MQTTTransport) publish(client mqtt.Client, pkt *meshproto.MeshPacket) error {
+	if int(pkt.Channel) >= len(t.opts.Channels) {
+		return fmt.Errorf("mqtt: send: channel %d is not bridged", pkt.Channel)
+	}
+	name := t.opts.Channels[pkt.Channel].Name
+
+	out := *pkt
+	if out.From == 0 {
+		out.From = t.opts.GatewayID
+	}
+	if err := t.keys.Encrypt(&out, name); err != nil {
+		return fmt.Errorf("mqtt: send: %w", err)
+	}
+	data, err := t.proto.EncodeServiceEnvelope(&meshproto.ServiceEnvelope{
+		Packet:    &out,
+		ChannelID: name,
+		GatewayID: t.gatewayID(),
+	})
+	if err != nil {
+		return fmt.Errorf("mqtt: send: %w", err)
+	}
+
+	tok := client.Publish(t.topic(name)+"/"+t.gatewayID(), mqttQoS, false, data)
+	if !tok.WaitTimeout(mqttPublishTimeout) {
+		return fmt.Errorf("mqtt: send: publish timed out")
+	}
+	if err := tok.Error(); err != nil {
+		return fmt.Errorf("mqtt: send: %w", err)
+	}
+	return nil
+}
+
+// answerConfig replays a minimal want_config response: our node number,
+// the bridged channels (with their keys, so the gateway can decrypt) and
+// the completion marker.
+func (t *MQTTTransport) answerConfig(id uint32) {
+	t.deliver(&meshproto.FromRadio{MyInfo: &meshproto.MyNodeInfo{MyNodeNum: t.opts.GatewayID}})
+	for i, ch := range t.opts.Channels {
+		role := meshproto.ChannelSecondary
+		if i == 0 {
+			role = meshproto.ChannelPrimary
+		}
+		t.deliver(&meshproto.FromRadio{Channel: &meshproto.Channel{
+			Index:    int32(i),
+			Role:     role,
+			Settings: &meshproto.ChannelSettings{Name: ch.Name, PSK: ch.PSK},
+		}})
+	}
+	t.deliver(&meshproto.FromRadio{ConfigCompleteID: id})
+}
+
+func (t *MQTTTransport) deliver(fr *meshproto.FromRadio) {
+	data, err := t.proto.EncodeFromRadio(fr)
+	if err != nil {
+		t.log.Warn("mqtt: dropping frame", zap.Error(err))
+		return
+	}
+	select {
+	case t.frames <- ProtoFrame{Data: data, Timestamp: time.Now().UTC()}:
+	default:
+		t.log.Warn("mqtt: frame channel full – dropping frame")
+	}
+}
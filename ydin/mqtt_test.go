@@ -0,0 +1,214 @@
+package transport
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eclipse/paho.mqtt.golang/packets"
+	"go.uber.org/zap"
+
+	meshproto "github.com/gg-glitch-88/meshigo-kore/ydin/proto"
+)
+
+// fakeBroker is just enough of an MQTT 3.1.1 broker for one client over
+// net.Pipe: it accepts the connection and subscriptions, acknowledges
+// QoS 1 publishes and lets the test publish to the client.
+type fakeBroker struct {
+	published chan *packets.PublishPacket // client → broker
+	subscribe chan []string               // topic filters per SUBSCRIBE
+
+	mu   sync.Mutex // serialises writes to conn
+	conn net.Conn
+}
+
+func newFakeBroker() *fakeBroker {
+	return &fakeBroker{
+		published: make(chan *packets.PublishPacket, 16),
+		subscribe: make(chan []string, 4),
+	}
+}
+
+// dial is the MQTTOptions.Dial hook.
+func (b *fakeBroker) dial() (net.Conn, error) {
+	client, conn := net.Pipe()
+	b.mu.Lock()
+	b.conn = conn
+	b.mu.Unlock()
+	go b.serve(conn)
+	return client, nil
+}
+
+func (b *fakeBroker) serve(conn net.Conn) {
+	defer conn.Close()
+	for {
+		cp, err := packets.ReadPacket(conn)
+		if err != nil {
+			return
+		}
+		switch p := cp.(type) {
+		case *packets.ConnectPacket:
+			b.write(packets.NewControlPacket(packets.Connack))
+		case *packets.SubscribePacket:
+			ack := packets.NewControlPacket(packets.Suback).(*packets.SubackPacket)
+			ack.MessageID = p.MessageID
+			ack.ReturnCodes = p.Qoss
+			b.write(ack)
+			b.subscribe <- p.Topics
+		case *packets.PublishPacket:
+			if p.Qos == 1 {
+				ack := packets.NewControlPacket(packets.Puback).(*packets.PubackPacket)
+				ack.MessageID = p.MessageID
+				b.write(ack)
+			}
+			b.published <- p
+		case *packets.PingreqPacket:
+			b.write(packets.NewControlPacket(packets.Pingresp))
+		case *packets.DisconnectPacket:
+			return
+		}
+	}
+}
+
+func (b *fakeBroker) write(cp packets.ControlPacket) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	cp.Write(b.conn) //nolint:errcheck
+}
+
+// publish delivers payload on topic to the client at QoS 0.
+func (b *fakeBroker) publish(topic string, payload []byte) {
+	p := packets.NewControlPacket(packets.Publish).(*packets.PublishPacket)
+	p.TopicName = topic
+	p.Payload = payload
+	b.write(p)
+}
+
+func decodeFrame(t *testing.T, data []byte) *meshproto.FromRadio {
+	t.Helper()
+	fr, err := meshproto.New().DecodeFromRadio(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fr
+}
+
+func TestMQTTTransport(t *testing.T) {
+	const gatewayID = 0x0a0b0c0d
+	b := newFakeBroker()
+	tr, err := NewMQTTTransport(MQTTOptions{
+		Region:    "EU_868",
+		GatewayID: gatewayID,
+		Dial:      b.dial,
+	}, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer tr.Disconnect() //nolint:errcheck
+
+	select {
+	case topics := <-b.subscribe:
+		if len(topics) != 1 || topics[0] != "msh/EU_868/2/e/LongFast/#" {
+			t.Errorf("subscribed to %q", topics)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no SUBSCRIBE")
+	}
+	waitState(t, tr, StateConnected)
+
+	m := meshproto.New()
+	send := func(tx *meshproto.ToRadio) ProtoFrame {
+		t.Helper()
+		data, err := m.EncodeToRadio(tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f := ProtoFrame{Data: data}
+		if err := tr.Send(f); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+		return f
+	}
+
+	// want_config is answered locally, with the default channel.
+	send(&meshproto.ToRadio{WantConfigID: 77})
+	if fr := decodeFrame(t, receiveFrame(t, tr)); fr.MyInfo == nil || fr.MyInfo.MyNodeNum != gatewayID {
+		t.Errorf("first config frame = %+v, want my_info", fr)
+	}
+	if fr := decodeFrame(t, receiveFrame(t, tr)); fr.Channel == nil || fr.Channel.Settings.Name != "LongFast" {
+		t.Errorf("second config frame = %+v, want channel LongFast", fr)
+	}
+	if fr := decodeFrame(t, receiveFrame(t, tr)); fr.ConfigCompleteID != 77 {
+		t.Errorf("third config frame = %+v, want config_complete_id 77", fr)
+	}
+
+	// A want_ack packet is published encrypted under our gateway topic.
+	f := send(&meshproto.ToRadio{Packet: &meshproto.MeshPacket{
+		ID: 0x5eed, To: 0x1234abcd, WantAck: true, HopLimit: 3,
+		PortNum: meshproto.PortTextMessage, Payload: []byte("ping"),
+	}})
+	var pub *packets.PublishPacket
+	select {
+	case pub = <-b.published:
+	case <-time.After(2 * time.Second):
+		t.Fatal("no PUBLISH")
+	}
+	if want := "msh/EU_868/2/e/LongFast/!0a0b0c0d"; pub.TopicName != want {
+		t.Errorf("topic = %q, want %q", pub.TopicName, want)
+	}
+	env, err := m.DecodeServiceEnvelope(pub.Payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env.GatewayID != "!0a0b0c0d" || env.ChannelID != "LongFast" || env.Packet.From != gatewayID {
+		t.Errorf("envelope = %+v", env)
+	}
+	keys := meshproto.NewKeyring()
+	if _, err := keys.Add("LongFast", []byte{1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := keys.Decrypt(env.Packet); err != nil || string(env.Packet.Payload) != "ping" {
+		t.Errorf("decrypt published packet: %q, %v", env.Packet.Payload, err)
+	}
+
+	// The broker's PUBACK is not a mesh ACK: no ROUTING_APP frame follows,
+	// and the transport says so up front.
+	if ReportsAcks(tr, f) {
+		t.Error("ReportsAcks = true, want false")
+	}
+	select {
+	case f := <-tr.Receive():
+		t.Errorf("unexpected frame after publish: %+v", decodeFrame(t, f.Data))
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// Packets from other gateways reach the gateway still encrypted; our
+	// own uplink echoed back does not.
+	echo, err := m.EncodeServiceEnvelope(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.publish(pub.TopicName, echo)
+
+	in := &meshproto.MeshPacket{ID: 9, From: 0x1234abcd, To: meshproto.BroadcastAddr,
+		PortNum: meshproto.PortTextMessage, Payload: []byte("pong")}
+	if err := keys.Encrypt(in, "LongFast"); err != nil {
+		t.Fatal(err)
+	}
+	other, err := m.EncodeServiceEnvelope(&meshproto.ServiceEnvelope{
+		Packet: in, ChannelID: "LongFast", GatewayID: "!1234abcd",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.publish("msh/EU_868/2/e/LongFast/!1234abcd", other)
+
+	fr := decodeFrame(t, receiveFrame(t, tr))
+	if fr.Packet == nil || fr.Packet.ID != 9 || !fr.Packet.ViaMQTT || fr.Packet.Encrypted == nil {
+		t.Errorf("received %+v, want packet 9 via MQTT, encrypted", fr.Packet)
+	}
+}
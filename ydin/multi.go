@@ -0,0 +1,649 @@
+package transport
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/gg-glitch-88/meshigo-kore/ydin/config"
+	meshproto "github.com/gg-glitch-88/meshigo-kore/ydin/proto"
+)
+
+// SendPolicy selects which link(s) MultiTransport writes outbound frames to.
+type SendPolicy string
+
+const (
+	// PolicyFailover sends on the first connected link, in configured order.
+	PolicyFailover SendPolicy = "failover"
+	// PolicyBroadcast sends on every connected link.
+	PolicyBroadcast SendPolicy = "broadcast"
+	// PolicyPinned sends packets on the link their channel is pinned to.
+	// Packets on unpinned channels fall back to failover.
+	PolicyPinned SendPolicy = "pinned"
+)
+
+const multiFrameChanSize = 512
+
+// Link is one transport managed by a MultiTransport.
+type Link struct {
+	Name      string // tags frames and health reports, e.g. "heltec-usb"
+	Kind      string // "tcp" | "serial" | "mqtt"
+	Transport TransportManager
+	Channels  []uint32 // PolicyPinned: channel indexes this link carries
+}
+
+// LinkHealth is a point-in-time report on one link.
+type LinkHealth struct {
+	Name      string    `json:"name"`
+	Kind      string    `json:"kind"`
+	State     string    `json:"state"`
+	FramesIn  uint64    `json:"frames_in"`
+	FramesOut uint64    `json:"frames_out"`
+	LastFrame time.Time `json:"last_frame,omitempty"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+type linkStats struct {
+	framesIn  atomic.Uint64
+	framesOut atomic.Uint64
+	lastFrame atomic.Int64 // unix nanos
+	mu        sync.Mutex
+	lastErr   string
+}
+
+// member is a Link while it belongs to a MultiTransport.
+type member struct {
+	Link
+	stats  linkStats
+	cancel context.CancelFunc // stops fanIn; nil until connected
+	done   chan struct{}      // closed when fanIn returns
+}
+
+// MultiTransport drives several radios (and MQTT bridges) as one
+// TransportManager. Inbound frames from every link are merged into a single
+// channel, each tagged with its link's name in ProtoFrame.Source.
+//
+// The want_config handshake is only run against one link at a time, the
+// first connected one, and device-state frames from the other links are
+// dropped, so the gateway sees a single, consistent device.
+//
+// Links can be added and removed while connected. The link list is
+// replaced, never modified, so senders and health reports read a
+// consistent snapshot without locking.
+type MultiTransport struct {
+	members atomic.Pointer[[]*member]
+	policy  atomic.Value // SendPolicy
+	proto   *meshproto.MeshtasticProtobuf
+	log     *zap.Logger
+	frames  chan ProtoFrame
+	config  atomic.Pointer[member] // link answering want_config
+	capture *CaptureWriter
+	mu      sync.Mutex // serialises Connect, Disconnect and link changes
+	ctx     context.Context
+	cancel  context.CancelFunc
+}
+
+// NewMultiTransport builds a MultiTransport over links, which must have
+// distinct non-empty names.
+func NewMultiTransport(policy SendPolicy, links []Link, log *zap.Logger) (*MultiTransport, error) {
+	if len(links) == 0 {
+		return nil, fmt.Errorf("transport: no links configured")
+	}
+	members := make([]*member, 0, len(links))
+	for _, l := range links {
+		if err := checkLink(members, l); err != nil {
+			return nil, err
+		}
+		members = append(members, &member{Link: l})
+	}
+
+	m := &MultiTransport{
+		proto:  meshproto.New(),
+		log:    log,
+		frames: make(chan ProtoFrame, multiFrameChanSize),
+	}
+	if err := m.SetPolicy(policy); err != nil {
+		return nil, err
+	}
+	m.members.Store(&members)
+	return m, nil
+}
+
+// SetCapture records every frame sent or received on any link to cw, which
+// the MultiTransport closes on Disconnect. It must be called before Connect.
+func (m *MultiTransport) SetCapture(cw *CaptureWriter) {
+	m.capture = cw
+}
+
+// SetPolicy changes the send policy; "" means PolicyFailover. It takes
+// effect from the next frame sent.
+func (m *MultiTransport) SetPolicy(policy SendPolicy) error {
+	switch policy {
+	case PolicyFailover, PolicyBroadcast, PolicyPinned:
+	case "":
+		policy = PolicyFailover
+	default:
+		return fmt.Errorf("transport: unknown send policy %q", policy)
+	}
+	m.policy.Store(policy)
+	return nil
+}
+
+// Connect connects every link and starts merging their frames. A link that
+// fails to connect is logged; it keeps retrying on its own.
+func (m *MultiTransport) Connect() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.cancel != nil {
+		return nil
+	}
+	m.ctx, m.cancel = context.WithCancel(context.Background())
+	for _, mb := range m.snapshot() {
+		m.start(mb)
+	}
+	return nil
+}
+
+func (m *MultiTransport) Disconnect() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.cancel != nil {
+		m.cancel()
+		m.cancel = nil
+	}
+	var errs []error
+	for _, mb := range m.snapshot() {
+		if err := m.stop(mb); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if m.capture != nil {
+		if err := m.capture.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// AddLink appends l to the links, connecting it straight away if the
+// MultiTransport is connected. l must keep link names unique and must not
+// pin a channel another link carries.
+func (m *MultiTransport) AddLink(l Link) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	old := m.snapshot()
+	if err := checkLink(old, l); err != nil {
+		return err
+	}
+	mb := &member{Link: l}
+	if m.cancel != nil {
+		m.start(mb)
+	}
+	members := append(slices.Clip(old), mb)
+	m.members.Store(&members)
+	m.log.Info("transport: link added", zap.String("link", l.Name), zap.String("kind", l.Kind))
+	return nil
+}
+
+// RemoveLink disconnects the link called name and drops it. The last link
+// cannot be removed. If it was answering want_config, the next handshake
+// picks another link.
+func (m *MultiTransport) RemoveLink(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	old := m.snapshot()
+	i := slices.IndexFunc(old, func(mb *member) bool { return mb.Name == name })
+	switch {
+	case i < 0:
+		return fmt.Errorf("transport: no link %q", name)
+	case len(old) == 1:
+		return fmt.Errorf("transport: cannot remove the only link %q", name)
+	}
+	mb := old[i]
+	members := slices.Delete(slices.Clone(old), i, i+1)
+	m.members.Store(&members)
+	m.config.CompareAndSwap(mb, nil)
+
+	err := m.stop(mb)
+	m.log.Info("transport: link removed", zap.String("link", name))
+	return err
+}
+
+// ReplaceLink swaps the link named l.Name for l, keeping its place in the
+// failover order, e.g. after its address changed. The old link is
+// disconnected; l is connected if the MultiTransport is.
+func (m *MultiTransport) ReplaceLink(l Link) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	old := m.snapshot()
+	i := slices.IndexFunc(old, func(mb *member) bool { return mb.Name == l.Name })
+	if i < 0 {
+		return fmt.Errorf("transport: no link %q", l.Name)
+	}
+	if err := checkLink(slices.Delete(slices.Clone(old), i, i+1), l); err != nil {
+		return err
+	}
+	mb := &member{Link: l}
+	if m.cancel != nil {
+		m.start(mb)
+	}
+	members := slices.Clone(old)
+	members[i] = mb
+	m.members.Store(&members)
+	m.config.CompareAndSwap(old[i], nil)
+
+	err := m.stop(old[i])
+	m.log.Info("transport: link replaced", zap.String("link", l.Name), zap.String("kind", l.Kind))
+	return err
+}
+
+// Send routes frame according to the send policy. want_config requests
+// always go to a single link, which then owns the device-state frames.
+func (m *MultiTransport) Send(frame ProtoFrame) error {
+	msg, err := m.proto.DecodeToRadio(frame.Data)
+	if err != nil {
+		return fmt.Errorf("transport: send: %w", err)
+	}
+	members := m.snapshot()
+	if msg.WantConfigID != 0 {
+		return m.sendConfig(frame, members)
+	}
+
+	switch m.policy.Load().(SendPolicy) {
+	case PolicyBroadcast:
+		return m.sendBroadcast(frame, members)
+	case PolicyPinned:
+		if msg.Packet != nil {
+			if mb, ok := pinnedLink(members, msg.Packet.Channel); ok {
+				return m.sendOn(mb, frame)
+			}
+		}
+		return m.sendFailover(frame, unpinned(members))
+	default:
+		return m.sendFailover(frame, members)
+	}
+}
+
+func (m *MultiTransport) Receive() <-chan ProtoFrame { return m.frames }
+
+// ReportsAcks asks the link(s) Send would pick for frame right now. Under
+// PolicyBroadcast one link relaying the ACK is enough.
+func (m *MultiTransport) ReportsAcks(frame ProtoFrame) bool {
+	msg, err := m.proto.DecodeToRadio(frame.Data)
+	if err != nil || msg.Packet == nil {
+		return true
+	}
+	members := m.snapshot()
+	switch m.policy.Load().(SendPolicy) {
+	case PolicyBroadcast:
+		for _, mb := range members {
+			if mb.Transport.GetConnectionState() == StateConnected && ReportsAcks(mb.Transport, frame) {
+				return true
+			}
+		}
+		return false
+	case PolicyPinned:
+		if mb, ok := pinnedLink(members, msg.Packet.Channel); ok {
+			return ReportsAcks(mb.Transport, frame)
+		}
+		members = unpinned(members)
+	}
+	for _, mb := range members {
+		if mb.Transport.GetConnectionState() == StateConnected {
+			return ReportsAcks(mb.Transport, frame)
+		}
+	}
+	return true
+}
+
+// GetConnectionState summarises the links: connected if any link is,
+// otherwise connecting if any link is trying, otherwise failed if any link
+// has failed.
+func (m *MultiTransport) GetConnectionState() ConnectionState {
+	agg := StateDisconnected
+	for _, mb := range m.snapshot() {
+		switch mb.Transport.GetConnectionState() {
+		case StateConnected:
+			return StateConnected
+		case StateConnecting:
+			agg = StateConnecting
+		case StateFailed:
+			if agg == StateDisconnected {
+				agg = StateFailed
+			}
+		}
+	}
+	return agg
+}
+
+// ConfigLink reports on the link that answered the last want_config, so
+// the gateway can repeat the handshake when that link reconnects or drops
+// while others stay up.
+func (m *MultiTransport) ConfigLink() (ConnectionState, uint64) {
+	mb := m.config.Load()
+	if mb == nil {
+		return m.GetConnectionState(), 0
+	}
+	return mb.Transport.GetConnectionState(), Connects(mb.Transport)
+}
+
+// Links reports the health of every link, in configured order.
+func (m *MultiTransport) Links() []LinkHealth {
+	members := m.snapshot()
+	out := make([]LinkHealth, len(members))
+	for i, mb := range members {
+		st := &mb.stats
+		h := LinkHealth{
+			Name:      mb.Name,
+			Kind:      mb.Kind,
+			State:     mb.Transport.GetConnectionState().String(),
+			FramesIn:  st.framesIn.Load(),
+			FramesOut: st.framesOut.Load(),
+		}
+		if ns := st.lastFrame.Load(); ns != 0 {
+			h.LastFrame = time.Unix(0, ns).UTC()
+		}
+		st.mu.Lock()
+		h.LastError = st.lastErr
+		st.mu.Unlock()
+		out[i] = h
+	}
+	return out
+}
+
+// ── internal ──────────────────────────────────────────────────────────────
+
+func (m *MultiTransport) snapshot() []*member { return *m.members.Load() }
+
+// checkLink reports whether l can join members.
+func checkLink(members []*member, l Link) error {
+	if l.Name == "" || slices.ContainsFunc(members, func(mb *member) bool { return mb.Name == l.Name }) {
+		return fmt.Errorf("transport: link names must be unique and non-empty (%q)", l.Name)
+	}
+	for _, ch := range l.Channels {
+		if other, ok := pinnedLink(members, ch); ok {
+			return fmt.Errorf("transport: channel %d pinned to both %q and %q", ch, other.Name, l.Name)
+		}
+	}
+	return nil
+}
+
+// start connects mb and starts its fanIn. m.mu must be held and m.ctx set.
+func (m *MultiTransport) start(mb *member) {
+	if err := mb.Transport.Connect(); err != nil {
+		m.recordError(mb, err)
+		m.log.Warn("transport: link connect failed",
+			zap.String("link", mb.Name), zap.Error(err))
+	}
+	var ctx context.Context
+	ctx, mb.cancel = context.WithCancel(m.ctx)
+	mb.done = make(chan struct{})
+	go m.fanIn(ctx, mb, mb.done)
+}
+
+// stop disconnects mb and waits for its fanIn to return. m.mu must be held.
+func (m *MultiTransport) stop(mb *member) error {
+	if mb.cancel != nil {
+		mb.cancel()
+	}
+	err := mb.Transport.Disconnect()
+	if mb.done != nil {
+		<-mb.done
+		mb.cancel, mb.done = nil, nil
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", mb.Name, err)
+	}
+	return nil
+}
+
+// fanIn forwards mb's frames, tagged with its name, until ctx is done,
+// then closes done.
+func (m *MultiTransport) fanIn(ctx context.Context, mb *member, done chan<- struct{}) {
+	defer close(done)
+
+	st := &mb.stats
+	in := mb.Transport.Receive()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case f, ok := <-in:
+			if !ok {
+				return
+			}
+			st.framesIn.Add(1)
+			st.lastFrame.Store(time.Now().UnixNano())
+			m.record(CaptureIn, mb, f)
+			if !m.accept(mb, f) {
+				continue
+			}
+			f.Source = mb.Name
+			select {
+			case m.frames <- f:
+			default:
+				m.log.Warn("transport: frame channel full – dropping frame",
+					zap.String("link", mb.Name))
+			}
+		}
+	}
+}
+
+// accept reports whether a frame from mb should reach the gateway.
+// Mesh packets always do; device-state frames only from the config link.
+func (m *MultiTransport) accept(mb *member, f ProtoFrame) bool {
+	if m.config.Load() == mb {
+		return true
+	}
+	fr, err := m.proto.DecodeFromRadio(f.Data)
+	if err != nil {
+		return true // let the gateway log it
+	}
+	return fr.Packet != nil
+}
+
+func unpinned(members []*member) []*member {
+	var out []*member
+	for _, mb := range members {
+		if len(mb.Channels) == 0 {
+			out = append(out, mb)
+		}
+	}
+	return out
+}
+
+func pinnedLink(members []*member, ch uint32) (*member, bool) {
+	for _, mb := range members {
+		if slices.Contains(mb.Channels, ch) {
+			return mb, true
+		}
+	}
+	return nil, false
+}
+
+// sendFailover tries each connected link in members in order until one
+// accepts the frame.
+func (m *MultiTransport) sendFailover(frame ProtoFrame, members []*member) error {
+	var errs []error
+	for _, mb := range members {
+		if mb.Transport.GetConnectionState() != StateConnected {
+			continue
+		}
+		err := m.sendOn(mb, frame)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, err)
+		m.log.Warn("transport: link send failed – failing over",
+			zap.String("link", mb.Name), zap.Error(err))
+	}
+	if len(errs) == 0 {
+		return fmt.Errorf("transport: no connected link")
+	}
+	return errors.Join(errs...)
+}
+
+// sendConfig sends a want_config request like sendFailover, making each
+// link tried the config link before writing to it: a link may answer
+// before its Send returns, and accept must not drop the answer. A link that
+// fails hands the role back to the previous config link.
+func (m *MultiTransport) sendConfig(frame ProtoFrame, members []*member) error {
+	var errs []error
+	for _, mb := range members {
+		if mb.Transport.GetConnectionState() != StateConnected {
+			continue
+		}
+		prev := m.config.Swap(mb)
+		err := m.sendOn(mb, frame)
+		if err == nil {
+			return nil
+		}
+		m.config.CompareAndSwap(mb, prev)
+		errs = append(errs, err)
+		m.log.Warn("transport: link send failed – failing over",
+			zap.String("link", mb.Name), zap.Error(err))
+	}
+	if len(errs) == 0 {
+		return fmt.Errorf("transport: no connected link")
+	}
+	return errors.Join(errs...)
+}
+
+// sendBroadcast sends on every connected link and succeeds if any did.
+func (m *MultiTransport) sendBroadcast(frame ProtoFrame, members []*member) error {
+	var errs []error
+	sent := false
+	for _, mb := range members {
+		if mb.Transport.GetConnectionState() != StateConnected {
+			continue
+		}
+		if err := m.sendOn(mb, frame); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		sent = true
+	}
+	if sent {
+		return nil
+	}
+	if len(errs) == 0 {
+		return fmt.Errorf("transport: no connected link")
+	}
+	return errors.Join(errs...)
+}
+
+func (m *MultiTransport) sendOn(mb *member, frame ProtoFrame) error {
+	if err := mb.Transport.Send(frame); err != nil {
+		m.recordError(mb, err)
+		return fmt.Errorf("%s: %w", mb.Name, err)
+	}
+	mb.stats.framesOut.Add(1)
+	m.record(CaptureOut, mb, frame)
+	return nil
+}
+
+func (m *MultiTransport) record(dir string, mb *member, frame ProtoFrame) {
+	if m.capture == nil {
+		return
+	}
+	if err := m.capture.Record(dir, mb.Name, frame); err != nil {
+		m.log.Warn("transport: capture", zap.Error(err))
+	}
+}
+
+func (m *MultiTransport) recordError(mb *member, err error) {
+	st := &mb.stats
+	st.mu.Lock()
+	st.lastErr = err.Error()
+	st.mu.Unlock()
+}
+
+// ── Construction from config ──────────────────────────────────────────────
+
+// New builds the transport described by cfg.Transport: a MultiTransport
+// over every configured link, even when there is only one, so health
+// reporting, frame tagging and capture look the same for every deployment.
+func New(cfg *config.Config, log *zap.Logger) (*MultiTransport, error) {
+	links := make([]Link, 0, len(cfg.Transport.Links))
+	for i, lc := range cfg.Transport.Links {
+		l, err := NewLink(lc, i, log)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, l)
+	}
+	m, err := NewMultiTransport(SendPolicy(cfg.Transport.SendPolicy), links, log)
+	if err != nil {
+		return nil, err
+	}
+	if path := cfg.Transport.CaptureFile; path != "" {
+		cw, err := OpenCapture(path)
+		if err != nil {
+			return nil, fmt.Errorf("transport: %w", err)
+		}
+		m.SetCapture(cw)
+		log.Info("transport: recording frames", zap.String("path", path))
+	}
+	return m, nil
+}
+
+// NewLink builds the link described by lc, the i-th configured link. Its
+// transport is not connected.
+func NewLink(lc config.LinkConfig, i int, log *zap.Logger) (Link, error) {
+	name := lc.EffectiveName(i)
+	lg := log.With(zap.String("link", name))
+
+	var tr TransportManager
+	switch lc.Type {
+	case "tcp":
+		tr = NewTCPTransport(lc.Address, lg)
+	case "serial":
+		tr = NewSerialTransport(lc.Address, lc.Baud, nil, lg)
+	case "mqtt":
+		opts, err := mqttOptions(lc)
+		if err != nil {
+			return Link{}, fmt.Errorf("transport: link %q: %w", name, err)
+		}
+		if tr, err = NewMQTTTransport(opts, lg); err != nil {
+			return Link{}, fmt.Errorf("transport: link %q: %w", name, err)
+		}
+	default:
+		return Link{}, fmt.Errorf("transport: link %q: unknown type %q", name, lc.Type)
+	}
+	return Link{Name: name, Kind: lc.Type, Transport: tr, Channels: lc.Channels}, nil
+}
+
+func mqttOptions(lc config.LinkConfig) (MQTTOptions, error) {
+	opts := MQTTOptions{
+		Broker:   lc.Address,
+		Username: lc.MQTT.Username,
+		Password: lc.MQTT.Password,
+		Root:     lc.MQTT.Root,
+		Region:   lc.MQTT.Region,
+	}
+	id := strings.TrimPrefix(lc.MQTT.GatewayID, "!")
+	if _, err := fmt.Sscanf(id, "%x", &opts.GatewayID); err != nil {
+		return opts, fmt.Errorf("invalid gateway_id %q", lc.MQTT.GatewayID)
+	}
+	for _, ch := range lc.MQTT.Channels {
+		psk, err := base64.StdEncoding.DecodeString(ch.PSK)
+		if err != nil {
+			return opts, fmt.Errorf("channel %q: invalid PSK: %w", ch.Name, err)
+		}
+		opts.Channels = append(opts.Channels, MQTTChannel{Name: ch.Name, PSK: psk})
+	}
+	return opts, nil
+}
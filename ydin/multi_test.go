@@ -0,0 +1,239 @@
+package transport
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	meshproto "github.com/gg-glitch-88/meshigo-kore/ydin/proto"
+)
+
+// stubLink is a TransportManager whose state the test sets directly. It
+// records the frames sent to it.
+type stubLink struct {
+	state    atomic.Int32
+	connects atomic.Uint64
+	acks     bool // ReportsAcks result
+	frames   chan ProtoFrame
+
+	mu   sync.Mutex
+	sent []ProtoFrame
+}
+
+func newStubLink(state ConnectionState, acks bool) *stubLink {
+	s := &stubLink{acks: acks, frames: make(chan ProtoFrame, 16)}
+	s.setState(state)
+	return s
+}
+
+func (s *stubLink) Connect() error    { return nil }
+func (s *stubLink) Disconnect() error { return nil }
+func (s *stubLink) Send(f ProtoFrame) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent = append(s.sent, f)
+	return nil
+}
+func (s *stubLink) Receive() <-chan ProtoFrame          { return s.frames }
+func (s *stubLink) GetConnectionState() ConnectionState { return ConnectionState(s.state.Load()) }
+func (s *stubLink) ReportsAcks(ProtoFrame) bool         { return s.acks }
+func (s *stubLink) Connects() uint64                    { return s.connects.Load() }
+func (s *stubLink) setState(state ConnectionState) {
+	if state == StateConnected {
+		s.connects.Add(1)
+	}
+	s.state.Store(int32(state))
+}
+
+func (s *stubLink) sentCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.sent)
+}
+
+func packetFrame(t *testing.T, channel uint32) ProtoFrame {
+	t.Helper()
+	data, err := meshproto.New().EncodeToRadio(&meshproto.ToRadio{Packet: &meshproto.MeshPacket{
+		ID: 1, To: 2, Channel: channel, WantAck: true,
+		PortNum: meshproto.PortTextMessage, Payload: []byte("x"),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ProtoFrame{Data: data}
+}
+
+func TestMultiTransportReportsAcks(t *testing.T) {
+	radio := newStubLink(StateConnected, true)
+	broker := newStubLink(StateConnected, false)
+	m, err := NewMultiTransport(PolicyFailover, []Link{
+		{Name: "mqtt", Kind: "mqtt", Transport: broker, Channels: []uint32{1}},
+		{Name: "radio", Kind: "tcp", Transport: radio},
+	}, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		policy  SendPolicy
+		channel uint32
+		radio   ConnectionState
+		want    bool
+	}{
+		{"failover picks the broker first", PolicyFailover, 0, StateConnected, false},
+		{"pinned to the broker", PolicyPinned, 1, StateConnected, false},
+		{"unpinned goes to the radio", PolicyPinned, 0, StateConnected, true},
+		{"broadcast reaches the radio", PolicyBroadcast, 0, StateConnected, true},
+		{"broadcast with the radio down", PolicyBroadcast, 0, StateConnecting, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := m.SetPolicy(tc.policy); err != nil {
+				t.Fatal(err)
+			}
+			radio.setState(tc.radio)
+			if got := ReportsAcks(m, packetFrame(t, tc.channel)); got != tc.want {
+				t.Errorf("ReportsAcks = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMultiTransportConfigLink(t *testing.T) {
+	a := newStubLink(StateConnected, true)
+	b := newStubLink(StateConnected, true)
+	m, err := NewMultiTransport(PolicyFailover, []Link{
+		{Name: "a", Kind: "tcp", Transport: a},
+		{Name: "b", Kind: "tcp", Transport: b},
+	}, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantConfig := func() {
+		t.Helper()
+		data, err := meshproto.New().EncodeToRadio(&meshproto.ToRadio{WantConfigID: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := m.Send(ProtoFrame{Data: data}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	check := func(wantState ConnectionState, wantConnects uint64) {
+		t.Helper()
+		if st, n := m.ConfigLink(); st != wantState || n != wantConnects {
+			t.Errorf("ConfigLink = %s, %d; want %s, %d", st, n, wantState, wantConnects)
+		}
+	}
+
+	// Before any handshake: the overall state.
+	check(StateConnected, 0)
+
+	wantConfig()
+	if a.sentCount() != 1 {
+		t.Fatalf("want_config went to b")
+	}
+	check(StateConnected, 1)
+
+	// a reconnects: the overall state never changes, the count does.
+	a.setState(StateConnecting)
+	check(StateConnecting, 1)
+	if m.GetConnectionState() != StateConnected {
+		t.Error("overall state should stay connected through b")
+	}
+	a.setState(StateConnected)
+	check(StateConnected, 2)
+
+	// a goes down; the next handshake moves to b.
+	a.setState(StateFailed)
+	wantConfig()
+	if b.sentCount() != 1 {
+		t.Fatalf("want_config did not fail over to b")
+	}
+	check(StateConnected, 1)
+
+	// Removing the config link falls back to the overall state.
+	if err := m.RemoveLink("b"); err != nil {
+		t.Fatal(err)
+	}
+	check(StateFailed, 0)
+}
+
+// replyingLink answers want_config from inside Send, as MQTTTransport and a
+// fast device do: Send returns only once the answer has reached the
+// MultiTransport's output, or after a timeout.
+type replyingLink struct {
+	*stubLink
+	fail     bool
+	answered chan struct{}
+}
+
+func (s *replyingLink) Send(f ProtoFrame) error {
+	if s.fail {
+		return errors.New("write: broken pipe")
+	}
+	s.stubLink.Send(f) //nolint:errcheck
+	data, err := meshproto.New().EncodeFromRadio(&meshproto.FromRadio{MyInfo: &meshproto.MyNodeInfo{MyNodeNum: 0x0a000001}})
+	if err != nil {
+		return err
+	}
+	s.frames <- ProtoFrame{Data: data}
+	select {
+	case <-s.answered:
+	case <-time.After(time.Second):
+	}
+	return nil
+}
+
+func TestMultiTransportConfigAnswerInsideSend(t *testing.T) {
+	broken := &replyingLink{stubLink: newStubLink(StateConnected, true), fail: true}
+	fast := &replyingLink{stubLink: newStubLink(StateConnected, true), answered: make(chan struct{})}
+	m, err := NewMultiTransport(PolicyFailover, []Link{
+		{Name: "broken", Kind: "serial", Transport: broken},
+		{Name: "fast", Kind: "mqtt", Transport: fast},
+	}, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Disconnect() //nolint:errcheck
+
+	data, err := meshproto.New().EncodeToRadio(&meshproto.ToRadio{WantConfigID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	errc := make(chan error, 1)
+	go func() { errc <- m.Send(ProtoFrame{Data: data}) }()
+
+	select {
+	case f := <-m.Receive():
+		if f.Source != "fast" {
+			t.Errorf("answer from %q, want fast", f.Source)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("the answer sent inside Send was dropped")
+	}
+	close(fast.answered)
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	if mb := m.config.Load(); mb == nil || mb.Name != "fast" {
+		t.Errorf("config link = %v, want fast", mb)
+	}
+
+	// A handshake that fails everywhere leaves the config link as it was.
+	broken.fail, fast.fail = true, true
+	if err := m.Send(ProtoFrame{Data: data}); err == nil {
+		t.Fatal("Send over broken links: want an error")
+	}
+	if mb := m.config.Load(); mb == nil || mb.Name != "fast" {
+		t.Errorf("config link after a failed handshake = %v, want fast", mb)
+	}
+}
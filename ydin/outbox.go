@@ -0,0 +1,283 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/gg-glitch-88/meshigo-kore/ydin/api"
+	meshproto "github.com/gg-glitch-88/meshigo-kore/ydin/proto"
+	"github.com/gg-glitch-88/meshigo-kore/ydin/store"
+	"github.com/gg-glitch-88/meshigo-kore/ydin/transport"
+)
+
+const (
+	outboxQueueSize     = 64
+	outboxMaxAttempts   = 5
+	outboxRetryDelay    = 5 * time.Second
+	outboxLinkPollDelay = time.Second
+	defaultHopLimit     = 3
+)
+
+// outboundItem is one message waiting in (or being drained from) the outbox.
+// mu guards msg.Status and attempts, which the send, ingest and ack
+// goroutines may race on.
+type outboundItem struct {
+	mu       sync.Mutex
+	msg      *store.Message
+	pkt      *meshproto.MeshPacket
+	attempts int
+}
+
+// MessageStatusEvent is the Data of an EventMessageStatus.
+type MessageStatusEvent struct {
+	ID       int64  `json:"id"`
+	MeshID   string `json:"mesh_id"`
+	Status   string `json:"status"`
+	Attempts int    `json:"attempts"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Enqueue records an outbound text message as queued and schedules it for
+// transmission. It assigns the row ID and the Meshtastic packet ID
+// (msg.MeshID) and returns a copy of msg taken before the send loop can
+// change it; msg itself must not be touched afterwards.
+func (g *GatewayService) Enqueue(msg *store.Message) (store.Message, error) {
+	pkt, err := outboundPacket(msg, newPacketID())
+	if err != nil {
+		return store.Message{}, err
+	}
+
+	// The device stamps From itself; we only label the stored row.
+	if me := g.stateStore.MyNodeNum(); me != 0 {
+		msg.FromNode = nodeHex(me)
+		g.dedup.remember(me, pkt.ID, time.Now().UTC())
+	}
+	msg.MeshID = fmt.Sprintf("%d", pkt.ID)
+	msg.Port = uint32(pkt.PortNum)
+	msg.Direction = store.DirectionOut
+	msg.Status = store.MessageStatusQueued
+	id, err := g.stateStore.RecordMessage(msg)
+	if err != nil {
+		return store.Message{}, fmt.Errorf("gateway: record outbound message: %w", err)
+	}
+	msg.ID = id
+	queued := *msg
+
+	item := &outboundItem{msg: msg, pkt: pkt}
+	g.publishStatus(item, "")
+	select {
+	case g.outbound <- item:
+	default:
+		g.setStatus(item, store.MessageStatusFailed, "outbox full")
+		queued.Status = store.MessageStatusFailed
+		return queued, fmt.Errorf("gateway: %w", api.ErrOutboxFull)
+	}
+	return queued, nil
+}
+
+// sendLoop drains the outbox in FIFO order until ctx is cancelled, after
+// first resuming what a previous run left unfinished.
+func (g *GatewayService) sendLoop(ctx context.Context) {
+	g.resumeOutbox(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case item := <-g.outbound:
+			g.transmit(ctx, item)
+		}
+	}
+}
+
+// resumeOutbox transmits again, oldest first, the outbound messages that
+// are still queued or sent in the database: those the last run had not
+// sent, or sent without seeing the outcome, before it stopped.
+func (g *GatewayService) resumeOutbox(ctx context.Context) {
+	msgs, err := g.stateStore.PendingMessages()
+	if err != nil {
+		g.log.Error("gateway: load unfinished outbound messages", zap.Error(err))
+		return
+	}
+	if len(msgs) > 0 {
+		g.log.Info("gateway: resuming outbox", zap.Int("messages", len(msgs)))
+	}
+	for _, msg := range msgs {
+		var pkt *meshproto.MeshPacket
+		id, err := strconv.ParseUint(msg.MeshID, 10, 32)
+		if err != nil {
+			err = fmt.Errorf("gateway: invalid mesh id %q", msg.MeshID)
+		} else {
+			pkt, err = outboundPacket(msg, uint32(id))
+		}
+		item := &outboundItem{msg: msg, pkt: pkt}
+		if err != nil {
+			g.setStatus(item, store.MessageStatusFailed, err.Error())
+			continue
+		}
+		g.transmit(ctx, item)
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// transmit sends one item, retrying on write errors. Time spent waiting for
+// the transport to (re)connect does not count against outboxMaxAttempts, so
+// queued messages survive a reconnect.
+func (g *GatewayService) transmit(ctx context.Context, item *outboundItem) {
+	data, err := g.protoHandler.EncodeToRadio(&meshproto.ToRadio{Packet: item.pkt})
+	if err != nil {
+		g.setStatus(item, store.MessageStatusFailed, err.Error())
+		return
+	}
+	frame := transport.ProtoFrame{
+		Data:      data,
+		Timestamp: time.Now().UTC(),
+	}
+
+	for {
+		if g.transport.GetConnectionState() != transport.StateConnected {
+			if !sleepCtx(ctx, outboxLinkPollDelay) {
+				return
+			}
+			continue
+		}
+
+		// Track before sending: the device can ACK faster than Send returns.
+		// A link that will never see the ACK (an MQTT broker) leaves the
+		// message relayed instead of timing it out.
+		trackAck := item.pkt.WantAck && transport.ReportsAcks(g.transport, frame)
+		if trackAck {
+			g.acks.track(item, frame)
+		}
+		item.mu.Lock()
+		item.attempts++
+		attempt := item.attempts
+		item.mu.Unlock()
+
+		err := g.transport.Send(frame)
+		if err == nil {
+			status := store.MessageStatusSent
+			if item.pkt.WantAck && !trackAck {
+				status = store.MessageStatusRelayed
+			}
+			g.setStatus(item, status, "")
+			return
+		}
+		g.acks.take(item.pkt.ID)
+		g.log.Warn("gateway: send failed",
+			zap.String("mesh_id", item.msg.MeshID),
+			zap.Int("attempt", attempt),
+			zap.Error(err))
+		if attempt >= outboxMaxAttempts {
+			g.setStatus(item, store.MessageStatusFailed, err.Error())
+			return
+		}
+		if !sleepCtx(ctx, outboxRetryDelay) {
+			return
+		}
+	}
+}
+
+// setStatus persists a delivery state change and publishes it, reporting
+// whether it took effect. A final state (acked, failed or relayed) is
+// sticky: a late "sent", or an ACK after the message was given up on, never
+// overwrites the outcome that raced ahead of it.
+func (g *GatewayService) setStatus(item *outboundItem, status, reason string) bool {
+	item.mu.Lock()
+	defer item.mu.Unlock()
+	if isFinalStatus(item.msg.Status) {
+		return false
+	}
+	item.msg.Status = status
+	item.msg.ErrorReason = reason
+	if err := g.stateStore.SetMessageStatus(item.msg.ID, status, reason); err != nil {
+		g.log.Warn("gateway: persist message status",
+			zap.Int64("id", item.msg.ID),
+			zap.String("status", status),
+			zap.Error(err))
+	}
+	g.publishStatus(item, reason)
+	return true
+}
+
+func (g *GatewayService) publishStatus(item *outboundItem, reason string) {
+	g.eventBus.PublishMessageStatus(&MessageStatusEvent{
+		ID:       item.msg.ID,
+		MeshID:   item.msg.MeshID,
+		Status:   item.msg.Status,
+		Attempts: item.attempts,
+		Error:    reason,
+	})
+}
+
+// ── helpers ───────────────────────────────────────────────────────────────
+
+// outboundPacket builds the want_ack packet with ID id that carries msg.
+func outboundPacket(msg *store.Message, id uint32) (*meshproto.MeshPacket, error) {
+	to, err := parseNodeRef(msg.ToNode)
+	if err != nil {
+		return nil, err
+	}
+	return &meshproto.MeshPacket{
+		ID:       id,
+		To:       to,
+		Channel:  uint32(msg.Channel),
+		PortNum:  meshproto.PortTextMessage,
+		Payload:  msg.Payload,
+		HopLimit: defaultHopLimit,
+		WantAck:  true,
+	}, nil
+}
+
+func isFinalStatus(status string) bool {
+	return status == store.MessageStatusAcked || status == store.MessageStatusFailed ||
+		status == store.MessageStatusRelayed
+}
+
+// newPacketID returns a random non-zero Meshtastic packet ID.
+func newPacketID() uint32 {
+	for {
+		if id := rand.Uint32(); id != 0 {
+			return id
+		}
+	}
+}
+
+// parseNodeRef converts a stored node reference ("broadcast", "!deadbeef"
+// or decimal) into a node number.
+func parseNodeRef(ref string) (uint32, error) {
+	if ref == "" || ref == "broadcast" {
+		return meshproto.BroadcastAddr, nil
+	}
+	var n uint32
+	var err error
+	if strings.HasPrefix(ref, "!") {
+		_, err = fmt.Sscanf(ref, "!%x", &n)
+	} else {
+		_, err = fmt.Sscanf(ref, "%d", &n)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("gateway: invalid node reference %q", ref)
+	}
+	return n, nil
+}
+
+// sleepCtx waits for d or until ctx is done; it reports whether d elapsed.
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
@@ -0,0 +1,129 @@
+package proto
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// ── Typed payload decoders ────────────────────────────────────────────────
+//
+// MeshPacket.Payload is an opaque byte slice whose schema depends on
+// PortNum. The functions below turn it into (and back from) the typed
+// structs in meshtastic.go, one pair per supported port.
+
+// DecodePayload decodes p.Payload according to p.PortNum and returns one of
+// string, *Position, *NodeInfo, *Telemetry or *Routing. Packets on ports we
+// do not interpret, and still-encrypted packets, yield an error.
+func (m *MeshtasticProtobuf) DecodePayload(p *MeshPacket) (interface{}, error) {
+	if p.Encrypted != nil {
+		return nil, fmt.Errorf("proto: packet %d is encrypted", p.ID)
+	}
+	switch p.PortNum {
+	case PortTextMessage:
+		return m.DecodeText(p.Payload)
+	case PortPosition:
+		return m.DecodePosition(p.Payload)
+	case PortNodeInfo:
+		return m.DecodeNodeInfo(p.Payload)
+	case PortTelemetry:
+		return m.DecodeTelemetry(p.Payload)
+	case PortRouting:
+		return m.DecodeRouting(p.Payload)
+	default:
+		return nil, fmt.Errorf("proto: no decoder for %s", MessageTypeLabel(p.PortNum))
+	}
+}
+
+// DecodeText validates a TEXT_MESSAGE_APP payload as UTF-8.
+func (m *MeshtasticProtobuf) DecodeText(payload []byte) (string, error) {
+	if !utf8.Valid(payload) {
+		return "", fmt.Errorf("proto: text payload is not valid UTF-8")
+	}
+	return string(payload), nil
+}
+
+// DecodePosition decodes a POSITION_APP payload.
+func (m *MeshtasticProtobuf) DecodePosition(payload []byte) (*Position, error) {
+	pos := &Position{}
+	if err := pos.unmarshal(payload); err != nil {
+		return nil, fmt.Errorf("proto: decode Position: %w", err)
+	}
+	return pos, nil
+}
+
+// EncodePosition encodes a POSITION_APP payload.
+func (m *MeshtasticProtobuf) EncodePosition(pos *Position) ([]byte, error) {
+	if pos == nil {
+		return nil, fmt.Errorf("proto: cannot encode nil Position")
+	}
+	return pos.marshal(nil), nil
+}
+
+// DecodeNodeInfo decodes a NODEINFO_APP payload (a Meshtastic User message).
+// NodeID is derived from the "!xxxxxxxx" user ID when present; callers
+// should prefer MeshPacket.From, which cannot be spoofed by the payload.
+func (m *MeshtasticProtobuf) DecodeNodeInfo(payload []byte) (*NodeInfo, error) {
+	n := &NodeInfo{}
+	if err := n.unmarshalUser(payload); err != nil {
+		return nil, fmt.Errorf("proto: decode User: %w", err)
+	}
+	fmt.Sscanf(n.UserID, "!%x", &n.NodeID) //nolint:errcheck
+	return n, nil
+}
+
+// EncodeNodeInfo encodes the user fields of n as a NODEINFO_APP payload.
+func (m *MeshtasticProtobuf) EncodeNodeInfo(n *NodeInfo) ([]byte, error) {
+	if n == nil {
+		return nil, fmt.Errorf("proto: cannot encode nil NodeInfo")
+	}
+	return n.marshalUser(nil), nil
+}
+
+// DecodeTelemetry decodes a TELEMETRY_APP payload.
+func (m *MeshtasticProtobuf) DecodeTelemetry(payload []byte) (*Telemetry, error) {
+	t := &Telemetry{}
+	if err := t.unmarshal(payload); err != nil {
+		return nil, fmt.Errorf("proto: decode Telemetry: %w", err)
+	}
+	return t, nil
+}
+
+// EncodeTelemetry encodes a TELEMETRY_APP payload. Only the first non-nil
+// variant (Device, Environment, Power) is written.
+func (m *MeshtasticProtobuf) EncodeTelemetry(t *Telemetry) ([]byte, error) {
+	if t == nil {
+		return nil, fmt.Errorf("proto: cannot encode nil Telemetry")
+	}
+	if t.Device == nil && t.Environment == nil && t.Power == nil {
+		return nil, fmt.Errorf("proto: Telemetry has no metrics variant set")
+	}
+	return t.marshal(nil), nil
+}
+
+// EncodeAdmin encodes an ADMIN_APP payload.
+func (m *MeshtasticProtobuf) EncodeAdmin(a *AdminMessage) ([]byte, error) {
+	if a == nil {
+		return nil, fmt.Errorf("proto: cannot encode nil AdminMessage")
+	}
+	if a.SetChannel == nil && !a.BeginEditSettings && !a.CommitEditSettings {
+		return nil, fmt.Errorf("proto: AdminMessage has no variant set")
+	}
+	return a.marshal(nil), nil
+}
+
+// DecodeRouting decodes a ROUTING_APP payload.
+func (m *MeshtasticProtobuf) DecodeRouting(payload []byte) (*Routing, error) {
+	r := &Routing{}
+	if err := r.unmarshal(payload); err != nil {
+		return nil, fmt.Errorf("proto: decode Routing: %w", err)
+	}
+	return r, nil
+}
+
+// EncodeRouting encodes a ROUTING_APP payload.
+func (m *MeshtasticProtobuf) EncodeRouting(r *Routing) ([]byte, error) {
+	if r == nil {
+		return nil, fmt.Errorf("proto: cannot encode nil Routing")
+	}
+	return r.marshal(nil), nil
+}
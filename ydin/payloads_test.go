@@ -0,0 +1,205 @@
+package proto
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func f32(v float32) *float32 { return &v }
+func u32(v uint32) *uint32   { return &v }
+
+func TestPositionRoundTrip(t *testing.T) {
+	m := New()
+	for _, tc := range []struct {
+		name string
+		pos  *Position
+	}{
+		{"empty", &Position{}},
+		{"fix", &Position{
+			LatitudeI: 601700000, LongitudeI: 249400000, Altitude: 12,
+			Time: 1700000000, Timestamp: 1699999990, PDOP: 150,
+			GroundSpeed: 3, GroundTrack: 9000000, SatsInView: 9, PrecisionBits: 32,
+		}},
+		// sfixed32 coordinates and a negative int32 altitude.
+		{"southwest, below sea level", &Position{LatitudeI: -338600000, LongitudeI: -703000000, Altitude: -28, PrecisionBits: 13}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			b, err := m.EncodePosition(tc.pos)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := m.DecodePosition(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tc.pos) {
+				t.Errorf("round trip:\n got %+v\nwant %+v", got, tc.pos)
+			}
+		})
+	}
+}
+
+func TestNodeInfoRoundTrip(t *testing.T) {
+	m := New()
+	for _, tc := range []struct {
+		name string
+		n    *NodeInfo
+	}{
+		{"router", &NodeInfo{
+			NodeID: 0x1234abcd, UserID: "!1234abcd", LongName: "Hilltop Relay", ShortName: "HTR",
+			HardwareModel: "HELTEC_V3", Role: "ROUTER", IsLicensed: true,
+			PublicKey: []byte{0x01, 0x02, 0x03, 0x04},
+		}},
+		// CLIENT is the proto3 default, so it is not on the wire and
+		// decodes as no role at all.
+		{"client, no key", &NodeInfo{
+			NodeID: 0xdeadbeef, UserID: "!deadbeef", LongName: "Pocket", ShortName: "PKT",
+			HardwareModel: "TBEAM",
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			b, err := m.EncodeNodeInfo(tc.n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := m.DecodeNodeInfo(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tc.n) {
+				t.Errorf("round trip:\n got %+v\nwant %+v", got, tc.n)
+			}
+		})
+	}
+
+	// Only the payload's user ID names the node.
+	got, err := m.DecodeNodeInfo(nil)
+	if err != nil || got.NodeID != 0 {
+		t.Errorf("empty User: %+v, %v", got, err)
+	}
+}
+
+func TestTelemetryRoundTrip(t *testing.T) {
+	m := New()
+	for _, tc := range []struct {
+		name string
+		tel  *Telemetry
+	}{
+		{"device", &Telemetry{Time: 1700000000, Device: &DeviceMetrics{
+			BatteryLevel: 101, Voltage: 4.15, ChannelUtil: 12.5, AirUtil: 1.25, UptimeSeconds: 86400,
+		}}},
+		{"environment, all set", &Telemetry{Time: 1700000000, Environment: &EnvironmentMetrics{
+			Temperature: f32(21.5), RelativeHumidity: f32(40), BarometricPressure: f32(1013.25),
+			GasResistance: f32(0.5), Voltage: f32(5.1), Current: f32(120), IAQ: u32(42), Lux: f32(300),
+		}}},
+		{"environment, some unset", &Telemetry{Time: 1700000000, Environment: &EnvironmentMetrics{
+			Temperature: f32(-4.5), IAQ: u32(0),
+		}}},
+		// A reported zero is not "not reported".
+		{"environment, zero reading", &Telemetry{Environment: &EnvironmentMetrics{Temperature: f32(0)}}},
+		{"environment, none set", &Telemetry{Environment: &EnvironmentMetrics{}}},
+		{"power", &Telemetry{Time: 1700000000, Power: &PowerMetrics{
+			Ch1Voltage: 12.6, Ch1Current: 850, Ch2Voltage: 5, Ch2Current: 120, Ch3Voltage: 3.3, Ch3Current: 15,
+		}}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			b, err := m.EncodeTelemetry(tc.tel)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := m.DecodeTelemetry(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tc.tel) {
+				t.Errorf("round trip:\n got %+v\nwant %+v", got, tc.tel)
+				if got.Environment != nil && tc.tel.Environment != nil {
+					t.Errorf("environment:\n got %s\nwant %s", envString(got.Environment), envString(tc.tel.Environment))
+				}
+			}
+		})
+	}
+
+	if _, err := m.EncodeTelemetry(&Telemetry{Time: 1}); err == nil {
+		t.Error("Telemetry with no variant: want an error")
+	}
+}
+
+// envString spells out the optional fields of e for failure messages.
+func envString(e *EnvironmentMetrics) string {
+	v := reflect.ValueOf(*e)
+	var s string
+	for i := 0; i < v.NumField(); i++ {
+		s += v.Type().Field(i).Name + "="
+		if f := v.Field(i); f.IsNil() {
+			s += "nil "
+		} else {
+			s += fmt.Sprint(f.Elem().Interface()) + " "
+		}
+	}
+	return s
+}
+
+func TestRoutingRoundTrip(t *testing.T) {
+	m := New()
+	for _, tc := range []struct {
+		name string
+		r    *Routing
+		ack  bool
+	}{
+		{"ack", &Routing{}, true},
+		{"error", &Routing{ErrorReason: RoutingNoResponse}, false},
+		{"route request", &Routing{RouteRequest: &RouteDiscovery{
+			Route: []uint32{0x1234abcd, 0xdeadbeef}, SNRTowards: []int32{24, -12, 7},
+		}}, false},
+		{"route reply", &Routing{RouteReply: &RouteDiscovery{
+			Route: []uint32{0x1234abcd}, SNRTowards: []int32{-40, 8},
+			RouteBack: []uint32{0xdeadbeef}, SNRBack: []int32{3, -1},
+		}}, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			b, err := m.EncodeRouting(tc.r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := m.DecodeRouting(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tc.r) {
+				t.Errorf("round trip:\n got %+v\nwant %+v", got, tc.r)
+			}
+			if got.IsAck() != tc.ack {
+				t.Errorf("IsAck = %v, want %v", got.IsAck(), tc.ack)
+			}
+		})
+	}
+}
+
+func TestDecodePayload(t *testing.T) {
+	m := New()
+	if _, err := m.DecodeText([]byte{0xff, 0xfe}); err == nil {
+		t.Error("DecodeText of invalid UTF-8: want an error")
+	}
+	v, err := m.DecodePayload(&MeshPacket{PortNum: PortTextMessage, Payload: []byte("hi")})
+	if err != nil || v != "hi" {
+		t.Errorf("text payload = %v, %v", v, err)
+	}
+	if _, err := m.DecodePayload(&MeshPacket{ID: 1, Encrypted: []byte{1}}); err == nil {
+		t.Error("encrypted packet: want an error")
+	}
+	if _, err := m.DecodePayload(&MeshPacket{PortNum: PortUnknown}); err == nil {
+		t.Error("unknown port: want an error")
+	}
+	for name, encode := range map[string]func() ([]byte, error){
+		"Position":  func() ([]byte, error) { return m.EncodePosition(nil) },
+		"NodeInfo":  func() ([]byte, error) { return m.EncodeNodeInfo(nil) },
+		"Telemetry": func() ([]byte, error) { return m.EncodeTelemetry(nil) },
+		"Routing":   func() ([]byte, error) { return m.EncodeRouting(nil) },
+	} {
+		if _, err := encode(); err == nil {
+			t.Errorf("encode nil %s: want an error", name)
+		}
+	}
+}
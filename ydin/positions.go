@@ -0,0 +1,118 @@
+package store
+
+import (
+	"fmt"
+	"time"
+)
+
+// Position is one position report from a node.
+type Position struct {
+	ID            int64     `json:"id"`
+	NodeID        string    `json:"node_id"` // "!deadbeef"
+	Time          time.Time `json:"time"`    // time of the fix, or of reception if the node sent none
+	Lat           float64   `json:"lat"`
+	Lon           float64   `json:"lon"`
+	Alt           int32     `json:"alt"`            // metres
+	PrecisionBits uint32    `json:"precision_bits"` // 32 = full precision
+	PDOP          float32   `json:"pdop"`           // 0 = unknown
+	GroundSpeed   uint32    `json:"ground_speed"`   // m/s
+	Heading       float64   `json:"heading"`        // degrees
+	SatsInView    uint32    `json:"sats_in_view"`
+	PacketID      uint32    `json:"packet_id"` // MeshPacket.id the report arrived in
+	ReceivedAt    time.Time `json:"received_at"`
+}
+
+// InsertPosition stores p and returns its row ID.
+func (db *DB) InsertPosition(p *Position) (int64, error) {
+	res, err := db.Exec(`
+		INSERT INTO positions (node_id, time, lat, lon, alt, precision_bits,
+		                       pdop, ground_speed, heading, sats_in_view,
+		                       packet_id, received_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		p.NodeID, p.Time.UnixMilli(), p.Lat, p.Lon, p.Alt, p.PrecisionBits,
+		p.PDOP, p.GroundSpeed, p.Heading, p.SatsInView,
+		p.PacketID, p.ReceivedAt.UnixMilli(),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("store: insert position: %w", err)
+	}
+	return res.LastInsertId()
+}
+
+// ListPositions returns nodeID's positions with from <= time < to, oldest
+// first. A zero from or to leaves that end open.
+//
+// When the range holds more than maxPoints reports it is split into
+// maxPoints equal time buckets and only the latest report of each bucket is
+// returned, so a long track keeps its shape at a bounded size. maxPoints <= 0
+// returns every report.
+func (db *DB) ListPositions(nodeID string, from, to time.Time, maxPoints int) ([]*Position, error) {
+	lo, hi := int64(0), int64(1<<62)
+	if !from.IsZero() {
+		lo = from.UnixMilli()
+	}
+	if !to.IsZero() {
+		hi = to.UnixMilli()
+	}
+
+	var (
+		count       int
+		first, last int64
+	)
+	err := db.QueryRow(`
+		SELECT COUNT(*), COALESCE(MIN(time), 0), COALESCE(MAX(time), 0)
+		FROM positions WHERE node_id = ? AND time >= ? AND time < ?`,
+		nodeID, lo, hi,
+	).Scan(&count, &first, &last)
+	if err != nil {
+		return nil, fmt.Errorf("store: list positions: %w", err)
+	}
+	if count == 0 {
+		return []*Position{}, nil
+	}
+
+	const cols = `id, node_id, time, lat, lon, alt, precision_bits, pdop,
+	              ground_speed, heading, sats_in_view, packet_id, received_at`
+	query := `SELECT ` + cols + ` FROM positions
+		WHERE node_id = ? AND time >= ? AND time < ?
+		ORDER BY time, id`
+	args := []any{nodeID, lo, hi}
+	bucketed := maxPoints > 0 && count > maxPoints
+	if bucketed {
+		// SQLite returns the bare columns of the row holding MAX(time), i.e.
+		// the latest report in each bucket.
+		bucket := (last-first)/int64(maxPoints) + 1
+		query = `SELECT ` + cols + `, MAX(time) FROM positions
+			WHERE node_id = ? AND time >= ? AND time < ?
+			GROUP BY (time - ?) / ?
+			ORDER BY time, id`
+		args = append(args, first, bucket)
+		count = maxPoints
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("store: list positions: %w", err)
+	}
+	defer rows.Close()
+
+	out := make([]*Position, 0, count)
+	for rows.Next() {
+		var (
+			p               Position
+			ts, rxTS, maxTS int64
+		)
+		dest := []any{&p.ID, &p.NodeID, &ts, &p.Lat, &p.Lon, &p.Alt, &p.PrecisionBits,
+			&p.PDOP, &p.GroundSpeed, &p.Heading, &p.SatsInView, &p.PacketID, &rxTS}
+		if bucketed {
+			dest = append(dest, &maxTS)
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("store: list positions: %w", err)
+		}
+		p.Time = time.UnixMilli(ts).UTC()
+		p.ReceivedAt = time.UnixMilli(rxTS).UTC()
+		out = append(out, &p)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,74 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestListPositions(t *testing.T) {
+	db := openTestDB(t)
+
+	// Ten reports a second apart, the n-th at lat n, stored newest first so
+	// that row order is not time order.
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(n int) time.Time { return start.Add(time.Duration(n) * time.Second) }
+	for n := 9; n >= 0; n-- {
+		if _, err := db.InsertPosition(&Position{
+			NodeID: "!1234abcd", Time: at(n), Lat: float64(n), Lon: 24.9,
+			PrecisionBits: 32, PacketID: uint32(100 + n), ReceivedAt: at(n).Add(time.Second),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Another node's report in the same range.
+	if _, err := db.InsertPosition(&Position{NodeID: "!deadbeef", Time: at(5), Lat: 99, ReceivedAt: at(5)}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		from, to  time.Time
+		maxPoints int
+		want      []float64 // lats, oldest first
+	}{
+		{"open range", time.Time{}, time.Time{}, 0, []float64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}},
+		{"open end", at(7), time.Time{}, 0, []float64{7, 8, 9}},
+		{"open start, to exclusive", time.Time{}, at(3), 0, []float64{0, 1, 2}},
+		{"closed range", at(3), at(6), 0, []float64{3, 4, 5}},
+		{"empty range", at(20), time.Time{}, 0, nil},
+		{"max_points above count", time.Time{}, time.Time{}, 10, []float64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}},
+		// 9s in 3 buckets of 3.001s: 0–3, 4–6 and 7–9; the latest of each.
+		{"bucketed", time.Time{}, time.Time{}, 3, []float64{3, 6, 9}},
+		{"bucketed range", at(2), at(8), 2, []float64{4, 7}},
+		{"one bucket", time.Time{}, time.Time{}, 1, []float64{9}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := db.ListPositions("!1234abcd", tt.from, tt.to, tt.maxPoints)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got == nil {
+				t.Fatal("ListPositions returned nil, want a slice")
+			}
+			var lats []float64
+			for _, p := range got {
+				lats = append(lats, p.Lat)
+				// Every column comes from the row holding the bucket's time.
+				n := int(p.Lat)
+				if !p.Time.Equal(at(n)) || p.PacketID != uint32(100+n) ||
+					!p.ReceivedAt.Equal(at(n).Add(time.Second)) || p.NodeID != "!1234abcd" {
+					t.Errorf("report %d = %+v: columns from different rows", n, p)
+				}
+			}
+			if len(lats) != len(tt.want) {
+				t.Fatalf("lats = %v, want %v", lats, tt.want)
+			}
+			for i := range lats {
+				if lats[i] != tt.want[i] {
+					t.Fatalf("lats = %v, want %v", lats, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"slices"
+	"strings"
+	"syscall"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/gg-glitch-88/meshigo-kore/ydin/config"
+	"github.com/gg-glitch-88/meshigo-kore/ydin/replication"
+	"github.com/gg-glitch-88/meshigo-kore/ydin/transport"
+)
+
+// ReloadResult reports what a configuration reload changed. Settings in
+// RestartRequired keep their old value until the gateway is restarted.
+type ReloadResult struct {
+	Applied         []config.Change `json:"applied"`
+	RestartRequired []config.Change `json:"restart_required"`
+}
+
+// SetConfigLoader sets how Reload reads the configuration, typically
+// config.Load with the gateway's command-line options. Once set, SIGHUP
+// reloads too. It must be called before Start.
+func (g *GatewayService) SetConfigLoader(load func() (*config.Config, error)) {
+	g.loadConfig = load
+}
+
+// SetLogLevel makes log.level live: reloads set lvl.
+func (g *GatewayService) SetLogLevel(lvl zap.AtomicLevel) {
+	g.reloadMu.Lock()
+	defer g.reloadMu.Unlock()
+	g.logLevel = &lvl
+}
+
+// SetReplication makes the replication settings live: reloads pass them
+// to m.
+func (g *GatewayService) SetReplication(m *replication.Manager) {
+	g.reloadMu.Lock()
+	defer g.reloadMu.Unlock()
+	g.replication = m
+}
+
+// Config returns the configuration in effect.
+func (g *GatewayService) Config() *config.Config { return g.config.Load() }
+
+// Reload reads the configuration with the loader set by SetConfigLoader
+// and applies it. An invalid configuration changes nothing.
+func (g *GatewayService) Reload() (*ReloadResult, error) {
+	if g.loadConfig == nil {
+		return nil, fmt.Errorf("gateway: reload: no config source")
+	}
+	cfg, err := g.loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	return g.ApplyConfig(cfg)
+}
+
+// ApplyConfig applies the settings of cfg that can change while running:
+// the API admin token and rate limit, the send policy and links, telemetry
+// retention, and the log level and replication settings once SetLogLevel
+// and SetReplication have been called. Every other change is reported as
+// needing a restart.
+func (g *GatewayService) ApplyConfig(cfg *config.Config) (*ReloadResult, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("gateway: reload: invalid settings:\n%w", err)
+	}
+
+	g.reloadMu.Lock()
+	defer g.reloadMu.Unlock()
+
+	old := g.config.Load()
+	next := *cfg
+
+	// Only read on start-up.
+	next.Gateway.ListenAddr = old.Gateway.ListenAddr
+	next.Store.Path = old.Store.Path
+	next.Transport.CaptureFile = old.Transport.CaptureFile
+
+	g.router.SetAdminToken(next.API.AdminToken)
+	g.router.SetRateLimit(next.API.RateLimit, next.API.RateBurst)
+
+	if mt, ok := g.transport.(*transport.MultiTransport); ok {
+		if err := mt.SetPolicy(transport.SendPolicy(next.Transport.SendPolicy)); err != nil {
+			next.Transport.SendPolicy = old.Transport.SendPolicy
+		}
+		next.Transport.Links = g.applyLinks(mt, old.Transport.Links, next.Transport.Links)
+	} else {
+		next.Transport = old.Transport
+	}
+
+	// store.telemetry_retention is read by retentionLoop on every pass.
+
+	if g.logLevel != nil {
+		lvl, err := zapcore.ParseLevel(strings.ToLower(next.Log.Level))
+		if err != nil {
+			return nil, fmt.Errorf("gateway: reload: %w", err)
+		}
+		g.logLevel.SetLevel(lvl)
+	} else {
+		next.Log = old.Log
+	}
+
+	if g.replication != nil {
+		g.replication.SetConfig(next.Replication)
+	} else {
+		next.Replication = old.Replication
+	}
+
+	g.config.Store(&next)
+	res := &ReloadResult{
+		Applied:         config.Diff(old, &next),
+		RestartRequired: config.Diff(&next, cfg),
+	}
+	for _, c := range res.Applied {
+		g.log.Info("gateway: setting changed",
+			zap.String("key", c.Key), zap.String("old", c.Old), zap.String("new", c.New))
+	}
+	for _, c := range res.RestartRequired {
+		g.log.Warn("gateway: setting changes on restart",
+			zap.String("key", c.Key), zap.String("current", c.Old), zap.String("configured", c.New))
+	}
+	g.eventBus.Publish(Event{Type: EventConfigReload, Data: res})
+	return res, nil
+}
+
+// ── internal ──────────────────────────────────────────────────────────────
+
+// reloadLoop reloads the configuration on every SIGHUP until ctx is done.
+func (g *GatewayService) reloadLoop(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			g.log.Info("gateway: SIGHUP – reloading config")
+			if _, err := g.Reload(); err != nil {
+				g.log.Error("gateway: reload failed – keeping current config", zap.Error(err))
+			}
+		}
+	}
+}
+
+// applyLinks brings mt's links from old to want – links are matched by
+// name: changed ones are replaced, missing ones removed, new ones added –
+// and returns the link configs now in effect, in mt's order. A link that
+// cannot be changed keeps its old config; a reordering needs a restart.
+func (g *GatewayService) applyLinks(mt *transport.MultiTransport, old, want []config.LinkConfig) []config.LinkConfig {
+	names := func(lcs []config.LinkConfig) []string {
+		out := make([]string, len(lcs))
+		for i, lc := range lcs {
+			out[i] = lc.EffectiveName(i)
+		}
+		return out
+	}
+	wantNames := names(want)
+	running := slices.Clone(old)
+	runningNames := names(old)
+
+	for i, name := range slices.Clone(runningNames) {
+		j := slices.Index(wantNames, name)
+		if j < 0 || reflect.DeepEqual(old[i], want[j]) {
+			continue
+		}
+		l, err := transport.NewLink(want[j], j, g.log)
+		if err == nil {
+			err = mt.ReplaceLink(l)
+		}
+		if err != nil {
+			g.log.Warn("gateway: replace link", zap.String("link", name), zap.Error(err))
+			continue
+		}
+		running[i] = want[j]
+	}
+
+	// Removals go first so that their pinned channels are free, except that
+	// the last link stays until another has been added.
+	remove := func() {
+		for i := len(running) - 1; i >= 0; i-- {
+			if slices.Contains(wantNames, runningNames[i]) || len(running) == 1 {
+				continue
+			}
+			if err := mt.RemoveLink(runningNames[i]); err != nil {
+				g.log.Warn("gateway: remove link", zap.String("link", runningNames[i]), zap.Error(err))
+				continue
+			}
+			running = slices.Delete(running, i, i+1)
+			runningNames = slices.Delete(runningNames, i, i+1)
+		}
+	}
+	remove()
+	for j, name := range wantNames {
+		if slices.Contains(runningNames, name) {
+			continue
+		}
+		l, err := transport.NewLink(want[j], j, g.log)
+		if err == nil {
+			err = mt.AddLink(l)
+		}
+		if err != nil {
+			g.log.Warn("gateway: add link", zap.String("link", name), zap.Error(err))
+			continue
+		}
+		running = append(running, want[j])
+		runningNames = append(runningNames, name)
+	}
+	remove()
+
+	// An unnamed link is known by its position, which may have moved.
+	for i := range running {
+		if running[i].Name == "" && running[i].EffectiveName(i) != runningNames[i] {
+			running[i].Name = runningNames[i]
+		}
+	}
+	return running
+}
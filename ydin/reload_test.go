@@ -0,0 +1,216 @@
+package gateway
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/gg-glitch-88/meshigo-kore/ydin/config"
+	"github.com/gg-glitch-88/meshigo-kore/ydin/transport"
+)
+
+// newReloadGateway builds a gateway over a MultiTransport of stub links,
+// one per config in links, with links as the configuration in effect.
+// Nothing is connected.
+func newReloadGateway(t *testing.T, links []config.LinkConfig) (*GatewayService, *transport.MultiTransport) {
+	t.Helper()
+	var ls []transport.Link
+	for i, lc := range links {
+		ls = append(ls, transport.Link{Name: lc.EffectiveName(i), Kind: lc.Type, Transport: &linkStub{}, Channels: lc.Channels})
+	}
+	mt, err := transport.NewMultiTransport(transport.PolicyFailover, ls, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := newTestGateway(t, mt)
+	cfg := *g.Config()
+	cfg.Transport.Links = links
+	g.config.Store(&cfg)
+	return g, mt
+}
+
+func tcpLink(name, addr string, channels ...uint32) config.LinkConfig {
+	return config.LinkConfig{Name: name, Type: "tcp", Address: addr, Channels: channels}
+}
+
+func keys(changes []config.Change) []string {
+	out := make([]string, len(changes))
+	for i, c := range changes {
+		out[i] = c.Key
+	}
+	return out
+}
+
+func TestApplyConfigLinks(t *testing.T) {
+	a, b := tcpLink("a", "a.local:4403"), tcpLink("b", "b.local:4403")
+	tests := []struct {
+		name        string
+		old, want   []config.LinkConfig
+		running     []config.LinkConfig // config in effect afterwards
+		kinds       []string            // of mt.Links(), in order
+		restartKeys []string
+	}{
+		{
+			name: "add", old: []config.LinkConfig{a}, want: []config.LinkConfig{a, b},
+			running: []config.LinkConfig{a, b}, kinds: []string{"tcp", "tcp"},
+		},
+		{
+			name: "remove", old: []config.LinkConfig{a, b}, want: []config.LinkConfig{b},
+			running: []config.LinkConfig{b}, kinds: []string{"tcp"},
+		},
+		{
+			name: "replace", old: []config.LinkConfig{a, b},
+			want:    []config.LinkConfig{a, {Name: "b", Type: "serial", Address: "/dev/ttyACM0"}},
+			running: []config.LinkConfig{a, {Name: "b", Type: "serial", Address: "/dev/ttyACM0"}},
+			kinds:   []string{"tcp", "serial"},
+		},
+		{
+			// The last link stays until its successor is in.
+			name: "replace the only link", old: []config.LinkConfig{a}, want: []config.LinkConfig{b},
+			running: []config.LinkConfig{b}, kinds: []string{"tcp"},
+		},
+		{
+			// b cannot be added while a holds channel 1, and a cannot go
+			// before another link is in: a stays.
+			name: "last link kept", old: []config.LinkConfig{tcpLink("a", "a.local:4403", 1)},
+			want:        []config.LinkConfig{tcpLink("b", "b.local:4403", 1)},
+			running:     []config.LinkConfig{tcpLink("a", "a.local:4403", 1)},
+			kinds:       []string{"tcp"},
+			restartKeys: []string{"transport.links.0.name", "transport.links.0.address"},
+		},
+		{
+			name: "reorder", old: []config.LinkConfig{a, b}, want: []config.LinkConfig{b, a},
+			running: []config.LinkConfig{a, b}, kinds: []string{"tcp", "tcp"},
+			restartKeys: []string{
+				"transport.links.0.name", "transport.links.0.address",
+				"transport.links.1.name", "transport.links.1.address",
+			},
+		},
+		{
+			// Channel 1 is still b's when a is replaced; b then lets go of it.
+			name: "replace blocked", old: []config.LinkConfig{a, tcpLink("b", "b.local:4403", 1)},
+			want:        []config.LinkConfig{tcpLink("a", "a.local:4403", 1), b},
+			running:     []config.LinkConfig{a, b},
+			kinds:       []string{"tcp", "tcp"},
+			restartKeys: []string{"transport.links.0.channels"},
+		},
+		{
+			// An unnamed link is known by its position: when a link before
+			// it goes, it keeps the name it runs under.
+			name: "unnamed link moved", old: []config.LinkConfig{a, {Type: "tcp", Address: "x.local:4403"}},
+			want:    []config.LinkConfig{b, {Type: "tcp", Address: "x.local:4403"}},
+			running: []config.LinkConfig{{Name: "tcp-1", Type: "tcp", Address: "x.local:4403"}, b},
+			kinds:   []string{"tcp", "tcp"},
+			restartKeys: []string{
+				"transport.links.0.name", "transport.links.0.address",
+				"transport.links.1.name", "transport.links.1.address",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, mt := newReloadGateway(t, tt.old)
+			cfg := *g.Config()
+			cfg.Transport.Links = tt.want
+
+			res, err := g.ApplyConfig(&cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := g.Config().Transport.Links; !reflect.DeepEqual(got, tt.running) {
+				t.Errorf("links in effect:\n got %+v\nwant %+v", got, tt.running)
+			}
+			var names, kinds []string
+			for _, lh := range mt.Links() {
+				names = append(names, lh.Name)
+				kinds = append(kinds, lh.Kind)
+			}
+			var wantNames []string
+			for i, lc := range tt.running {
+				wantNames = append(wantNames, lc.EffectiveName(i))
+			}
+			if !reflect.DeepEqual(names, wantNames) || !reflect.DeepEqual(kinds, tt.kinds) {
+				t.Errorf("mt.Links() = %v %v, want %v %v", names, kinds, wantNames, tt.kinds)
+			}
+
+			// Whatever is not running as configured waits for a restart;
+			// everything else that changed was applied.
+			if got := keys(res.RestartRequired); !slices.Equal(got, tt.restartKeys) {
+				t.Errorf("restart required = %v, want %v", got, tt.restartKeys)
+			}
+			if want := config.Diff(&config.Config{Transport: config.TransportConfig{Links: tt.old}},
+				&config.Config{Transport: config.TransportConfig{Links: tt.running}}); !reflect.DeepEqual(res.Applied, want) {
+				t.Errorf("applied = %v, want %v", res.Applied, want)
+			}
+		})
+	}
+}
+
+func TestApplyConfigRestartRequired(t *testing.T) {
+	g, _ := newReloadGateway(t, []config.LinkConfig{tcpLink("a", "a.local:4403")})
+	events, unsubscribe := g.eventBus.Subscribe()
+	defer unsubscribe()
+	old := *g.Config()
+
+	cfg := old
+	cfg.Gateway.ListenAddr = ":9999"
+	cfg.Store.Path = "/elsewhere.db"
+	cfg.Transport.CaptureFile = "/tmp/frames.cap"
+	cfg.API.AdminToken = "n3w-t0ken"
+	cfg.API.RateLimit = 5
+	cfg.Transport.SendPolicy = "broadcast"
+	cfg.Log.Level = "debug" // no SetLogLevel: needs a restart too
+
+	res, err := g.ApplyConfig(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantApplied := []string{"api.admin_token", "api.rate_limit", "transport.send_policy"}
+	if got := keys(res.Applied); !slices.Equal(got, wantApplied) {
+		t.Errorf("applied = %v, want %v", got, wantApplied)
+	}
+	wantRestart := []string{"gateway.listen_addr", "transport.capture_file", "store.path", "log.level"}
+	if got := keys(res.RestartRequired); !slices.Equal(got, wantRestart) {
+		t.Errorf("restart required = %v, want %v", got, wantRestart)
+	}
+	for _, c := range res.Applied {
+		if strings.Contains(c.New, "n3w-t0ken") {
+			t.Errorf("admin token not redacted: %v", c)
+		}
+	}
+
+	now := g.Config()
+	if now.Gateway.ListenAddr != old.Gateway.ListenAddr || now.Store.Path != old.Store.Path ||
+		now.Log.Level != old.Log.Level || now.API.AdminToken != "n3w-t0ken" || now.Transport.SendPolicy != "broadcast" {
+		t.Errorf("config in effect = %+v", now)
+	}
+	select {
+	case e := <-events:
+		if e.Type != EventConfigReload || e.Data != res {
+			t.Errorf("event = %+v, want the config_reload result", e)
+		}
+	default:
+		t.Error("no config_reload event")
+	}
+}
+
+func TestApplyConfigInvalid(t *testing.T) {
+	g, mt := newReloadGateway(t, []config.LinkConfig{tcpLink("a", "a.local:4403")})
+	old := g.Config()
+
+	cfg := *old
+	cfg.API.AdminToken = "n3w-t0ken"
+	cfg.Transport.Links = []config.LinkConfig{tcpLink("a", "a.local:4403"), tcpLink("a", "b.local:4403")}
+	if _, err := g.ApplyConfig(&cfg); err == nil {
+		t.Fatal("duplicate link names: want an error")
+	}
+	if g.Config() != old {
+		t.Errorf("config changed to %+v", g.Config())
+	}
+	if links := mt.Links(); len(links) != 1 || links[0].Name != "a" {
+		t.Errorf("links = %+v, want only a", links)
+	}
+}
@@ -0,0 +1,224 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	meshproto "github.com/gg-glitch-88/meshigo-kore/ydin/proto"
+)
+
+const (
+	replayFrameChanSize = 256
+	// replayStartGrace is how long playback waits for the gateway's first
+	// want_config before starting anyway.
+	replayStartGrace = 2 * time.Second
+)
+
+// ReplayTransport plays the inbound frames of a capture file back to the
+// gateway as if a device were sending them. Outbound frames are accepted and
+// discarded.
+//
+// Frames carry their recorded timestamps and are never dropped, so a replay
+// reproduces the original ingest exactly. The one exception is the config
+// handshake: recorded config_complete_ids are rewritten to the ID of the
+// gateway's latest want_config, otherwise the gateway would ignore them.
+type ReplayTransport struct {
+	path     string
+	speed    float64
+	log      *zap.Logger
+	proto    *meshproto.MeshtasticProtobuf
+	frames   chan ProtoFrame
+	state    atomic.Int32 // ConnectionState
+	configID atomic.Uint32
+	wantCfg  chan struct{}
+	done     chan struct{}
+	err      error // why playback failed; written before done is closed
+	mu       sync.Mutex
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// NewReplayTransport replays the capture at path. speed scales the recorded
+// gaps between frames: 1 is real time, 10 ten times faster, and 0 plays
+// frames back to back.
+func NewReplayTransport(path string, speed float64, log *zap.Logger) (*ReplayTransport, error) {
+	if speed < 0 {
+		return nil, fmt.Errorf("replay: speed must not be negative")
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("replay: %w", err)
+	}
+	t := &ReplayTransport{
+		path:    path,
+		speed:   speed,
+		log:     log,
+		proto:   meshproto.New(),
+		frames:  make(chan ProtoFrame, replayFrameChanSize),
+		wantCfg: make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	t.state.Store(int32(StateDisconnected))
+	return t, nil
+}
+
+// Connect starts playback. A ReplayTransport plays its capture once.
+func (t *ReplayTransport) Connect() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.cancel != nil {
+		return nil
+	}
+	select {
+	case <-t.done:
+		return fmt.Errorf("replay: capture already played")
+	default:
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.cancel = cancel
+	t.state.Store(int32(StateConnected))
+	t.wg.Add(1)
+	go t.play(ctx)
+	return nil
+}
+
+func (t *ReplayTransport) Disconnect() error {
+	t.mu.Lock()
+	if t.cancel != nil {
+		t.cancel()
+		t.cancel = nil
+	}
+	t.mu.Unlock()
+
+	t.wg.Wait()
+	t.state.Store(int32(StateDisconnected))
+	return nil
+}
+
+// Send discards frame, noting want_config requests for the handshake.
+func (t *ReplayTransport) Send(frame ProtoFrame) error {
+	msg, err := t.proto.DecodeToRadio(frame.Data)
+	if err != nil {
+		return fmt.Errorf("replay: send: %w", err)
+	}
+	if msg.WantConfigID != 0 {
+		t.configID.Store(msg.WantConfigID)
+		select {
+		case t.wantCfg <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+func (t *ReplayTransport) Receive() <-chan ProtoFrame { return t.frames }
+
+func (t *ReplayTransport) GetConnectionState() ConnectionState {
+	return ConnectionState(t.state.Load())
+}
+
+// Done is closed once playback ends: every frame of the capture has been
+// delivered, the capture could not be read (see Err), or Disconnect
+// stopped it.
+func (t *ReplayTransport) Done() <-chan struct{} { return t.done }
+
+// Err returns the error that ended playback, or nil if the capture played
+// through or Disconnect stopped it. It is only meaningful once Done is
+// closed.
+func (t *ReplayTransport) Err() error {
+	select {
+	case <-t.done:
+		return t.err
+	default:
+		return nil
+	}
+}
+
+// ── internal ──────────────────────────────────────────────────────────────
+
+func (t *ReplayTransport) play(ctx context.Context) {
+	defer t.wg.Done()
+	defer close(t.done)
+
+	f, err := os.Open(t.path)
+	if err != nil {
+		t.log.Error("replay: open capture", zap.Error(err))
+		t.err = fmt.Errorf("replay: %w", err)
+		t.state.Store(int32(StateFailed))
+		return
+	}
+	defer f.Close()
+
+	select {
+	case <-ctx.Done():
+		return
+	case <-t.wantCfg:
+	case <-time.After(replayStartGrace):
+	}
+
+	cr := NewCaptureReader(f)
+	var prev time.Time
+	frames := 0
+	for {
+		rec, err := cr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.log.Error("replay: stopped", zap.Error(err))
+			t.err = fmt.Errorf("replay: after %d frames: %w", frames, err)
+			t.state.Store(int32(StateFailed))
+			return
+		}
+		if rec.Direction != CaptureIn {
+			continue
+		}
+
+		if t.speed > 0 && !prev.IsZero() && rec.Time.After(prev) {
+			gap := time.Duration(float64(rec.Time.Sub(prev)) / t.speed)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(gap):
+			}
+		}
+		prev = rec.Time
+
+		frame := ProtoFrame{Data: t.rewriteConfigID(rec.Data), Timestamp: rec.Time, Source: rec.Transport}
+		select {
+		case <-ctx.Done():
+			return
+		case t.frames <- frame:
+			frames++
+		}
+	}
+
+	t.log.Info("replay: capture finished", zap.String("path", t.path), zap.Int("frames", frames))
+}
+
+// rewriteConfigID points a recorded config_complete_id at the gateway's
+// current handshake.
+func (t *ReplayTransport) rewriteConfigID(data []byte) []byte {
+	id := t.configID.Load()
+	if id == 0 {
+		return data
+	}
+	fr, err := t.proto.DecodeFromRadio(data)
+	if err != nil || fr.ConfigCompleteID == 0 {
+		return data
+	}
+	fr.ConfigCompleteID = id
+	out, err := t.proto.EncodeFromRadio(fr)
+	if err != nil {
+		return data
+	}
+	return out
+}
@@ -0,0 +1,150 @@
+package transport
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	meshproto "github.com/gg-glitch-88/meshigo-kore/ydin/proto"
+)
+
+// writeCapture writes frames as inbound records, then appends tail as is.
+func writeCapture(t *testing.T, frames [][]byte, tail string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	cw := NewCaptureWriter(&buf)
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	for i, data := range frames {
+		if err := cw.Record(CaptureIn, "radio", ProtoFrame{Data: data, Timestamp: at.Add(time.Duration(i) * time.Second)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	buf.WriteString(tail)
+	path := filepath.Join(t.TempDir(), "capture.jsonl")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func startReplay(t *testing.T, path string) *ReplayTransport {
+	t.Helper()
+	rt, err := NewReplayTransport(path, 0, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rt.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { rt.Disconnect() }) //nolint:errcheck
+	// Start playback without waiting out replayStartGrace.
+	data, err := meshproto.New().EncodeToRadio(&meshproto.ToRadio{WantConfigID: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rt.Send(ProtoFrame{Data: data}); err != nil {
+		t.Fatal(err)
+	}
+	return rt
+}
+
+func waitDone(t *testing.T, rt *ReplayTransport) {
+	t.Helper()
+	select {
+	case <-rt.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("Done not closed")
+	}
+}
+
+func TestReplayTransport(t *testing.T) {
+	m := meshproto.New()
+	var frames [][]byte
+	for _, fr := range []*meshproto.FromRadio{
+		{MyInfo: &meshproto.MyNodeInfo{MyNodeNum: 0xdeadbeef}},
+		{ConfigCompleteID: 99},
+		{Packet: &meshproto.MeshPacket{ID: 1, From: 2, PortNum: meshproto.PortTextMessage, Payload: []byte("hi")}},
+	} {
+		data, err := m.EncodeFromRadio(fr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		frames = append(frames, data)
+	}
+	rt := startReplay(t, writeCapture(t, frames, ""))
+
+	for i := range frames {
+		fr := decodeFrame(t, receiveFrame(t, rt))
+		if i == 1 && fr.ConfigCompleteID != 5 {
+			t.Errorf("config_complete_id = %d, want it rewritten to 5", fr.ConfigCompleteID)
+		}
+	}
+	waitDone(t, rt)
+	if err := rt.Err(); err != nil {
+		t.Errorf("Err = %v", err)
+	}
+	if err := rt.Disconnect(); err != nil {
+		t.Fatal(err)
+	}
+	if err := rt.Connect(); err == nil {
+		t.Error("Connect after playback: want an error")
+	}
+}
+
+func TestReplayTransportCorrupt(t *testing.T) {
+	data, err := meshproto.New().EncodeFromRadio(&meshproto.FromRadio{ConfigCompleteID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rt := startReplay(t, writeCapture(t, [][]byte{data}, "{not json\n"))
+	receiveFrame(t, rt)
+	waitDone(t, rt)
+	if rt.Err() == nil {
+		t.Error("Err = nil, want the decode error")
+	}
+	if s := rt.GetConnectionState(); s != StateFailed {
+		t.Errorf("state = %s, want failed", s)
+	}
+}
+
+func TestReplayTransportOpenFailure(t *testing.T) {
+	path := writeCapture(t, nil, "")
+	rt, err := NewReplayTransport(path, 0, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Gone between NewReplayTransport and playback.
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := rt.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Disconnect() //nolint:errcheck
+	waitDone(t, rt)
+	if rt.Err() == nil {
+		t.Error("Err = nil, want the open error")
+	}
+}
+
+func TestReplayTransportDisconnect(t *testing.T) {
+	rt, err := NewReplayTransport(writeCapture(t, nil, ""), 0, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rt.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	// Still waiting for want_config when stopped.
+	if err := rt.Disconnect(); err != nil {
+		t.Fatal(err)
+	}
+	waitDone(t, rt)
+	if err := rt.Err(); err != nil {
+		t.Errorf("Err = %v, want nil after Disconnect", err)
+	}
+}
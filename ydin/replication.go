@@ -5,6 +5,9 @@ package replication
 import (
 	"context"
 	"fmt"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -24,29 +27,48 @@ type Peer struct {
 
 // Manager orchestrates peer discovery and content replication.
 type Manager struct {
-	cfg   *config.ReplicationConfig
-	db    *store.DB
-	log   *zap.Logger
-	mu    sync.RWMutex
-	peers map[string]*Peer
+	db      *store.DB
+	log     *zap.Logger
+	mu      sync.RWMutex
+	cfg     config.ReplicationConfig
+	blocked map[string]bool // cfg.BlockedNodes, normalised to "!%08x"
+	peers   map[string]*Peer
 }
 
 // New creates a Manager. Call Start to begin background work.
 func New(cfg *config.ReplicationConfig, db *store.DB, log *zap.Logger) *Manager {
-	return &Manager{
-		cfg:   cfg,
+	m := &Manager{
 		db:    db,
 		log:   log,
 		peers: make(map[string]*Peer),
 	}
+	m.SetConfig(*cfg)
+	return m
+}
+
+// SetConfig replaces the limits and content policy. Peers already
+// registered are kept even if there are now more than MaxPeers; messages
+// skipped under the old policy are reconsidered on the next sync.
+func (m *Manager) SetConfig(cfg config.ReplicationConfig) {
+	blocked := make(map[string]bool, len(cfg.BlockedNodes))
+	for _, id := range cfg.BlockedNodes {
+		blocked[normalizeNodeID(id)] = true
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cfg = cfg
+	m.blocked = blocked
 }
 
 // Start launches discovery and sync loops; blocks until ctx is done.
 func (m *Manager) Start(ctx context.Context) error {
+	m.mu.RLock()
 	m.log.Info("replication manager starting",
 		zap.Int("max_peers", m.cfg.MaxPeers),
 		zap.Int64("storage_limit_bytes", m.cfg.StorageLimitBytes),
 	)
+	m.mu.RUnlock()
 
 	ticker := time.NewTicker(30 * time.Second)
 	defer ticker.Stop()
@@ -91,27 +113,43 @@ func (m *Manager) PeerCount() int {
 
 // ── Content policy ────────────────────────────────────────────────────────
 
-// AllowedToReplicate checks content policy for a given message.
-// Currently: always allow. Extend with block-lists, size caps, etc.
+// AllowedToReplicate checks content policy for a given message: its
+// payload must fit the storage limit, if there is one, its sender must not
+// be blocked and, if replication is restricted to some channels, it must be
+// on one of them.
 func (m *Manager) AllowedToReplicate(msg *store.Message) bool {
-	if int64(len(msg.Payload)) > m.cfg.StorageLimitBytes {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if limit := m.cfg.StorageLimitBytes; limit > 0 && int64(len(msg.Payload)) > limit {
 		m.log.Warn("replication: payload exceeds storage limit – skipping",
 			zap.String("mesh_id", msg.MeshID),
 			zap.Int("payload_bytes", len(msg.Payload)),
 		)
 		return false
 	}
+	if m.blocked[msg.FromNode] {
+		return false
+	}
+	if len(m.cfg.Channels) > 0 && !slices.Contains(m.cfg.Channels, uint32(msg.Channel)) {
+		return false
+	}
 	return true
 }
 
+// normalizeNodeID turns a configured node ID, with or without the "!", into
+// the "!%08x" form messages are stored with.
+func normalizeNodeID(id string) string {
+	n, err := strconv.ParseUint(strings.TrimPrefix(id, "!"), 16, 32)
+	if err != nil {
+		return id
+	}
+	return fmt.Sprintf("!%08x", n)
+}
+
 // ── Internal sync ─────────────────────────────────────────────────────────
 
 func (m *Manager) syncUnsyncedMessages() {
-	msgs, err := m.db.ListMessages(100)
-	if err != nil {
-		m.log.Error("replication: list messages", zap.Error(err))
-		return
-	}
 	m.mu.RLock()
 	peerCount := len(m.peers)
 	m.mu.RUnlock()
@@ -120,16 +158,29 @@ func (m *Manager) syncUnsyncedMessages() {
 		return
 	}
 
-	for _, msg := range msgs {
-		if msg.Synced {
-			continue
+	// Page through every unsynced message: ones the content policy skips
+	// stay unsynced and must not hide those behind them.
+	q := store.MessageQuery{Unsynced: true, Limit: 100}
+	for {
+		msgs, next, err := m.db.ListMessages(q)
+		if err != nil {
+			m.log.Error("replication: list messages", zap.Error(err))
+			return
+		}
+		var synced []int64
+		for _, msg := range msgs {
+			if !m.AllowedToReplicate(msg) {
+				continue
+			}
+			// TODO: push msg.Payload to connected peers via transport.
+			synced = append(synced, msg.ID)
 		}
-		if !m.AllowedToReplicate(msg) {
-			continue
+		if err := m.db.MarkSynced(synced...); err != nil {
+			m.log.Warn("replication: mark synced", zap.Error(err), zap.Int("count", len(synced)))
 		}
-		// TODO: push msg.Payload to connected peers via transport.
-		if err := m.db.MarkSynced(msg.ID); err != nil {
-			m.log.Warn("replication: mark synced", zap.Error(err), zap.Int64("id", msg.ID))
+		if next == 0 {
+			return
 		}
+		q.Before = next
 	}
 }
@@ -0,0 +1,34 @@
+package replication
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/gg-glitch-88/meshigo-kore/ydin/config"
+	"github.com/gg-glitch-88/meshigo-kore/ydin/store"
+)
+
+func TestAllowedToReplicate(t *testing.T) {
+	big := &store.Message{FromNode: "!00000001", Payload: make([]byte, 2048)}
+	tests := []struct {
+		name string
+		cfg  config.ReplicationConfig
+		msg  *store.Message
+		want bool
+	}{
+		{"within limit", config.ReplicationConfig{StorageLimitBytes: 4096}, big, true},
+		{"over limit", config.ReplicationConfig{StorageLimitBytes: 1024}, big, false},
+		{"no limit", config.ReplicationConfig{}, big, true},
+		{"blocked sender", config.ReplicationConfig{BlockedNodes: []string{"!00000001"}}, big, false},
+		{"other channel", config.ReplicationConfig{Channels: []uint32{1}}, big, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New(&tt.cfg, nil, zap.NewNop())
+			if got := m.AllowedToReplicate(tt.msg); got != tt.want {
+				t.Errorf("AllowedToReplicate = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
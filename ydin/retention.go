@@ -0,0 +1,36 @@
+package gateway
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/gg-glitch-88/meshigo-kore/ydin/store"
+)
+
+// retentionInterval is how often expired telemetry is pruned.
+const retentionInterval = time.Hour
+
+// retentionLoop prunes the telemetry time series on start-up and then
+// every retentionInterval, keeping what store.telemetry_retention asks for.
+func (g *GatewayService) retentionLoop(ctx context.Context) {
+	ticker := time.NewTicker(retentionInterval)
+	defer ticker.Stop()
+
+	for {
+		keep := store.TelemetryRetention(g.config.Load().Store.TelemetryRetention)
+		n, err := g.stateStore.PruneTelemetry(keep)
+		if err != nil {
+			g.log.Warn("gateway: prune telemetry", zap.Error(err))
+		} else if n > 0 {
+			g.log.Info("gateway: pruned telemetry", zap.Int64("rows", n))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package transport
+
+import (
+	"bytes"
+	"io"
+
+	"go.bug.st/serial"
+	"go.uber.org/zap"
+)
+
+const (
+	serialDefaultBaud = 115200
+	serialWakeBytes   = 32 // START2 bytes sent on open to resync the device parser
+)
+
+// SerialOpener opens the serial device at path. It is swappable so tests can
+// hand SerialTransport one end of an in-memory pipe instead of real hardware.
+type SerialOpener func(path string, baud int) (io.ReadWriteCloser, error)
+
+// OpenSerialPort is the default SerialOpener, backed by go.bug.st/serial.
+func OpenSerialPort(path string, baud int) (io.ReadWriteCloser, error) {
+	return serial.Open(path, &serial.Mode{BaudRate: baud})
+}
+
+// SerialTransport connects to a Meshtastic device over USB serial
+// (e.g. /dev/ttyUSB0 or /dev/ttyACM0 on a Heltec board).
+// It uses Meshtastic's stream framing: 0x94 0xC3 + 16-bit length + payload.
+type SerialTransport struct {
+	*streamLink
+	path   string
+	baud   int
+	opener SerialOpener
+}
+
+// NewSerialTransport constructs a SerialTransport for the device at path.
+// A zero baud selects 115200, the Meshtastic default. A nil opener selects
+// OpenSerialPort.
+func NewSerialTransport(path string, baud int, opener SerialOpener, log *zap.Logger) *SerialTransport {
+	if baud == 0 {
+		baud = serialDefaultBaud
+	}
+	if opener == nil {
+		opener = OpenSerialPort
+	}
+	t := &SerialTransport{path: path, baud: baud, opener: opener}
+	t.streamLink = newStreamLink("serial", t.open, log, zap.String("path", path), zap.Int("baud", baud))
+	return t
+}
+
+// ── internal ──────────────────────────────────────────────────────────────
+
+// open opens the port and wakes the device on it.
+func (t *SerialTransport) open() (io.ReadWriteCloser, error) {
+	port, err := t.opener(t.path, t.baud)
+	if err != nil {
+		return nil, err
+	}
+	if err := wakeDevice(port); err != nil {
+		port.Close()
+		return nil, err
+	}
+	return port, nil
+}
+
+// wakeDevice sends a run of START2 bytes, which the firmware's stream parser
+// treats as noise, to flush any half-received frame left from a previous
+// session before the first real request.
+func wakeDevice(w io.Writer) error {
+	_, err := w.Write(bytes.Repeat([]byte{streamStart2}, serialWakeBytes))
+	return err
+}
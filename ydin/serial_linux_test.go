@@ -0,0 +1,104 @@
+//go:build linux
+
+package transport
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+	"unsafe"
+
+	"go.uber.org/zap"
+)
+
+// openPTY opens a pseudo-terminal pair and returns its master end and the
+// path of its slave, which go.bug.st/serial opens like any other tty.
+func openPTY(t *testing.T) (*os.File, string) {
+	t.Helper()
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		t.Skipf("no pty support: %v", err)
+	}
+	t.Cleanup(func() { master.Close() })
+
+	var unlock int32
+	var n uint32
+	if err := ioctl(master, syscall.TIOCSPTLCK, unsafe.Pointer(&unlock)); err != nil {
+		t.Fatalf("unlock pty: %v", err)
+	}
+	if err := ioctl(master, syscall.TIOCGPTN, unsafe.Pointer(&n)); err != nil {
+		t.Fatalf("pty number: %v", err)
+	}
+	return master, fmt.Sprintf("/dev/pts/%d", n)
+}
+
+func ioctl(f *os.File, req uint, arg unsafe.Pointer) error {
+	conn, err := f.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var errno syscall.Errno
+	if err := conn.Control(func(fd uintptr) {
+		_, _, errno = syscall.Syscall(syscall.SYS_IOCTL, fd, uintptr(req), uintptr(arg))
+	}); err != nil {
+		return err
+	}
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// TestSerialTransportPTY runs SerialTransport over OpenSerialPort, the
+// go.bug.st/serial opener used for real hardware, with the test playing the
+// device on the master end of a pty.
+func TestSerialTransportPTY(t *testing.T) {
+	dev, path := openPTY(t)
+	tr := NewSerialTransport(path, 0, nil, zap.NewNop())
+	if err := tr.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer tr.Disconnect() //nolint:errcheck
+	waitState(t, tr, StateConnected)
+
+	dev.SetDeadline(time.Now().Add(2 * time.Second)) //nolint:errcheck
+	wake := make([]byte, serialWakeBytes)
+	if _, err := io.ReadFull(dev, wake); err != nil {
+		t.Fatalf("read wake-up bytes: %v", err)
+	}
+	if !bytes.Equal(wake, bytes.Repeat([]byte{streamStart2}, serialWakeBytes)) {
+		t.Fatalf("wake-up bytes = %x", wake)
+	}
+
+	// The port is raw: bytes the tty layer would otherwise translate (CR,
+	// NL, ^C, ^D) pass through untouched.
+	payload := []byte{'\r', '\n', 0x03, 0x04, 0x7f}
+	if _, err := dev.Write(append([]byte{0x94, 0xC3, 0x00, byte(len(payload))}, payload...)); err != nil {
+		t.Fatal(err)
+	}
+	if got := receiveFrame(t, tr); !bytes.Equal(got, payload) {
+		t.Errorf("received %x, want %x", got, payload)
+	}
+
+	if err := tr.Send(ProtoFrame{Data: payload}); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 4+len(payload))
+	if _, err := io.ReadFull(dev, buf); err != nil {
+		t.Fatal(err)
+	}
+	if want := append([]byte{0x94, 0xC3, 0x00, byte(len(payload))}, payload...); !bytes.Equal(buf, want) {
+		t.Errorf("device read %x, want %x", buf, want)
+	}
+
+	if err := tr.Disconnect(); err != nil {
+		t.Fatal(err)
+	}
+	if s := tr.GetConnectionState(); s != StateDisconnected {
+		t.Errorf("state after Disconnect = %s", s)
+	}
+}
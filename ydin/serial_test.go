@@ -0,0 +1,148 @@
+package transport
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// pipeOpener hands SerialTransport the host end of a net.Pipe per open and
+// passes the device end to the test.
+type pipeOpener struct {
+	devices chan net.Conn
+}
+
+func newPipeOpener() *pipeOpener {
+	return &pipeOpener{devices: make(chan net.Conn, 1)}
+}
+
+func (o *pipeOpener) open(path string, baud int) (io.ReadWriteCloser, error) {
+	host, dev := net.Pipe()
+	o.devices <- dev
+	return host, nil
+}
+
+// device waits for the next open and consumes the wake-up bytes the
+// transport writes first.
+func (o *pipeOpener) device(t *testing.T) net.Conn {
+	t.Helper()
+	select {
+	case dev := <-o.devices:
+		wake := make([]byte, serialWakeBytes)
+		dev.SetDeadline(time.Now().Add(2 * time.Second)) //nolint:errcheck
+		if _, err := io.ReadFull(dev, wake); err != nil {
+			t.Fatalf("read wake-up bytes: %v", err)
+		}
+		if !bytes.Equal(wake, bytes.Repeat([]byte{streamStart2}, serialWakeBytes)) {
+			t.Fatalf("wake-up bytes = %x", wake)
+		}
+		return dev
+	case <-time.After(2 * time.Second):
+		t.Fatal("transport did not open the port")
+		return nil
+	}
+}
+
+func waitState(t *testing.T, tr TransportManager, want ConnectionState) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for tr.GetConnectionState() != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("state = %s, want %s", tr.GetConnectionState(), want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func receiveFrame(t *testing.T, tr TransportManager) []byte {
+	t.Helper()
+	select {
+	case f := <-tr.Receive():
+		return f.Data
+	case <-time.After(2 * time.Second):
+		t.Fatal("no frame received")
+		return nil
+	}
+}
+
+func TestSerialTransport(t *testing.T) {
+	o := newPipeOpener()
+	tr := NewSerialTransport("/dev/ttyTEST", 0, o.open, zap.NewNop())
+	if err := tr.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer tr.Disconnect() //nolint:errcheck
+
+	dev := o.device(t)
+	waitState(t, tr, StateConnected)
+
+	// Device → host: a frame between lines of console output.
+	go func() {
+		dev.Write([]byte("INFO | booting\r\n"))            //nolint:errcheck
+		dev.Write([]byte{0x94, 0xC3, 0x00, 0x03, 1, 2, 3}) //nolint:errcheck
+	}()
+	if got := receiveFrame(t, tr); !bytes.Equal(got, []byte{1, 2, 3}) {
+		t.Errorf("received %x, want 010203", got)
+	}
+
+	// Host → device.
+	errc := make(chan error, 1)
+	go func() { errc <- tr.Send(ProtoFrame{Data: []byte{0xAA, 0xBB}}) }()
+	buf := make([]byte, 6)
+	if _, err := io.ReadFull(dev, buf); err != nil {
+		t.Fatal(err)
+	}
+	if want := []byte{0x94, 0xC3, 0x00, 0x02, 0xAA, 0xBB}; !bytes.Equal(buf, want) {
+		t.Errorf("device read %x, want %x", buf, want)
+	}
+	if err := <-errc; err != nil {
+		t.Errorf("Send: %v", err)
+	}
+
+	// Unplugging the board reopens the port, and frames flow again.
+	dev.Close()
+	dev = o.device(t)
+	waitState(t, tr, StateConnected)
+	go dev.Write([]byte{0x94, 0xC3, 0x00, 0x01, 9}) //nolint:errcheck
+	if got := receiveFrame(t, tr); !bytes.Equal(got, []byte{9}) {
+		t.Errorf("after reconnect received %x, want 09", got)
+	}
+
+	if err := tr.Disconnect(); err != nil {
+		t.Fatal(err)
+	}
+	if s := tr.GetConnectionState(); s != StateDisconnected {
+		t.Errorf("state after Disconnect = %s", s)
+	}
+	if err := tr.Send(ProtoFrame{Data: []byte{1}}); err == nil {
+		t.Error("Send after Disconnect: want an error")
+	}
+}
+
+func TestSerialTransportOpenFailure(t *testing.T) {
+	opened := make(chan struct{}, 1)
+	fail := func(path string, baud int) (io.ReadWriteCloser, error) {
+		opened <- struct{}{}
+		return nil, errors.New("no such device")
+	}
+	tr := NewSerialTransport("/dev/ttyMISSING", 0, fail, zap.NewNop())
+	if err := tr.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	<-opened
+	waitState(t, tr, StateFailed)
+
+	// Disconnect must not wait out the retry backoff.
+	start := time.Now()
+	if err := tr.Disconnect(); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("Disconnect took %s", d)
+	}
+}
@@ -0,0 +1,281 @@
+// Package sim is an in-process Meshtastic mesh: virtual nodes on a map,
+// joined by a LoRa-ish radio model, each exposing the same phone API as a
+// real device through a TransportManager. It lets the gateway run, and be
+// tested end to end, without hardware.
+package sim
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	meshproto "github.com/gg-glitch-88/meshigo-kore/ydin/proto"
+)
+
+// Radio model defaults: LongFast (SF11, 250 kHz, CR 4/5) at 20 dBm, with a
+// log-distance path loss that gives roughly 25 km of line-of-sight range.
+const (
+	defaultTxPowerDBm   = 20.0
+	defaultNoiseDBm     = -120.0
+	defaultMinSNR       = -17.5 // SF11 demodulation floor
+	pathLossAt1m        = 40.0  // dB
+	pathLossExponent    = 2.7
+	defaultHopLimit     = 3
+	defaultRebroadcast  = 2 * time.Second
+	historyTTL          = 10 * time.Minute
+	utilWindow          = time.Minute
+	airtimeWindow       = time.Hour
+	loraSpreadingFactor = 11
+	loraBandwidthHz     = 250_000
+	loraPreamble        = 16
+	loraHeaderBytes     = 16 // Meshtastic radio header in front of the payload
+)
+
+// Config tunes the radio model and traffic generators. Zero values select
+// the defaults noted on each field.
+type Config struct {
+	// Seed seeds the RNG behind packet IDs, random loss and relay jitter.
+	// Packets move in real time on timers, so the order in which those
+	// draws happen, and so a run, is not reproducible from the seed alone.
+	Seed uint64
+
+	LossRate         float64       // extra random loss on every link, 0–1
+	RebroadcastDelay time.Duration // max random wait before relaying (2s)
+	MinSNR           float64       // below this a packet is never heard (-17.5 dB)
+	NoiseFloor       float64       // dBm (-120)
+
+	// Traffic generators; zero disables the corresponding traffic.
+	NodeInfoInterval  time.Duration
+	PositionInterval  time.Duration
+	TelemetryInterval time.Duration
+	TextInterval      time.Duration // random chatter on the primary channel
+}
+
+// NodeConfig places one virtual node.
+type NodeConfig struct {
+	Num       uint32
+	LongName  string
+	ShortName string
+	Hardware  string // HardwareModel enum name, e.g. "HELTEC_V3"
+	Role      string // Config.DeviceConfig.Role name, e.g. "CLIENT"
+
+	Lat, Lon float64 // degrees
+	Alt      int32   // metres
+	Mobile   bool    // wanders a little between position reports
+
+	HopLimit uint32  // for packets it originates (3)
+	TxPower  float64 // dBm (20)
+	Battery  uint32  // starting percent (100); 101 means mains powered
+}
+
+// Network is a simulated mesh. All methods are safe for concurrent use.
+type Network struct {
+	cfg   Config
+	log   *zap.Logger
+	proto *meshproto.MeshtasticProtobuf
+
+	mu      sync.Mutex
+	rng     *rand.Rand
+	nodes   map[uint32]*Node
+	order   []uint32 // insertion order, for stable iteration
+	started time.Time
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// New creates an empty network. Add nodes, then Start it.
+func New(cfg Config, log *zap.Logger) *Network {
+	if cfg.RebroadcastDelay == 0 {
+		cfg.RebroadcastDelay = defaultRebroadcast
+	}
+	if cfg.MinSNR == 0 {
+		cfg.MinSNR = defaultMinSNR
+	}
+	if cfg.NoiseFloor == 0 {
+		cfg.NoiseFloor = defaultNoiseDBm
+	}
+	return &Network{
+		cfg:   cfg,
+		log:   log,
+		proto: meshproto.New(),
+		rng:   rand.New(rand.NewPCG(cfg.Seed, cfg.Seed^0x9e3779b97f4a7c15)),
+		nodes: make(map[uint32]*Node),
+	}
+}
+
+// AddNode places a node on the map and returns it. Node numbers must be
+// unique and neither 0 nor the broadcast address.
+func (n *Network) AddNode(nc NodeConfig) (*Node, error) {
+	if nc.Num == 0 || nc.Num == meshproto.BroadcastAddr {
+		return nil, fmt.Errorf("sim: invalid node number %d", nc.Num)
+	}
+	if nc.HopLimit == 0 {
+		nc.HopLimit = defaultHopLimit
+	}
+	if nc.TxPower == 0 {
+		nc.TxPower = defaultTxPowerDBm
+	}
+	if nc.Battery == 0 {
+		nc.Battery = 100
+	}
+	if nc.LongName == "" {
+		nc.LongName = fmt.Sprintf("Meshtastic %04x", nc.Num&0xffff)
+	}
+	if nc.ShortName == "" {
+		nc.ShortName = fmt.Sprintf("%04x", nc.Num&0xffff)
+	}
+	if nc.Hardware == "" {
+		nc.Hardware = "PORTDUINO"
+	}
+	if nc.Role == "" {
+		nc.Role = "CLIENT"
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if _, ok := n.nodes[nc.Num]; ok {
+		return nil, fmt.Errorf("sim: node %d already exists", nc.Num)
+	}
+	node := &Node{
+		net:     n,
+		cfg:     nc,
+		lat:     nc.Lat,
+		lon:     nc.Lon,
+		battery: nc.Battery,
+		history: make(map[histKey]*histEntry),
+		nodeDB:  make(map[uint32]*meshproto.NodeInfo),
+	}
+	node.radio = newRadio(node)
+	n.nodes[nc.Num] = node
+	n.order = append(n.order, nc.Num)
+	return node, nil
+}
+
+// Node returns the node numbered num.
+func (n *Network) Node(num uint32) (*Node, bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	node, ok := n.nodes[num]
+	return node, ok
+}
+
+// Start begins generating traffic. Packets sent through a Radio are carried
+// whether or not the network is started.
+func (n *Network) Start(ctx context.Context) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.cancel != nil {
+		return
+	}
+	n.started = time.Now()
+	n.ctx, n.cancel = context.WithCancel(ctx)
+	for _, num := range n.order {
+		n.wg.Add(1)
+		go n.nodes[num].trafficLoop(n.ctx)
+	}
+}
+
+// Stop halts traffic generation and waits for the generators to exit.
+func (n *Network) Stop() {
+	n.mu.Lock()
+	cancel := n.cancel
+	n.cancel = nil
+	n.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+	n.wg.Wait()
+}
+
+// ── Radio model ───────────────────────────────────────────────────────────
+
+// transmit puts pkt on the air from node src. Every node in range hears it
+// once the frame's time on air has elapsed. n.mu must be held.
+func (n *Network) transmit(src *Node, pkt *meshproto.MeshPacket) {
+	dur := airtime(len(pkt.Payload) + loraHeaderBytes + 8) // + Data framing
+	now := time.Now()
+	src.addAirtime(now, dur, true)
+
+	for _, num := range n.order {
+		dst := n.nodes[num]
+		if dst == src {
+			continue
+		}
+		snr, rssi := n.link(src, dst)
+		if snr < n.cfg.MinSNR {
+			continue
+		}
+		dst.addAirtime(now, dur, false)
+		if n.rng.Float64() < n.lossProbability(snr) {
+			continue
+		}
+		rx := *pkt
+		rx.RxSNR = float32(math.Round(snr*4) / 4) // firmware reports quarter-dB steps
+		rx.RxRSSI = int32(math.Round(rssi))
+		time.AfterFunc(dur, func() {
+			n.mu.Lock()
+			defer n.mu.Unlock()
+			dst.receive(&rx)
+		})
+	}
+}
+
+// link returns the SNR and RSSI at dst of a transmission from src.
+func (n *Network) link(src, dst *Node) (snr, rssi float64) {
+	d := max(distance(src.lat, src.lon, dst.lat, dst.lon), 1)
+	rssi = src.cfg.TxPower - (pathLossAt1m + 10*pathLossExponent*math.Log10(d))
+	return rssi - n.cfg.NoiseFloor, rssi
+}
+
+// lossProbability rises steeply as snr approaches the demodulation floor,
+// on top of the configured base loss.
+func (n *Network) lossProbability(snr float64) float64 {
+	margin := snr - n.cfg.MinSNR
+	edge := math.Exp(-margin / 2)
+	return min(1, n.cfg.LossRate+(1-n.cfg.LossRate)*edge)
+}
+
+// airtime is the LoRa time on air of a payload of size bytes at the
+// LongFast settings (SF11, BW 250 kHz, CR 4/5, explicit header, CRC on).
+func airtime(size int) time.Duration {
+	const sf = loraSpreadingFactor
+	tsym := float64(int(1)<<sf) / loraBandwidthHz
+	num := float64(8*size - 4*sf + 28 + 16)
+	payloadSyms := 8 + max(math.Ceil(num/float64(4*sf))*5, 0)
+	secs := tsym * (loraPreamble + 4.25 + payloadSyms)
+	return time.Duration(secs * float64(time.Second))
+}
+
+// distance is the great-circle distance in metres.
+func distance(lat1, lon1, lat2, lon2 float64) float64 {
+	const earthRadius = 6_371_000.0
+	rad := math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLon := (lon2 - lon1) * rad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(a))
+}
+
+// newPacketID returns a random non-zero packet ID. n.mu must be held.
+func (n *Network) newPacketID() uint32 {
+	for {
+		if id := n.rng.Uint32(); id != 0 {
+			return id
+		}
+	}
+}
+
+// jitter returns a random duration in [0, d). n.mu must be held.
+func (n *Network) jitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(n.rng.Int64N(int64(d)))
+}
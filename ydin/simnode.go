@@ -0,0 +1,371 @@
+package sim
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"go.uber.org/zap"
+
+	meshproto "github.com/gg-glitch-88/meshigo-kore/ydin/proto"
+)
+
+type histKey struct {
+	from, id uint32
+}
+
+// histEntry is a node's memory of one packet, as in the firmware's
+// PacketHistory.
+type histEntry struct {
+	seen    time.Time
+	relay   *time.Timer // pending rebroadcast, cancelled if a neighbour relays first
+	awaitTx bool        // we originated it with want_ack and await an implicit ack
+}
+
+type airtimeEvent struct {
+	at  time.Time
+	dur time.Duration
+	tx  bool
+}
+
+// Node is one virtual Meshtastic device. Its phone API is Radio().
+type Node struct {
+	net   *Network
+	cfg   NodeConfig
+	radio *Radio
+
+	// Guarded by net.mu.
+	lat, lon float64
+	battery  uint32
+	history  map[histKey]*histEntry
+	nodeDB   map[uint32]*meshproto.NodeInfo
+	airtime  []airtimeEvent
+}
+
+// Num returns the node number.
+func (nd *Node) Num() uint32 { return nd.cfg.Num }
+
+// Radio returns the node's phone API as a transport.TransportManager.
+func (nd *Node) Radio() *Radio { return nd.radio }
+
+// MoveTo relocates the node.
+func (nd *Node) MoveTo(lat, lon float64) {
+	nd.net.mu.Lock()
+	defer nd.net.mu.Unlock()
+	nd.lat, nd.lon = lat, lon
+}
+
+// send originates pkt from this node, as the firmware does for a packet
+// handed over the phone API. net.mu must be held.
+func (nd *Node) send(pkt *meshproto.MeshPacket) {
+	out := *pkt
+	out.From = nd.cfg.Num
+	if out.ID == 0 {
+		out.ID = nd.net.newPacketID()
+	}
+	if out.HopLimit == 0 || out.HopLimit > 7 {
+		out.HopLimit = nd.cfg.HopLimit
+	}
+	out.HopStart = out.HopLimit
+	out.RxTime = uint32(time.Now().Unix())
+
+	nd.history[histKey{out.From, out.ID}] = &histEntry{
+		seen:    time.Now(),
+		awaitTx: out.WantAck && out.To == meshproto.BroadcastAddr,
+	}
+	nd.net.transmit(nd, &out)
+}
+
+// receive handles one packet heard over the air. net.mu must be held.
+func (nd *Node) receive(pkt *meshproto.MeshPacket) {
+	nd.expireHistory()
+
+	k := histKey{pkt.From, pkt.ID}
+	if h, ok := nd.history[k]; ok {
+		// A duplicate: someone relayed it. Our own broadcast coming back is
+		// the implicit ACK; a pending relay of ours is now redundant.
+		if h.awaitTx {
+			h.awaitTx = false
+			nd.deliverAck(pkt.ID, meshproto.RoutingNone)
+		}
+		if h.relay != nil && h.relay.Stop() {
+			h.relay = nil
+		}
+		return
+	}
+	h := &histEntry{seen: time.Now()}
+	nd.history[k] = h
+
+	nd.learn(pkt)
+	nd.radio.deliver(&meshproto.FromRadio{Packet: pkt})
+
+	toMe := pkt.To == nd.cfg.Num
+	if toMe && pkt.WantAck {
+		nd.sendAck(pkt)
+	}
+	if toMe || pkt.HopLimit == 0 {
+		return
+	}
+
+	// Managed flooding: weaker receptions wait less, so the node that
+	// extends the range furthest tends to relay first.
+	relay := *pkt
+	relay.HopLimit--
+	relay.RxSNR, relay.RxRSSI = 0, 0
+	snrWeight := math.Max(0, math.Min(1, (float64(pkt.RxSNR)-nd.net.cfg.MinSNR)/30))
+	delay := time.Duration(snrWeight*float64(nd.net.cfg.RebroadcastDelay)) +
+		nd.net.jitter(nd.net.cfg.RebroadcastDelay/4)
+	h.relay = time.AfterFunc(delay, func() {
+		nd.net.mu.Lock()
+		defer nd.net.mu.Unlock()
+		if h.relay == nil {
+			return
+		}
+		h.relay = nil
+		nd.net.transmit(nd, &relay)
+	})
+}
+
+// learn updates the node DB from a received packet.
+func (nd *Node) learn(pkt *meshproto.MeshPacket) {
+	info, ok := nd.nodeDB[pkt.From]
+	if !ok {
+		info = &meshproto.NodeInfo{NodeID: pkt.From}
+		nd.nodeDB[pkt.From] = info
+	}
+	info.LastHeard = uint32(time.Now().Unix())
+	info.SNR = pkt.RxSNR
+	if hops, ok := pkt.HopsAway(); ok {
+		info.HopsAway = hops
+	}
+
+	switch pkt.PortNum {
+	case meshproto.PortNodeInfo:
+		if u, err := nd.net.proto.DecodeNodeInfo(pkt.Payload); err == nil {
+			info.UserID, info.LongName, info.ShortName = u.UserID, u.LongName, u.ShortName
+			info.HardwareModel, info.Role = u.HardwareModel, u.Role
+		}
+	case meshproto.PortPosition:
+		if p, err := nd.net.proto.DecodePosition(pkt.Payload); err == nil {
+			info.Position = p
+		}
+	case meshproto.PortTelemetry:
+		if t, err := nd.net.proto.DecodeTelemetry(pkt.Payload); err == nil && t.Device != nil {
+			info.DeviceMetrics = t.Device
+		}
+	}
+}
+
+// sendAck answers a want_ack packet addressed to us.
+func (nd *Node) sendAck(pkt *meshproto.MeshPacket) {
+	payload, err := nd.net.proto.EncodeRouting(&meshproto.Routing{ErrorReason: meshproto.RoutingNone})
+	if err != nil {
+		nd.net.log.Warn("sim: encode ack", zap.Error(err))
+		return
+	}
+	nd.send(&meshproto.MeshPacket{
+		To:        pkt.From,
+		Channel:   pkt.Channel,
+		PortNum:   meshproto.PortRouting,
+		Payload:   payload,
+		RequestID: pkt.ID,
+	})
+}
+
+// deliverAck tells our own phone API that packet id was acknowledged.
+func (nd *Node) deliverAck(id uint32, reason meshproto.RoutingError) {
+	payload, err := nd.net.proto.EncodeRouting(&meshproto.Routing{ErrorReason: reason})
+	if err != nil {
+		return
+	}
+	nd.radio.deliver(&meshproto.FromRadio{Packet: &meshproto.MeshPacket{
+		ID:        nd.net.newPacketID(),
+		From:      nd.cfg.Num,
+		To:        nd.cfg.Num,
+		PortNum:   meshproto.PortRouting,
+		Payload:   payload,
+		RequestID: id,
+		RxTime:    uint32(time.Now().Unix()),
+	}})
+}
+
+func (nd *Node) expireHistory() {
+	now := time.Now()
+	for k, h := range nd.history {
+		if now.Sub(h.seen) > historyTTL && h.relay == nil {
+			delete(nd.history, k)
+		}
+	}
+}
+
+// ── Channel utilisation ───────────────────────────────────────────────────
+
+func (nd *Node) addAirtime(at time.Time, dur time.Duration, tx bool) {
+	nd.airtime = append(nd.airtime, airtimeEvent{at: at, dur: dur, tx: tx})
+}
+
+// utilisation returns the percentage of the last minute the channel was
+// busy, and of the last hour this node spent transmitting, as reported in
+// DeviceMetrics.channel_utilization and air_util_tx.
+func (nd *Node) utilisation(now time.Time) (channel, txAir float32) {
+	var busy, tx time.Duration
+	keep := nd.airtime[:0]
+	for _, e := range nd.airtime {
+		age := now.Sub(e.at)
+		if age > airtimeWindow {
+			continue
+		}
+		keep = append(keep, e)
+		if age <= utilWindow {
+			busy += e.dur
+		}
+		if e.tx {
+			tx += e.dur
+		}
+	}
+	nd.airtime = keep
+	channel = float32(100 * busy.Seconds() / utilWindow.Seconds())
+	txAir = float32(100 * tx.Seconds() / airtimeWindow.Seconds())
+	return min(channel, 100), txAir
+}
+
+// ── Traffic ───────────────────────────────────────────────────────────────
+
+// trafficLoop broadcasts this node's periodic packets until ctx is done.
+// Each node starts at a random offset so the mesh does not beat in step.
+func (nd *Node) trafficLoop(ctx context.Context) {
+	defer nd.net.wg.Done()
+
+	type gen struct {
+		every time.Duration
+		next  time.Time
+		send  func()
+	}
+	cfg := nd.net.cfg
+	gens := []*gen{
+		{every: cfg.NodeInfoInterval, send: nd.sendNodeInfo},
+		{every: cfg.PositionInterval, send: nd.sendPosition},
+		{every: cfg.TelemetryInterval, send: nd.sendTelemetry},
+		{every: cfg.TextInterval, send: nd.sendChatter},
+	}
+	nd.net.mu.Lock()
+	now := time.Now()
+	for _, g := range gens {
+		g.next = now.Add(nd.net.jitter(g.every))
+	}
+	nd.net.mu.Unlock()
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			for _, g := range gens {
+				if g.every <= 0 || now.Before(g.next) {
+					continue
+				}
+				nd.net.mu.Lock()
+				g.send()
+				g.next = now.Add(g.every/2 + nd.net.jitter(g.every))
+				nd.net.mu.Unlock()
+			}
+		}
+	}
+}
+
+// The senders below run with net.mu held.
+
+func (nd *Node) sendNodeInfo() {
+	payload, err := nd.net.proto.EncodeNodeInfo(nd.user())
+	if err != nil {
+		return
+	}
+	nd.broadcast(meshproto.PortNodeInfo, payload)
+}
+
+func (nd *Node) sendPosition() {
+	if nd.cfg.Mobile {
+		// A slow random walk of up to ~50 m per report.
+		nd.lat += (nd.net.rng.Float64() - 0.5) * 0.0009
+		nd.lon += (nd.net.rng.Float64() - 0.5) * 0.0009
+	}
+	payload, err := nd.net.proto.EncodePosition(nd.position())
+	if err != nil {
+		return
+	}
+	nd.broadcast(meshproto.PortPosition, payload)
+}
+
+func (nd *Node) sendTelemetry() {
+	if nd.battery > 0 && nd.battery <= 100 && nd.net.rng.IntN(4) == 0 {
+		nd.battery--
+	}
+	payload, err := nd.net.proto.EncodeTelemetry(&meshproto.Telemetry{
+		Time:   uint32(time.Now().Unix()),
+		Device: nd.deviceMetrics(time.Now()),
+	})
+	if err != nil {
+		return
+	}
+	nd.broadcast(meshproto.PortTelemetry, payload)
+}
+
+var chatter = []string{
+	"hello mesh", "anyone on?", "testing 1 2 3", "signal check", "qsl", "73",
+}
+
+func (nd *Node) sendChatter() {
+	nd.broadcast(meshproto.PortTextMessage, []byte(chatter[nd.net.rng.IntN(len(chatter))]))
+}
+
+func (nd *Node) broadcast(port meshproto.PortNum, payload []byte) {
+	nd.send(&meshproto.MeshPacket{
+		To:      meshproto.BroadcastAddr,
+		PortNum: port,
+		Payload: payload,
+	})
+}
+
+func (nd *Node) user() *meshproto.NodeInfo {
+	return &meshproto.NodeInfo{
+		NodeID:        nd.cfg.Num,
+		UserID:        nodeHex(nd.cfg.Num),
+		LongName:      nd.cfg.LongName,
+		ShortName:     nd.cfg.ShortName,
+		HardwareModel: nd.cfg.Hardware,
+		Role:          nd.cfg.Role,
+	}
+}
+
+func (nd *Node) position() *meshproto.Position {
+	return &meshproto.Position{
+		LatitudeI:     int32(math.Round(nd.lat * 1e7)),
+		LongitudeI:    int32(math.Round(nd.lon * 1e7)),
+		Altitude:      nd.cfg.Alt,
+		Time:          uint32(time.Now().Unix()),
+		PrecisionBits: 32,
+	}
+}
+
+func (nd *Node) deviceMetrics(now time.Time) *meshproto.DeviceMetrics {
+	chUtil, airUtil := nd.utilisation(now)
+	voltage := float32(3.3 + 0.9*float64(min(nd.battery, 100))/100)
+	var uptime uint32
+	if !nd.net.started.IsZero() {
+		uptime = uint32(now.Sub(nd.net.started).Seconds())
+	}
+	return &meshproto.DeviceMetrics{
+		BatteryLevel:  nd.battery,
+		Voltage:       voltage,
+		ChannelUtil:   chUtil,
+		AirUtil:       airUtil,
+		UptimeSeconds: uptime,
+	}
+}
+
+func nodeHex(n uint32) string {
+	return fmt.Sprintf("!%08x", n)
+}
@@ -0,0 +1,134 @@
+package sim
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	meshproto "github.com/gg-glitch-88/meshigo-kore/ydin/proto"
+	"github.com/gg-glitch-88/meshigo-kore/ydin/transport"
+)
+
+const radioFrameChanSize = 256
+
+// Radio is a virtual node's phone API. It implements
+// transport.TransportManager, so a GatewayService can be attached to any
+// node of the simulated mesh exactly as to a real device.
+type Radio struct {
+	node   *Node
+	frames chan transport.ProtoFrame
+	state  atomic.Int32 // transport.ConnectionState
+}
+
+var _ transport.TransportManager = (*Radio)(nil)
+
+func newRadio(nd *Node) *Radio {
+	r := &Radio{node: nd, frames: make(chan transport.ProtoFrame, radioFrameChanSize)}
+	r.state.Store(int32(transport.StateDisconnected))
+	return r
+}
+
+func (r *Radio) Connect() error {
+	r.state.Store(int32(transport.StateConnected))
+	return nil
+}
+
+func (r *Radio) Disconnect() error {
+	r.state.Store(int32(transport.StateDisconnected))
+	return nil
+}
+
+// Send hands a ToRadio to the node: packets are transmitted, want_config
+// is answered with the node's identity, node DB and channel settings.
+func (r *Radio) Send(frame transport.ProtoFrame) error {
+	if r.GetConnectionState() != transport.StateConnected {
+		return fmt.Errorf("sim: radio %s not connected", nodeHex(r.node.cfg.Num))
+	}
+	msg, err := r.node.net.proto.DecodeToRadio(frame.Data)
+	if err != nil {
+		return fmt.Errorf("sim: %w", err)
+	}
+
+	nd := r.node
+	nd.net.mu.Lock()
+	defer nd.net.mu.Unlock()
+	switch {
+	case msg.WantConfigID != 0:
+		nd.answerConfig(msg.WantConfigID)
+	case msg.Packet != nil:
+		nd.send(msg.Packet)
+	}
+	return nil
+}
+
+func (r *Radio) Receive() <-chan transport.ProtoFrame { return r.frames }
+
+func (r *Radio) GetConnectionState() transport.ConnectionState {
+	return transport.ConnectionState(r.state.Load())
+}
+
+// deliver queues a FromRadio for the attached client. Like a real device
+// with no phone connected, a disconnected radio discards it.
+func (r *Radio) deliver(fr *meshproto.FromRadio) {
+	if r.GetConnectionState() != transport.StateConnected {
+		return
+	}
+	data, err := r.node.net.proto.EncodeFromRadio(fr)
+	if err != nil {
+		r.node.net.log.Warn("sim: encode FromRadio", zap.Error(err))
+		return
+	}
+	select {
+	case r.frames <- transport.ProtoFrame{Data: data, Timestamp: time.Now().UTC()}:
+	default:
+		r.node.net.log.Warn("sim: radio frame channel full – dropping frame",
+			zap.String("node", nodeHex(r.node.cfg.Num)))
+	}
+}
+
+// answerConfig streams the want_config response in firmware order.
+// net.mu must be held.
+func (nd *Node) answerConfig(id uint32) {
+	r := nd.radio
+	r.deliver(&meshproto.FromRadio{MyInfo: &meshproto.MyNodeInfo{
+		MyNodeNum: nd.cfg.Num,
+		PioEnv:    "sim",
+	}})
+	r.deliver(&meshproto.FromRadio{Metadata: &meshproto.DeviceMetadata{
+		FirmwareVersion: "2.5.0.sim",
+		HardwareModel:   nd.cfg.Hardware,
+		Role:            nd.cfg.Role,
+		HasBluetooth:    true,
+	}})
+
+	self := nd.user()
+	self.Position = nd.position()
+	self.DeviceMetrics = nd.deviceMetrics(time.Now())
+	self.LastHeard = uint32(time.Now().Unix())
+	r.deliver(&meshproto.FromRadio{NodeInfo: self})
+	for _, num := range nd.net.order {
+		if info, ok := nd.nodeDB[num]; ok {
+			r.deliver(&meshproto.FromRadio{NodeInfo: info})
+		}
+	}
+
+	r.deliver(&meshproto.FromRadio{Channel: &meshproto.Channel{
+		Index:    0,
+		Role:     meshproto.ChannelPrimary,
+		Settings: &meshproto.ChannelSettings{PSK: []byte{1}},
+	}})
+	for i := int32(1); i < 8; i++ {
+		r.deliver(&meshproto.FromRadio{Channel: &meshproto.Channel{Index: i, Role: meshproto.ChannelDisabled}})
+	}
+
+	r.deliver(&meshproto.FromRadio{Config: &meshproto.Config{Device: &meshproto.DeviceConfig{Role: nd.cfg.Role}}})
+	r.deliver(&meshproto.FromRadio{Config: &meshproto.Config{LoRa: &meshproto.LoRaConfig{
+		UsePreset: true,
+		HopLimit:  nd.cfg.HopLimit,
+		TxEnabled: true,
+		TxPower:   int32(nd.cfg.TxPower),
+	}}})
+	r.deliver(&meshproto.FromRadio{ConfigCompleteID: id})
+}
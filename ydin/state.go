@@ -7,33 +7,53 @@ import (
 	"sync"
 	"time"
 
+	meshproto "github.com/gg-glitch-88/meshigo-kore/ydin/proto"
 	"github.com/gg-glitch-88/meshigo-kore/ydin/store"
 )
 
 // Node is a known mesh participant.
 type Node struct {
-	NodeID    uint32
-	NodeIDHex string // e.g. "!deadbeef"
-	LongName  string
-	ShortName string
-	Hardware  string
-	Role      string
-	LastSeen  time.Time
+	NodeID          uint32
+	NodeIDHex       string // e.g. "!deadbeef"
+	LongName        string
+	ShortName       string
+	Hardware        string
+	Role            string
+	FirmwareVersion string // known only for the attached device
+	PublicKey       []byte // Curve25519 key for PKI direct messages
+	IsLicensed      bool
+	LastSeen        time.Time
+	// Last packet heard
+	SNR      float32 // dB
+	HopsAway int     // -1 when unknown
+	ViaMQTT  bool
 	// Latest telemetry
 	BatteryLevel uint32
 	Voltage      float32
+	ChannelUtil  float32 // percent
 	// Latest position
 	Lat float64
 	Lon float64
 	Alt int32
 }
 
+// NewNode returns an empty record for nodeID.
+func NewNode(nodeID uint32) *Node {
+	return &Node{
+		NodeID:    nodeID,
+		NodeIDHex: fmt.Sprintf("!%08x", nodeID),
+		HopsAway:  -1,
+	}
+}
+
 // Manager holds all runtime state: known nodes + recent messages.
 // All exported methods are safe for concurrent use.
 type Manager struct {
-	db    *store.DB
-	mu    sync.RWMutex
-	nodes map[uint32]*Node // keyed by numeric node ID
+	db       *store.DB
+	mu       sync.RWMutex
+	nodes    map[uint32]*Node // keyed by numeric node ID
+	device   DeviceInfo
+	channels []meshproto.Channel
 }
 
 // New creates a Manager and hydrates the node cache from the database.
@@ -51,37 +71,38 @@ func New(db *store.DB) (*Manager, error) {
 // ── Node state ────────────────────────────────────────────────────────────
 
 // UpsertNode creates or refreshes a node in both memory and the database.
+// The cache keeps a copy of n, so the caller may go on using it. A zero
+// LastSeen is set to now; node DB entries replayed by the device
+// keep their own last-heard time.
 func (m *Manager) UpsertNode(n *Node) error {
 	if n.NodeID == 0 {
 		return fmt.Errorf("state: node ID must not be zero")
 	}
-	n.LastSeen = time.Now().UTC()
+	if n.LastSeen.IsZero() {
+		n.LastSeen = time.Now().UTC()
+	}
 	if n.NodeIDHex == "" {
 		n.NodeIDHex = fmt.Sprintf("!%08x", n.NodeID)
 	}
 
+	c := *n
 	m.mu.Lock()
-	m.nodes[n.NodeID] = n
+	m.nodes[n.NodeID] = &c
 	m.mu.Unlock()
 
-	// Persist to SQLite
-	_, err := m.db.Exec(`
-		INSERT INTO peers (node_id, display_name, last_seen, transport)
-		VALUES (?, ?, ?, 'mesh')
-		ON CONFLICT(node_id) DO UPDATE
-		  SET display_name = excluded.display_name,
-		      last_seen    = excluded.last_seen`,
-		n.NodeIDHex, n.LongName, n.LastSeen.Unix(),
-	)
-	return err
+	return m.saveNode(n)
 }
 
-// GetNode retrieves a node by numeric ID.
+// GetNode retrieves a snapshot of a node by numeric ID.
 func (m *Manager) GetNode(nodeID uint32) (*Node, bool) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 	n, ok := m.nodes[nodeID]
-	return n, ok
+	if !ok {
+		return nil, false
+	}
+	copy := *n
+	return &copy, true
 }
 
 // ListNodes returns a snapshot of all known nodes.
@@ -103,27 +124,120 @@ func (m *Manager) NodeCount() int {
 	return len(m.nodes)
 }
 
-// UpdateTelemetry updates battery and signal data for a node.
-func (m *Manager) UpdateTelemetry(nodeID uint32, battery uint32, voltage, chanUtil float32) {
+// Heard notes a packet from nodeID: when it arrived, how well and how far
+// it travelled. Unknown nodes are ignored; hopsAway < 0 keeps the previous
+// value.
+func (m *Manager) Heard(nodeID uint32, at time.Time, snr float32, hopsAway int, viaMQTT bool) error {
 	m.mu.Lock()
-	defer m.mu.Unlock()
-	if n, ok := m.nodes[nodeID]; ok {
-		n.BatteryLevel = battery
-		n.Voltage = voltage
-		n.LastSeen = time.Now().UTC()
+	n, ok := m.nodes[nodeID]
+	if !ok {
+		m.mu.Unlock()
+		return nil
+	}
+	if at.After(n.LastSeen) {
+		n.LastSeen = at
+	}
+	n.SNR = snr
+	if hopsAway >= 0 {
+		n.HopsAway = hopsAway
+	}
+	n.ViaMQTT = viaMQTT
+	snapshot := *n
+	m.mu.Unlock()
+
+	_, err := m.db.Exec(
+		`UPDATE nodes SET last_seen = ?, snr = ?, hops_away = ?, via_mqtt = ? WHERE node_id = ?`,
+		snapshot.LastSeen.UnixMilli(), snapshot.SNR, snapshot.HopsAway, snapshot.ViaMQTT, snapshot.NodeIDHex)
+	if err != nil {
+		return fmt.Errorf("state: node heard: %w", err)
 	}
+	return nil
 }
 
-// UpdatePosition updates GPS coordinates for a node.
-func (m *Manager) UpdatePosition(nodeID uint32, lat, lon float64, alt int32) {
+// UpdateTelemetry records a telemetry report taken at ts: device metrics
+// refresh the node in memory (it counts as seen at ts), and every reported
+// reading is appended to the time-series store.
+func (m *Manager) UpdateTelemetry(nodeID uint32, ts time.Time, t *meshproto.Telemetry) error {
+	if d := t.Device; d != nil {
+		m.mu.Lock()
+		n, ok := m.nodes[nodeID]
+		var snapshot Node
+		if ok {
+			n.BatteryLevel = d.BatteryLevel
+			n.Voltage = d.Voltage
+			n.ChannelUtil = d.ChannelUtil
+			if ts.After(n.LastSeen) {
+				n.LastSeen = ts
+			}
+			snapshot = *n
+		}
+		m.mu.Unlock()
+		if ok {
+			if err := m.saveNode(&snapshot); err != nil {
+				return err
+			}
+		}
+	}
+	return m.db.InsertTelemetry(telemetrySamples(fmt.Sprintf("!%08x", nodeID), ts, t))
+}
+
+// TelemetrySeries returns one metric of nodeID over [from, to), aggregated
+// per step (0 = raw samples), keeping the newest limit points (0 = all).
+func (m *Manager) TelemetrySeries(nodeID uint32, metric string, from, to time.Time, step time.Duration, limit int) ([]store.TelemetryPoint, error) {
+	return m.db.QueryTelemetry(fmt.Sprintf("!%08x", nodeID), metric, from, to, step, limit)
+}
+
+// PruneTelemetry applies keep to the time-series store.
+func (m *Manager) PruneTelemetry(keep store.TelemetryRetention) (int64, error) {
+	return m.db.PruneTelemetry(time.Now(), keep)
+}
+
+// UpdatePosition records a position report: it moves the node in memory
+// and appends the report to its track. p.NodeID is filled in if empty.
+// The node counts as seen at p.ReceivedAt, so a replayed capture keeps its
+// recorded times.
+func (m *Manager) UpdatePosition(nodeID uint32, p *store.Position) error {
+	if p.NodeID == "" {
+		p.NodeID = fmt.Sprintf("!%08x", nodeID)
+	}
+	if p.ReceivedAt.IsZero() {
+		p.ReceivedAt = time.Now().UTC()
+	}
+	if p.Time.IsZero() {
+		p.Time = p.ReceivedAt
+	}
+
 	m.mu.Lock()
-	defer m.mu.Unlock()
-	if n, ok := m.nodes[nodeID]; ok {
-		n.Lat = lat
-		n.Lon = lon
-		n.Alt = alt
-		n.LastSeen = time.Now().UTC()
+	n, ok := m.nodes[nodeID]
+	var snapshot Node
+	if ok {
+		n.Lat = p.Lat
+		n.Lon = p.Lon
+		n.Alt = p.Alt
+		if p.ReceivedAt.After(n.LastSeen) {
+			n.LastSeen = p.ReceivedAt
+		}
+		snapshot = *n
+	}
+	m.mu.Unlock()
+	if ok {
+		if err := m.saveNode(&snapshot); err != nil {
+			return err
+		}
 	}
+
+	id, err := m.db.InsertPosition(p)
+	if err != nil {
+		return err
+	}
+	p.ID = id
+	return nil
+}
+
+// Track returns the positions nodeID reported with from <= time < to,
+// oldest first, thinned to at most maxPoints (see store.DB.ListPositions).
+func (m *Manager) Track(nodeID uint32, from, to time.Time, maxPoints int) ([]*store.Position, error) {
+	return m.db.ListPositions(fmt.Sprintf("!%08x", nodeID), from, to, maxPoints)
 }
 
 // ── Message state ─────────────────────────────────────────────────────────
@@ -133,16 +247,70 @@ func (m *Manager) RecordMessage(msg *store.Message) (int64, error) {
 	return m.db.InsertMessage(msg)
 }
 
+// SetMessageStatus persists the delivery state of an outbound message.
+func (m *Manager) SetMessageStatus(id int64, status, reason string) error {
+	return m.db.SetMessageStatus(id, status, reason)
+}
+
+// PendingMessages returns the outbound messages still queued or sent, with
+// no outcome yet, oldest first.
+func (m *Manager) PendingMessages() ([]*store.Message, error) {
+	return m.db.PendingMessages()
+}
+
+// SetMessageReception persists the reception summary of an inbound message.
+func (m *Manager) SetMessageReception(id int64, rx store.Reception) error {
+	return m.db.SetMessageReception(id, rx)
+}
+
 // RecentMessages returns the n most recent messages.
 func (m *Manager) RecentMessages(n int) ([]*store.Message, error) {
-	return m.db.ListMessages(n)
+	msgs, _, err := m.db.ListMessages(store.MessageQuery{Limit: n})
+	return msgs, err
 }
 
 // ── internal ──────────────────────────────────────────────────────────────
 
+// nodeColumns is the column list shared by saveNode and loadNodes.
+const nodeColumns = `node_id, long_name, short_name, hardware, role, firmware_version,
+	public_key, is_licensed, last_seen, snr, hops_away, via_mqtt,
+	battery_level, voltage, channel_util, lat, lon, alt`
+
+// saveNode writes every field of n to the nodes table.
+func (m *Manager) saveNode(n *Node) error {
+	_, err := m.db.Exec(`
+		INSERT INTO nodes (`+nodeColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(node_id) DO UPDATE SET
+		  long_name        = excluded.long_name,
+		  short_name       = excluded.short_name,
+		  hardware         = excluded.hardware,
+		  role             = excluded.role,
+		  firmware_version = excluded.firmware_version,
+		  public_key       = excluded.public_key,
+		  is_licensed      = excluded.is_licensed,
+		  last_seen        = excluded.last_seen,
+		  snr              = excluded.snr,
+		  hops_away        = excluded.hops_away,
+		  via_mqtt         = excluded.via_mqtt,
+		  battery_level    = excluded.battery_level,
+		  voltage          = excluded.voltage,
+		  channel_util     = excluded.channel_util,
+		  lat              = excluded.lat,
+		  lon              = excluded.lon,
+		  alt              = excluded.alt`,
+		n.NodeIDHex, n.LongName, n.ShortName, n.Hardware, n.Role, n.FirmwareVersion,
+		n.PublicKey, n.IsLicensed, n.LastSeen.UnixMilli(), n.SNR, n.HopsAway, n.ViaMQTT,
+		n.BatteryLevel, n.Voltage, n.ChannelUtil, n.Lat, n.Lon, n.Alt,
+	)
+	if err != nil {
+		return fmt.Errorf("state: save node %s: %w", n.NodeIDHex, err)
+	}
+	return nil
+}
+
 func (m *Manager) loadNodes() error {
-	rows, err := m.db.Query(
-		`SELECT node_id, display_name, last_seen FROM peers`)
+	rows, err := m.db.Query(`SELECT ` + nodeColumns + ` FROM nodes`)
 	if err != nil {
 		return err
 	}
@@ -150,21 +318,64 @@ func (m *Manager) loadNodes() error {
 
 	for rows.Next() {
 		var (
-			nodeIDHex   string
-			displayName string
-			lastSeenTS  int64
+			n          Node
+			lastSeenMS int64
 		)
-		if err := rows.Scan(&nodeIDHex, &displayName, &lastSeenTS); err != nil {
+		err := rows.Scan(&n.NodeIDHex, &n.LongName, &n.ShortName, &n.Hardware, &n.Role,
+			&n.FirmwareVersion, &n.PublicKey, &n.IsLicensed, &lastSeenMS, &n.SNR,
+			&n.HopsAway, &n.ViaMQTT, &n.BatteryLevel, &n.Voltage, &n.ChannelUtil,
+			&n.Lat, &n.Lon, &n.Alt)
+		if err != nil {
 			return err
 		}
-		var nodeNum uint32
-		fmt.Sscanf(nodeIDHex, "!%x", &nodeNum) //nolint:errcheck
-		m.nodes[nodeNum] = &Node{
-			NodeID:    nodeNum,
-			NodeIDHex: nodeIDHex,
-			LongName:  displayName,
-			LastSeen:  time.Unix(lastSeenTS, 0),
+		if _, err := fmt.Sscanf(n.NodeIDHex, "!%x", &n.NodeID); err != nil {
+			return fmt.Errorf("node %q: %w", n.NodeIDHex, err)
 		}
+		n.LastSeen = time.UnixMilli(lastSeenMS).UTC()
+		m.nodes[n.NodeID] = &n
 	}
 	return rows.Err()
 }
+
+// telemetrySamples flattens a telemetry report into one sample per reading.
+// Environment fields the node did not report are skipped.
+func telemetrySamples(nodeID string, ts time.Time, t *meshproto.Telemetry) []store.TelemetrySample {
+	var out []store.TelemetrySample
+	add := func(metric string, v float64) {
+		out = append(out, store.TelemetrySample{NodeID: nodeID, Metric: metric, Time: ts, Value: v})
+	}
+	addOpt := func(metric string, v *float32) {
+		if v != nil {
+			add(metric, float64(*v))
+		}
+	}
+
+	if d := t.Device; d != nil {
+		add(store.MetricBatteryLevel, float64(d.BatteryLevel))
+		add(store.MetricVoltage, float64(d.Voltage))
+		add(store.MetricChannelUtil, float64(d.ChannelUtil))
+		add(store.MetricAirUtilTx, float64(d.AirUtil))
+		add(store.MetricUptime, float64(d.UptimeSeconds))
+	}
+	if e := t.Environment; e != nil {
+		addOpt(store.MetricTemperature, e.Temperature)
+		addOpt(store.MetricRelativeHumidity, e.RelativeHumidity)
+		addOpt(store.MetricBarometricPressure, e.BarometricPressure)
+		addOpt(store.MetricGasResistance, e.GasResistance)
+		addOpt(store.MetricEnvVoltage, e.Voltage)
+		addOpt(store.MetricEnvCurrent, e.Current)
+		addOpt(store.MetricLux, e.Lux)
+		if e.IAQ != nil {
+			add(store.MetricIAQ, float64(*e.IAQ))
+		}
+	}
+	if p := t.Power; p != nil {
+		add(store.MetricCh1Voltage, float64(p.Ch1Voltage))
+		add(store.MetricCh1Current, float64(p.Ch1Current))
+		add(store.MetricCh2Voltage, float64(p.Ch2Voltage))
+		add(store.MetricCh2Current, float64(p.Ch2Current))
+		add(store.MetricCh3Voltage, float64(p.Ch3Voltage))
+		add(store.MetricCh3Current, float64(p.Ch3Current))
+	}
+	return out
+}
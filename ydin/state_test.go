@@ -0,0 +1,102 @@
+package state
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gg-glitch-88/meshigo-kore/ydin/store"
+)
+
+// openManager opens a Manager over the migrated database at path.
+func openManager(t *testing.T, path string) *Manager {
+	t.Helper()
+	db, err := store.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := store.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	m, err := New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestUpsertNodeKeepsCopy(t *testing.T) {
+	m := openManager(t, filepath.Join(t.TempDir(), "test.db"))
+
+	n := NewNode(0xdeadbeef)
+	n.LongName = "Pocket"
+	if err := m.UpsertNode(n); err != nil {
+		t.Fatal(err)
+	}
+
+	// The caller goes on with its record, as handleNodeInfo does when it
+	// publishes it, while others read the cache; run with -race.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		n.LongName = "Renamed"
+		n.SNR = 9.5
+	}()
+	for range 100 {
+		m.GetNode(n.NodeID)
+		m.ListNodes()
+	}
+	<-done
+
+	got, _ := m.GetNode(n.NodeID)
+	if got.LongName != "Pocket" || got.SNR != 0 {
+		t.Errorf("cached node changed with the caller's record: %+v", got)
+	}
+}
+
+func TestNodePersistRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	m := openManager(t, path)
+
+	full := &Node{
+		NodeID:          0x1234abcd,
+		NodeIDHex:       "!1234abcd",
+		LongName:        "Hilltop Relay",
+		ShortName:       "HTR",
+		Hardware:        "HELTEC_V3",
+		Role:            "ROUTER",
+		FirmwareVersion: "2.5.6.d55c08d",
+		PublicKey:       []byte{0x01, 0x02, 0x03, 0x04, 0xfe, 0xff},
+		IsLicensed:      true,
+		LastSeen:        time.Date(2026, 3, 1, 12, 30, 45, 123e6, time.UTC),
+		SNR:             -7.25,
+		HopsAway:        -1, // unknown
+		ViaMQTT:         true,
+		BatteryLevel:    101,
+		Voltage:         4.15,
+		ChannelUtil:     12.5,
+		Lat:             60.1699,
+		Lon:             24.9384,
+		Alt:             -12,
+	}
+	// A direct neighbour known only from its packets: no key, zero hops.
+	sparse := NewNode(0xdeadbeef)
+	sparse.HopsAway = 0
+	sparse.LastSeen = full.LastSeen
+
+	for _, n := range []*Node{full, sparse} {
+		want := *n
+		if err := m.UpsertNode(n); err != nil {
+			t.Fatal(err)
+		}
+		got, ok := openManager(t, path).GetNode(n.NodeID)
+		if !ok {
+			t.Fatalf("node %s not reloaded", want.NodeIDHex)
+		}
+		if !reflect.DeepEqual(*got, want) {
+			t.Errorf("reloaded node:\n got %+v\nwant %+v", *got, want)
+		}
+	}
+}
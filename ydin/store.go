@@ -14,6 +14,7 @@ type DB struct {
 }
 
 // Open opens (or creates) the SQLite file at path with WAL journal mode.
+// It refuses a database whose schema is newer than this build.
 func Open(path string) (*DB, error) {
 	dsn := fmt.Sprintf("file:%s?_journal_mode=WAL&_foreign_keys=ON&_busy_timeout=5000", path)
 	raw, err := sql.Open("sqlite3", dsn)
@@ -21,33 +22,143 @@ func Open(path string) (*DB, error) {
 		return nil, fmt.Errorf("store: open %s: %w", path, err)
 	}
 	if err := raw.Ping(); err != nil {
+		raw.Close()
 		return nil, fmt.Errorf("store: ping: %w", err)
 	}
 	// Limit writer concurrency to 1; SQLite WAL allows concurrent readers.
 	raw.SetMaxOpenConns(1)
-	return &DB{raw}, nil
-}
 
-// Migrate applies the embedded DDL schema to the database.
-// It is idempotent (IF NOT EXISTS everywhere).
-func Migrate(db *DB) error {
-	ddl := []string{
-		ddlMessages,
-		ddlFiles,
-		ddlPeers,
-		ddlWikiPages,
+	db := &DB{raw}
+	version, err := SchemaVersion(db)
+	if err != nil {
+		raw.Close()
+		return nil, err
 	}
-	for _, stmt := range ddl {
-		if _, err := db.Exec(stmt); err != nil {
-			return fmt.Errorf("store: migrate: %w", err)
-		}
+	if version > LatestVersion() {
+		raw.Close()
+		return nil, fmt.Errorf("store: open %s: %w (database %d, build %d)",
+			path, ErrSchemaTooNew, version, LatestVersion())
 	}
-	return nil
+	return db, nil
+}
+
+// ── Schema history ────────────────────────────────────────────────────────
+//
+// Append only. A migration that has shipped is never edited; change the
+// schema by adding the next version.
+
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "initial schema",
+		Up:      ddlInitial,
+		Down: `
+DROP TABLE wiki_pages;
+DROP TABLE peers;
+DROP TABLE files;
+DROP TABLE messages;
+`,
+	},
+	{
+		Version: 2,
+		Name:    "message direction and status",
+		Up: `
+ALTER TABLE messages ADD COLUMN direction TEXT NOT NULL DEFAULT 'in';       -- 'in' | 'out'
+ALTER TABLE messages ADD COLUMN status    TEXT NOT NULL DEFAULT 'received'; -- see MessageStatus* constants
+`,
+		Down: `
+ALTER TABLE messages DROP COLUMN status;
+ALTER TABLE messages DROP COLUMN direction;
+`,
+	},
+	{
+		Version: 3,
+		Name:    "message delivery errors",
+		Up:      `ALTER TABLE messages ADD COLUMN error_reason TEXT NOT NULL DEFAULT ''; -- why delivery failed, if it did`,
+		Down:    `ALTER TABLE messages DROP COLUMN error_reason;`,
+	},
+	{
+		Version: 4,
+		Name:    "message reception summary",
+		Up: `
+ALTER TABLE messages ADD COLUMN copies    INTEGER NOT NULL DEFAULT 1;  -- copies heard (rebroadcasts, transports)
+ALTER TABLE messages ADD COLUMN rx_snr    REAL    NOT NULL DEFAULT 0;  -- best SNR across copies, dB
+ALTER TABLE messages ADD COLUMN rx_rssi   INTEGER NOT NULL DEFAULT 0;  -- best RSSI across copies, dBm (0 = unknown)
+ALTER TABLE messages ADD COLUMN hops_away INTEGER NOT NULL DEFAULT -1; -- fewest hops across copies (-1 = unknown)
+`,
+		Down: `
+ALTER TABLE messages DROP COLUMN hops_away;
+ALTER TABLE messages DROP COLUMN rx_rssi;
+ALTER TABLE messages DROP COLUMN rx_snr;
+ALTER TABLE messages DROP COLUMN copies;
+`,
+	},
+	{
+		Version: 5,
+		Name:    "position history",
+		Up:      ddlPositions,
+		Down:    `DROP TABLE positions;`,
+	},
+	{
+		Version: 6,
+		Name:    "telemetry time series",
+		Up:      ddlTelemetry,
+		Down: `
+DROP TABLE telemetry_1h;
+DROP TABLE telemetry_1m;
+DROP TABLE telemetry;
+`,
+	},
+	{
+		Version: 7,
+		Name:    "nodes table",
+		Up:      migrateNodes,
+		Down: `
+INSERT INTO peers (node_id, display_name, last_seen, transport)
+    SELECT node_id, long_name, last_seen / 1000, 'mesh' FROM nodes WHERE true
+    ON CONFLICT(node_id) DO NOTHING;
+DROP TABLE nodes;
+`,
+	},
+	{
+		// A sender reuses a packet ID only for copies of the same packet.
+		// The index is what enforces that, and what InsertMessage's
+		// ON CONFLICT (from_node, mesh_id) relies on; duplicates stored
+		// before it existed are dropped first, keeping the oldest row.
+		Version: 8,
+		Name:    "unique sender packet",
+		Up: `
+DELETE FROM messages WHERE id NOT IN (
+    SELECT MIN(id) FROM messages GROUP BY from_node, mesh_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_messages_sender_packet ON messages (from_node, mesh_id);
+`,
+		Down: `DROP INDEX idx_messages_sender_packet;`,
+	},
+	{
+		Version: 9,
+		Name:    "message port",
+		Up:      `ALTER TABLE messages ADD COLUMN port INTEGER NOT NULL DEFAULT 0; -- Meshtastic PortNum; 0 = unknown, as for every earlier row`,
+		Down:    `ALTER TABLE messages DROP COLUMN port;`,
+	},
+	{
+		Version: 10,
+		Name:    "kore data",
+		Up: `
+CREATE TABLE kore_data (
+    id         TEXT    PRIMARY KEY,
+    data       TEXT    NOT NULL,
+    updated_at INTEGER NOT NULL  -- Unix milliseconds
+);
+`,
+		Down: `DROP TABLE kore_data;`,
+	},
 }
 
 // ── DDL statements ────────────────────────────────────────────────────────
 
-const ddlMessages = `
+// ddlInitial is the schema as first released. IF NOT EXISTS lets it adopt
+// databases created before migrations were versioned.
+const ddlInitial = `
 CREATE TABLE IF NOT EXISTS messages (
     id          INTEGER PRIMARY KEY AUTOINCREMENT,
     mesh_id     TEXT    NOT NULL,          -- Meshtastic packet ID
@@ -59,9 +170,7 @@ CREATE TABLE IF NOT EXISTS messages (
     synced      INTEGER NOT NULL DEFAULT 0 -- bool: 0 = pending, 1 = synced
 );
 CREATE INDEX IF NOT EXISTS idx_messages_received_at ON messages (received_at DESC);
-`
 
-const ddlFiles = `
 CREATE TABLE IF NOT EXISTS files (
     id          INTEGER PRIMARY KEY AUTOINCREMENT,
     info_hash   TEXT    NOT NULL UNIQUE,  -- BitTorrent info-hash (hex)
@@ -71,9 +180,7 @@ CREATE TABLE IF NOT EXISTS files (
     seeding     INTEGER NOT NULL DEFAULT 0
 );
 CREATE INDEX IF NOT EXISTS idx_files_info_hash ON files (info_hash);
-`
 
-const ddlPeers = `
 CREATE TABLE IF NOT EXISTS peers (
     id           INTEGER PRIMARY KEY AUTOINCREMENT,
     node_id      TEXT    NOT NULL UNIQUE,
@@ -81,9 +188,7 @@ CREATE TABLE IF NOT EXISTS peers (
     last_seen    INTEGER NOT NULL,        -- Unix seconds
     transport    TEXT    NOT NULL DEFAULT 'mesh' -- 'mesh' | 'tcp' | 'ble'
 );
-`
 
-const ddlWikiPages = `
 CREATE TABLE IF NOT EXISTS wiki_pages (
     id         INTEGER PRIMARY KEY AUTOINCREMENT,
     slug       TEXT    NOT NULL UNIQUE,
@@ -93,3 +198,96 @@ CREATE TABLE IF NOT EXISTS wiki_pages (
 );
 CREATE INDEX IF NOT EXISTS idx_wiki_pages_slug ON wiki_pages (slug);
 `
+
+const ddlPositions = `
+CREATE TABLE positions (
+    id             INTEGER PRIMARY KEY AUTOINCREMENT,
+    node_id        TEXT    NOT NULL,
+    time           INTEGER NOT NULL,           -- Unix milliseconds of the fix
+    lat            REAL    NOT NULL,
+    lon            REAL    NOT NULL,
+    alt            INTEGER NOT NULL DEFAULT 0, -- metres
+    precision_bits INTEGER NOT NULL DEFAULT 32,
+    pdop           REAL    NOT NULL DEFAULT 0,
+    ground_speed   INTEGER NOT NULL DEFAULT 0, -- m/s
+    heading        REAL    NOT NULL DEFAULT 0, -- degrees
+    sats_in_view   INTEGER NOT NULL DEFAULT 0,
+    packet_id      INTEGER NOT NULL DEFAULT 0, -- source MeshPacket.id
+    received_at    INTEGER NOT NULL            -- Unix milliseconds
+);
+CREATE INDEX idx_positions_node_time ON positions (node_id, time);
+`
+
+const ddlTelemetry = `
+CREATE TABLE telemetry (
+    id      INTEGER PRIMARY KEY AUTOINCREMENT,
+    node_id TEXT    NOT NULL,
+    metric  TEXT    NOT NULL,  -- see Metric* constants
+    time    INTEGER NOT NULL,  -- Unix milliseconds
+    value   REAL    NOT NULL
+);
+CREATE INDEX idx_telemetry_series ON telemetry (node_id, metric, time);
+CREATE INDEX idx_telemetry_time ON telemetry (time);
+
+CREATE TABLE telemetry_1m (
+    node_id TEXT    NOT NULL,
+    metric  TEXT    NOT NULL,
+    bucket  INTEGER NOT NULL,  -- Unix milliseconds, start of the minute
+    count   INTEGER NOT NULL,
+    sum     REAL    NOT NULL,
+    min     REAL    NOT NULL,
+    max     REAL    NOT NULL,
+    PRIMARY KEY (node_id, metric, bucket)
+);
+
+CREATE TABLE telemetry_1h (
+    node_id TEXT    NOT NULL,
+    metric  TEXT    NOT NULL,
+    bucket  INTEGER NOT NULL,  -- Unix milliseconds, start of the hour
+    count   INTEGER NOT NULL,
+    sum     REAL    NOT NULL,
+    min     REAL    NOT NULL,
+    max     REAL    NOT NULL,
+    PRIMARY KEY (node_id, metric, bucket)
+);
+`
+
+// migrateNodes moves mesh nodes out of peers, which is left to replication,
+// into a table holding the full node record.
+const migrateNodes = `
+CREATE TABLE nodes (
+    node_id          TEXT    PRIMARY KEY,        -- "!deadbeef"
+    long_name        TEXT    NOT NULL DEFAULT '',
+    short_name       TEXT    NOT NULL DEFAULT '',
+    hardware         TEXT    NOT NULL DEFAULT '',
+    role             TEXT    NOT NULL DEFAULT '',
+    firmware_version TEXT    NOT NULL DEFAULT '',
+    public_key       BLOB,
+    is_licensed      INTEGER NOT NULL DEFAULT 0,
+    last_seen        INTEGER NOT NULL,           -- Unix milliseconds
+    snr              REAL    NOT NULL DEFAULT 0, -- of the last packet heard, dB
+    hops_away        INTEGER NOT NULL DEFAULT -1, -- -1 = unknown
+    via_mqtt         INTEGER NOT NULL DEFAULT 0,
+    battery_level    INTEGER NOT NULL DEFAULT 0,
+    voltage          REAL    NOT NULL DEFAULT 0,
+    channel_util     REAL    NOT NULL DEFAULT 0,
+    lat              REAL    NOT NULL DEFAULT 0,
+    lon              REAL    NOT NULL DEFAULT 0,
+    alt              INTEGER NOT NULL DEFAULT 0
+);
+INSERT INTO nodes (node_id, long_name, last_seen)
+    SELECT node_id, COALESCE(display_name, ''), last_seen * 1000
+    FROM peers WHERE transport = 'mesh';
+UPDATE nodes SET (lat, lon, alt) = (
+    SELECT lat, lon, alt FROM positions p
+    WHERE p.node_id = nodes.node_id ORDER BY time DESC LIMIT 1)
+    WHERE node_id IN (SELECT node_id FROM positions);
+UPDATE nodes SET
+    battery_level = COALESCE((SELECT value FROM telemetry t WHERE t.node_id = nodes.node_id
+                              AND metric = 'battery_level' ORDER BY time DESC LIMIT 1), 0),
+    voltage       = COALESCE((SELECT value FROM telemetry t WHERE t.node_id = nodes.node_id
+                              AND metric = 'voltage' ORDER BY time DESC LIMIT 1), 0),
+    channel_util  = COALESCE((SELECT value FROM telemetry t WHERE t.node_id = nodes.node_id
+                              AND metric = 'channel_utilization' ORDER BY time DESC LIMIT 1), 0);
+DELETE FROM peers WHERE transport = 'mesh';
+`
@@ -0,0 +1,307 @@
+package transport
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Meshtastic stream protocol, shared by the serial and TCP (:4403) APIs:
+//
+//	0x94 0xC3 <len MSB> <len LSB> <protobuf payload, len bytes>
+//
+// Anything between frames is the device's debug console output. After a
+// corrupt header the reader discards bytes until the next 0x94 0xC3.
+const (
+	streamStart1     = 0x94
+	streamStart2     = 0xC3
+	streamHeaderLen  = 4
+	streamMaxPayload = 512 // firmware MAX_TO_FROM_RADIO_SIZE
+	streamMaxLogLine = 256
+
+	streamInitialBackoff = 2 * time.Second
+	streamMaxBackoff     = 60 * time.Second
+	streamFrameChanSize  = 256
+)
+
+// encodeStreamFrame prefixes payload with the 4-byte stream header.
+func encodeStreamFrame(payload []byte) ([]byte, error) {
+	if len(payload) > streamMaxPayload {
+		return nil, fmt.Errorf("frame too large (%d > %d bytes)", len(payload), streamMaxPayload)
+	}
+	out := make([]byte, streamHeaderLen, streamHeaderLen+len(payload))
+	out[0] = streamStart1
+	out[1] = streamStart2
+	out[2] = byte(len(payload) >> 8)
+	out[3] = byte(len(payload))
+	return append(out, payload...), nil
+}
+
+// streamReader splits a Meshtastic byte stream into frame payloads and
+// forwards interleaved debug output to the logger, one line at a time.
+type streamReader struct {
+	r       *bufio.Reader
+	log     *zap.Logger
+	name    string // log prefix, e.g. "serial"
+	logLine []byte
+}
+
+func newStreamReader(r io.Reader, name string, log *zap.Logger) *streamReader {
+	return &streamReader{
+		r:    bufio.NewReaderSize(r, streamHeaderLen+streamMaxPayload),
+		log:  log,
+		name: name,
+	}
+}
+
+// next returns the payload of the next well-formed frame. It only returns
+// an error when the underlying reader does.
+func (s *streamReader) next() ([]byte, error) {
+	for {
+		b, err := s.r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if b != streamStart1 {
+			s.logByte(b)
+			continue
+		}
+
+		b, err = s.r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if b != streamStart2 {
+			// Not a header after all; re-examine b, it may be a START1.
+			s.logByte(streamStart1)
+			s.r.UnreadByte() //nolint:errcheck // a ReadByte just succeeded
+			continue
+		}
+
+		// Peek at the length: if it is invalid, this was no header, and the
+		// scan resumes at its first byte, which may be a real START1.
+		lenBuf, err := s.r.Peek(2)
+		if err != nil {
+			if err == io.EOF && len(lenBuf) > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		n := int(lenBuf[0])<<8 | int(lenBuf[1])
+		if n == 0 || n > streamMaxPayload {
+			s.log.Warn(s.name+": invalid frame length – resyncing", zap.Int("size", n))
+			continue
+		}
+		s.r.Discard(2) //nolint:errcheck // the bytes were just peeked
+
+		payload := make([]byte, n)
+		if _, err := io.ReadFull(s.r, payload); err != nil {
+			return nil, err
+		}
+		s.flushLog()
+		return payload, nil
+	}
+}
+
+// logByte accumulates one byte of device console output.
+func (s *streamReader) logByte(b byte) {
+	if b == '\n' || len(s.logLine) >= streamMaxLogLine {
+		s.flushLog()
+		if b == '\n' {
+			return
+		}
+	}
+	if b != '\r' {
+		s.logLine = append(s.logLine, b)
+	}
+}
+
+func (s *streamReader) flushLog() {
+	if len(s.logLine) == 0 {
+		return
+	}
+	// logLine is reused, so log a copy: a core may hold on to the field.
+	s.log.Debug(s.name+": device log", zap.String("line", string(s.logLine)))
+	s.logLine = s.logLine[:0]
+}
+
+// ── Stream link ───────────────────────────────────────────────────────────
+
+// streamLink is the open → read → reconnect-with-backoff cycle shared by
+// the stream transports. TCPTransport and SerialTransport embed one and
+// differ only in how they open the byte stream, so a device that drops off
+// the network or is unplugged is picked up again without restarting the
+// gateway.
+type streamLink struct {
+	name     string      // log prefix: "tcp" or "serial"
+	target   []zap.Field // identify the device in logs
+	dial     func() (io.ReadWriteCloser, error)
+	log      *zap.Logger
+	frames   chan ProtoFrame
+	state    atomic.Int32 // ConnectionState
+	connects atomic.Uint64
+	mu       sync.Mutex
+	conn     io.ReadWriteCloser
+	writeMu  sync.Mutex
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+func newStreamLink(name string, dial func() (io.ReadWriteCloser, error), log *zap.Logger, target ...zap.Field) *streamLink {
+	l := &streamLink{
+		name:   name,
+		target: target,
+		dial:   dial,
+		log:    log,
+		frames: make(chan ProtoFrame, streamFrameChanSize),
+	}
+	l.state.Store(int32(StateDisconnected))
+	return l
+}
+
+func (l *streamLink) Connect() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.cancel != nil {
+		return nil // connect loop already running
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	l.cancel = cancel
+	l.wg.Add(1)
+	go l.readLoop(ctx)
+	return nil
+}
+
+func (l *streamLink) Disconnect() error {
+	l.mu.Lock()
+	if l.cancel != nil {
+		l.cancel()
+		l.cancel = nil
+	}
+	if l.conn != nil {
+		l.conn.Close()
+		l.conn = nil
+	}
+	// readLoop takes mu on its way out, so wait without holding it.
+	l.mu.Unlock()
+	l.wg.Wait()
+	l.state.Store(int32(StateDisconnected))
+	return nil
+}
+
+func (l *streamLink) Send(frame ProtoFrame) error {
+	l.mu.Lock()
+	conn := l.conn
+	l.mu.Unlock()
+
+	if conn == nil {
+		return fmt.Errorf("%s: not connected", l.name)
+	}
+	data, err := encodeStreamFrame(frame.Data)
+	if err != nil {
+		return fmt.Errorf("%s: send: %w", l.name, err)
+	}
+	l.writeMu.Lock()
+	defer l.writeMu.Unlock()
+	if _, err := conn.Write(data); err != nil {
+		return fmt.Errorf("%s: send: %w", l.name, err)
+	}
+	return nil
+}
+
+func (l *streamLink) Receive() <-chan ProtoFrame { return l.frames }
+
+func (l *streamLink) GetConnectionState() ConnectionState {
+	return ConnectionState(l.state.Load())
+}
+
+func (l *streamLink) Connects() uint64 { return l.connects.Load() }
+
+func (l *streamLink) readLoop(ctx context.Context) {
+	defer l.wg.Done()
+
+	backoff := streamInitialBackoff
+	for {
+		if ctx.Err() != nil {
+			l.state.Store(int32(StateDisconnected))
+			return
+		}
+
+		l.state.Store(int32(StateConnecting))
+		conn, err := l.dial()
+		if err != nil {
+			l.log.Warn(l.name+": connect failed", append(l.target,
+				zap.Duration("retry_in", backoff),
+				zap.Error(err),
+			)...)
+			l.state.Store(int32(StateFailed))
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+				backoff = min(backoff*2, streamMaxBackoff)
+				continue
+			}
+		}
+
+		backoff = streamInitialBackoff
+		l.mu.Lock()
+		l.conn = conn
+		l.mu.Unlock()
+		l.connects.Add(1)
+		l.state.Store(int32(StateConnected))
+		l.log.Info(l.name+": connected", l.target...)
+
+		l.readFrames(ctx, conn)
+
+		l.mu.Lock()
+		if l.conn == conn {
+			l.conn = nil
+		}
+		l.mu.Unlock()
+		conn.Close()
+		l.state.Store(int32(StateDisconnected))
+
+		if ctx.Err() != nil {
+			return
+		}
+		l.log.Info(l.name+": connection lost, reconnecting", l.target...)
+	}
+}
+
+func (l *streamLink) readFrames(ctx context.Context, conn io.ReadWriteCloser) {
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+	defer close(done)
+
+	sr := newStreamReader(conn, l.name, l.log)
+	for {
+		payload, err := sr.next()
+		if err != nil {
+			if ctx.Err() == nil {
+				l.log.Debug(l.name+": read", zap.Error(err))
+			}
+			return
+		}
+		select {
+		case l.frames <- ProtoFrame{Data: payload, Timestamp: time.Now().UTC()}:
+		case <-ctx.Done():
+			return
+		default:
+			l.log.Warn(l.name + ": frame channel full – dropping frame")
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package transport
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"testing/iotest"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func frame(t *testing.T, payload []byte) []byte {
+	t.Helper()
+	b, err := encodeStreamFrame(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func cat(parts ...[]byte) []byte { return bytes.Join(parts, nil) }
+
+func TestStreamReader(t *testing.T) {
+	max := bytes.Repeat([]byte{0x5A}, streamMaxPayload)
+
+	tests := []struct {
+		name   string
+		stream []byte
+		want   [][]byte
+	}{
+		{
+			name:   "back to back",
+			stream: cat(frame(t, []byte{1}), frame(t, []byte{2, 3})),
+			want:   [][]byte{{1}, {2, 3}},
+		},
+		{
+			name:   "garbage before and between frames",
+			stream: cat([]byte{0x00, 0xC3, 0xFF}, frame(t, []byte{1}), []byte("noise"), frame(t, []byte{2})),
+			want:   [][]byte{{1}, {2}},
+		},
+		{
+			name:   "START1 not followed by START2",
+			stream: cat([]byte{0x94, 0x00, 0x94, 0x94}, frame(t, []byte{7})),
+			want:   [][]byte{{7}},
+		},
+		{
+			name:   "length over 512 is skipped",
+			stream: cat([]byte{0x94, 0xC3, 0x02, 0x01}, frame(t, []byte{4})),
+			want:   [][]byte{{4}},
+		},
+		{
+			name:   "zero length is skipped",
+			stream: cat([]byte{0x94, 0xC3, 0x00, 0x00}, frame(t, []byte{5})),
+			want:   [][]byte{{5}},
+		},
+		{
+			// 0x94C3 is too long: it is START1 START2 of the real frame.
+			name:   "invalid length is rescanned for a header",
+			stream: cat([]byte{0x94, 0xC3}, frame(t, []byte{6})),
+			want:   [][]byte{{6}},
+		},
+		{
+			name:   "invalid length ending in START1",
+			stream: cat([]byte{0x94, 0xC3, 0xC3}, frame(t, []byte{8})),
+			want:   [][]byte{{8}},
+		},
+		{
+			name:   "length of exactly 512",
+			stream: frame(t, max),
+			want:   [][]byte{max},
+		},
+		{
+			name:   "payload containing the magic",
+			stream: frame(t, []byte{0x94, 0xC3, 0x00, 0x01}),
+			want:   [][]byte{{0x94, 0xC3, 0x00, 0x01}},
+		},
+	}
+	for _, tc := range tests {
+		for _, split := range []bool{false, true} {
+			name := tc.name
+			var r io.Reader = bytes.NewReader(tc.stream)
+			if split {
+				name += "/split reads"
+				r = iotest.OneByteReader(r)
+			}
+			t.Run(name, func(t *testing.T) {
+				sr := newStreamReader(r, "test", zap.NewNop())
+				for i, want := range tc.want {
+					got, err := sr.next()
+					if err != nil {
+						t.Fatalf("frame %d: %v", i, err)
+					}
+					if !bytes.Equal(got, want) {
+						t.Fatalf("frame %d = %x, want %x", i, got, want)
+					}
+				}
+				if _, err := sr.next(); err != io.EOF {
+					t.Errorf("after the last frame: err = %v, want EOF", err)
+				}
+			})
+		}
+	}
+}
+
+func TestStreamReaderTruncated(t *testing.T) {
+	for name, stream := range map[string][]byte{
+		"in header":  {0x94, 0xC3, 0x00},
+		"in payload": {0x94, 0xC3, 0x00, 0x04, 1, 2},
+	} {
+		t.Run(name, func(t *testing.T) {
+			sr := newStreamReader(bytes.NewReader(stream), "test", zap.NewNop())
+			if _, err := sr.next(); !errors.Is(err, io.ErrUnexpectedEOF) {
+				t.Errorf("err = %v, want ErrUnexpectedEOF", err)
+			}
+		})
+	}
+}
+
+func TestStreamReaderDeviceLog(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	stream := cat([]byte("INFO  | ??:??:?? 3 Power up\r\nDEBUG | partial"), frame(t, []byte{1}))
+	sr := newStreamReader(iotest.OneByteReader(bytes.NewReader(stream)), "serial", zap.New(core))
+
+	if _, err := sr.next(); err != nil {
+		t.Fatal(err)
+	}
+	var lines []string
+	for _, e := range logs.FilterMessage("serial: device log").All() {
+		lines = append(lines, e.ContextMap()["line"].(string))
+	}
+	want := []string{"INFO  | ??:??:?? 3 Power up", "DEBUG | partial"}
+	if len(lines) != len(want) || lines[0] != want[0] || lines[1] != want[1] {
+		t.Errorf("device log lines = %q, want %q", lines, want)
+	}
+}
+
+func TestEncodeStreamFrame(t *testing.T) {
+	got := frame(t, []byte{0xAA, 0xBB, 0xCC})
+	if want := []byte{0x94, 0xC3, 0x00, 0x03, 0xAA, 0xBB, 0xCC}; !bytes.Equal(got, want) {
+		t.Errorf("frame = %x, want %x", got, want)
+	}
+	if _, err := encodeStreamFrame(make([]byte, streamMaxPayload+1)); err == nil {
+		t.Error("oversized payload: want an error")
+	}
+}
+
+// TestTCPTransportConformance runs TCPTransport against a fake meshtasticd
+// that speaks the stream protocol on a local port, as the real daemon does
+// on :4403.
+func TestTCPTransportConformance(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	conns := make(chan net.Conn)
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conns <- c
+		}
+	}()
+
+	tr := NewTCPTransport(ln.Addr().String(), zap.NewNop())
+	if err := tr.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer tr.Disconnect() //nolint:errcheck
+
+	daemon := <-conns
+	waitState(t, tr, StateConnected)
+	if n := tr.Connects(); n != 1 {
+		t.Errorf("Connects = %d, want 1", n)
+	}
+
+	// Client → daemon: one stream frame, header included.
+	if err := tr.Send(ProtoFrame{Data: []byte{0x18, 0x2A}}); err != nil {
+		t.Fatal(err)
+	}
+	got := make([]byte, 6)
+	if _, err := io.ReadFull(daemon, got); err != nil {
+		t.Fatal(err)
+	}
+	if want := []byte{0x94, 0xC3, 0x00, 0x02, 0x18, 0x2A}; !bytes.Equal(got, want) {
+		t.Errorf("daemon read %x, want %x", got, want)
+	}
+
+	// Daemon → client: a frame split across writes, after console output.
+	for _, part := range [][]byte{[]byte("log line\n\x94"), {0xC3, 0x00}, {0x02, 0x08}, {0x01}} {
+		if _, err := daemon.Write(part); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := receiveFrame(t, tr); !bytes.Equal(got, []byte{0x08, 0x01}) {
+		t.Errorf("received %x, want 0801", got)
+	}
+
+	// The daemon restarting is a lost connection: the client dials again.
+	daemon.Close()
+	daemon = <-conns
+	defer daemon.Close()
+	waitState(t, tr, StateConnected)
+	if n := tr.Connects(); n != 2 {
+		t.Errorf("Connects after reconnect = %d, want 2", n)
+	}
+	if _, err := daemon.Write(frame(t, []byte{0x42})); err != nil {
+		t.Fatal(err)
+	}
+	if got := receiveFrame(t, tr); !bytes.Equal(got, []byte{0x42}) {
+		t.Errorf("after reconnect received %x, want 42", got)
+	}
+}
@@ -0,0 +1,220 @@
+package store
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Telemetry metric names. Device metrics keep their Meshtastic field names;
+// environment voltage and current are prefixed to tell them from the
+// device's own.
+const (
+	MetricBatteryLevel       = "battery_level"
+	MetricVoltage            = "voltage"
+	MetricChannelUtil        = "channel_utilization"
+	MetricAirUtilTx          = "air_util_tx"
+	MetricUptime             = "uptime_seconds"
+	MetricTemperature        = "temperature"
+	MetricRelativeHumidity   = "relative_humidity"
+	MetricBarometricPressure = "barometric_pressure"
+	MetricGasResistance      = "gas_resistance"
+	MetricEnvVoltage         = "env_voltage"
+	MetricEnvCurrent         = "env_current"
+	MetricIAQ                = "iaq"
+	MetricLux                = "lux"
+	MetricCh1Voltage         = "ch1_voltage"
+	MetricCh1Current         = "ch1_current"
+	MetricCh2Voltage         = "ch2_voltage"
+	MetricCh2Current         = "ch2_current"
+	MetricCh3Voltage         = "ch3_voltage"
+	MetricCh3Current         = "ch3_current"
+)
+
+// TelemetryMetrics lists every metric name the store accepts.
+var TelemetryMetrics = []string{
+	MetricBatteryLevel, MetricVoltage, MetricChannelUtil, MetricAirUtilTx, MetricUptime,
+	MetricTemperature, MetricRelativeHumidity, MetricBarometricPressure, MetricGasResistance,
+	MetricEnvVoltage, MetricEnvCurrent, MetricIAQ, MetricLux,
+	MetricCh1Voltage, MetricCh1Current, MetricCh2Voltage, MetricCh2Current,
+	MetricCh3Voltage, MetricCh3Current,
+}
+
+// TelemetrySample is one reading of one metric.
+type TelemetrySample struct {
+	NodeID string // "!deadbeef"
+	Metric string
+	Time   time.Time
+	Value  float64
+}
+
+// TelemetryPoint is a metric aggregated over one step of a series. Raw
+// samples have Count 1 and Avg = Min = Max.
+type TelemetryPoint struct {
+	Time  time.Time `json:"time"` // start of the step
+	Avg   float64   `json:"avg"`
+	Min   float64   `json:"min"`
+	Max   float64   `json:"max"`
+	Count int       `json:"count"`
+}
+
+// TelemetryRetention is how long each resolution is kept.
+type TelemetryRetention struct {
+	Raw    time.Duration
+	Minute time.Duration
+	Hour   time.Duration
+}
+
+// DefaultTelemetryRetention keeps raw samples for a week, minute rollups
+// for a month and hour rollups for a year.
+var DefaultTelemetryRetention = TelemetryRetention{
+	Raw:    7 * 24 * time.Hour,
+	Minute: 30 * 24 * time.Hour,
+	Hour:   365 * 24 * time.Hour,
+}
+
+// rollups are the aggregate tables maintained alongside the raw samples,
+// coarsest first.
+var rollups = []struct {
+	table string
+	res   time.Duration
+}{
+	{"telemetry_1h", time.Hour},
+	{"telemetry_1m", time.Minute},
+}
+
+// InsertTelemetry stores samples and folds them into the minute and hour
+// rollups, all in one transaction.
+func (db *DB) InsertTelemetry(samples []TelemetrySample) error {
+	if len(samples) == 0 {
+		return nil
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("store: insert telemetry: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	for _, s := range samples {
+		ts := s.Time.UnixMilli()
+		if _, err := tx.Exec(
+			`INSERT INTO telemetry (node_id, metric, time, value) VALUES (?, ?, ?, ?)`,
+			s.NodeID, s.Metric, ts, s.Value,
+		); err != nil {
+			return fmt.Errorf("store: insert telemetry: %w", err)
+		}
+		for _, r := range rollups {
+			res := r.res.Milliseconds()
+			if _, err := tx.Exec(`
+				INSERT INTO `+r.table+` (node_id, metric, bucket, count, sum, min, max)
+				VALUES (?, ?, ?, 1, ?, ?, ?)
+				ON CONFLICT (node_id, metric, bucket) DO UPDATE
+				  SET count = count + 1,
+				      sum   = sum + excluded.sum,
+				      min   = MIN(min, excluded.min),
+				      max   = MAX(max, excluded.max)`,
+				s.NodeID, s.Metric, ts/res*res, s.Value, s.Value, s.Value,
+			); err != nil {
+				return fmt.Errorf("store: roll up telemetry: %w", err)
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("store: insert telemetry: %w", err)
+	}
+	return nil
+}
+
+// QueryTelemetry returns nodeID's metric series with from <= time < to,
+// oldest first. step 0 returns the raw samples; any other step aggregates
+// them, reading from the coarsest rollup whose resolution divides step.
+// limit > 0 keeps only the newest limit points.
+func (db *DB) QueryTelemetry(nodeID, metric string, from, to time.Time, step time.Duration, limit int) ([]TelemetryPoint, error) {
+	if step < 0 {
+		return nil, fmt.Errorf("store: query telemetry: negative step")
+	}
+	lo, hi := from.UnixMilli(), to.UnixMilli()
+	if limit <= 0 {
+		limit = -1 // no limit in SQLite
+	}
+
+	var query string
+	var args []any
+	switch {
+	case step == 0:
+		query = `SELECT time, value, value, value, 1 FROM telemetry
+			WHERE node_id = ? AND metric = ? AND time >= ? AND time < ?
+			ORDER BY time DESC LIMIT ?`
+		args = []any{nodeID, metric, lo, hi, limit}
+	default:
+		ms := step.Milliseconds()
+		query = `SELECT time / ? * ? AS b, AVG(value), MIN(value), MAX(value), COUNT(*)
+			FROM telemetry
+			WHERE node_id = ? AND metric = ? AND time >= ? AND time < ?
+			GROUP BY b ORDER BY b DESC LIMIT ?`
+		for _, r := range rollups {
+			if step%r.res == 0 {
+				// Rollup buckets are aligned to their resolution, so a bucket
+				// belongs wholly to one step.
+				query = `SELECT bucket / ? * ? AS b, SUM(sum) / SUM(count), MIN(min), MAX(max), SUM(count)
+					FROM ` + r.table + `
+					WHERE node_id = ? AND metric = ? AND bucket >= ? AND bucket < ?
+					GROUP BY b ORDER BY b DESC LIMIT ?`
+				break
+			}
+		}
+		args = []any{ms, ms, nodeID, metric, lo, hi, limit}
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("store: query telemetry: %w", err)
+	}
+	defer rows.Close()
+
+	out := []TelemetryPoint{}
+	for rows.Next() {
+		var (
+			p  TelemetryPoint
+			ts int64
+		)
+		if err := rows.Scan(&ts, &p.Avg, &p.Min, &p.Max, &p.Count); err != nil {
+			return nil, fmt.Errorf("store: query telemetry: %w", err)
+		}
+		p.Time = time.UnixMilli(ts).UTC()
+		out = append(out, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: query telemetry: %w", err)
+	}
+	slices.Reverse(out)
+	return out, nil
+}
+
+// PruneTelemetry deletes samples and rollups older than their retention
+// and returns how many rows were removed. A zero retention keeps that
+// resolution forever.
+func (db *DB) PruneTelemetry(now time.Time, keep TelemetryRetention) (int64, error) {
+	steps := []struct {
+		table, column string
+		age           time.Duration
+	}{
+		{"telemetry", "time", keep.Raw},
+		{"telemetry_1m", "bucket", keep.Minute},
+		{"telemetry_1h", "bucket", keep.Hour},
+	}
+	var total int64
+	for _, s := range steps {
+		if s.age <= 0 {
+			continue
+		}
+		res, err := db.Exec(`DELETE FROM `+s.table+` WHERE `+s.column+` < ?`,
+			now.Add(-s.age).UnixMilli())
+		if err != nil {
+			return total, fmt.Errorf("store: prune %s: %w", s.table, err)
+		}
+		n, _ := res.RowsAffected()
+		total += n
+	}
+	return total, nil
+}
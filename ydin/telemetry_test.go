@@ -0,0 +1,70 @@
+package store
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func openTestDB(t *testing.T) *DB {
+	t.Helper()
+	db, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestQueryTelemetryLimit(t *testing.T) {
+	db := openTestDB(t)
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var samples []TelemetrySample
+	for i := 0; i < 5; i++ {
+		samples = append(samples, TelemetrySample{
+			NodeID: "!1234abcd", Metric: MetricVoltage,
+			Time: start.Add(time.Duration(i) * time.Minute), Value: float64(i),
+		})
+	}
+	if err := db.InsertTelemetry(samples); err != nil {
+		t.Fatal(err)
+	}
+	end := start.Add(time.Hour)
+
+	tests := []struct {
+		name  string
+		step  time.Duration
+		limit int
+		want  []float64 // Avg, oldest first
+	}{
+		{"raw", 0, 0, []float64{0, 1, 2, 3, 4}},
+		{"raw newest", 0, 3, []float64{2, 3, 4}},
+		{"raw over limit", 0, 10, []float64{0, 1, 2, 3, 4}},
+		{"minute newest", time.Minute, 2, []float64{3, 4}},
+		{"two minutes", 2 * time.Minute, 0, []float64{0.5, 2.5, 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			points, err := db.QueryTelemetry("!1234abcd", MetricVoltage, start, end, tt.step, tt.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []float64
+			for _, p := range points {
+				got = append(got, p.Avg)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("points = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("points = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
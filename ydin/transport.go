@@ -29,10 +29,12 @@ func (s ConnectionState) String() string {
 	}
 }
 
-// ProtoFrame is a raw Meshtastic framed payload (varint-length-prefixed bytes).
+// ProtoFrame is one FromRadio/ToRadio protobuf. Transports strip and add the
+// 0x94 0xC3 stream header themselves, so Data never includes it.
 type ProtoFrame struct {
 	Data      []byte
 	Timestamp time.Time
+	Source    string // receiving link, set by MultiTransport on inbound frames
 }
 
 // TransportManager is the abstraction over BLE and TCP transports.
@@ -49,3 +51,45 @@ type TransportManager interface {
 	// GetConnectionState returns the current link state.
 	GetConnectionState() ConnectionState
 }
+
+// AckReporter is implemented by transports that can tell, before sending,
+// whether a want_ack frame will be answered by a ROUTING_APP response. A
+// radio relays the mesh ACK; an MQTT broker's PUBACK says nothing about
+// delivery. Transports that do not implement it are assumed to.
+type AckReporter interface {
+	ReportsAcks(frame ProtoFrame) bool
+}
+
+// ReportsAcks reports whether t is expected to answer frame's want_ack.
+func ReportsAcks(t TransportManager, frame ProtoFrame) bool {
+	if r, ok := t.(AckReporter); ok {
+		return r.ReportsAcks(frame)
+	}
+	return true
+}
+
+// ConnectCounter is implemented by transports that count how many times
+// they have connected. A reconnect can come and go between two polls of
+// GetConnectionState; the count still moves.
+type ConnectCounter interface {
+	Connects() uint64
+}
+
+// Connects returns t's connect count, or 0 if t does not keep one.
+func Connects(t TransportManager) uint64 {
+	if c, ok := t.(ConnectCounter); ok {
+		return c.Connects()
+	}
+	return 0
+}
+
+// ConfigLinker is implemented by transports that run the want_config
+// handshake over one of several links (see MultiTransport). The handshake
+// must be repeated when that link reconnects, whatever the other links do,
+// so the gateway watches it instead of the overall state.
+type ConfigLinker interface {
+	// ConfigLink returns the state and connect count of the link that
+	// answered the last want_config. Before any has, it returns the
+	// overall state and 0.
+	ConfigLink() (ConnectionState, uint64)
+}
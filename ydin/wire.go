@@ -0,0 +1,1008 @@
+package proto
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// This file maps the hand-written Go structs in meshtastic.go onto the
+// Meshtastic protobuf wire schema (meshtastic/protobufs: mesh.proto,
+// channel.proto, config.proto, telemetry.proto). Field numbers below are
+// taken verbatim from those definitions; unknown fields are skipped so
+// newer firmware keeps decoding.
+
+// ── field walker ──────────────────────────────────────────────────────────
+
+// field is one decoded tag/value pair. Scalar values (varint, fixed32,
+// fixed64) are held in u; length-delimited values in b.
+type field struct {
+	num protowire.Number
+	typ protowire.Type
+	u   uint64
+	b   []byte
+}
+
+func (f field) uint32() uint32   { return uint32(f.u) }
+func (f field) int32() int32     { return int32(f.u) }
+func (f field) bool() bool       { return f.u != 0 }
+func (f field) float32() float32 { return math.Float32frombits(uint32(f.u)) }
+func (f field) string() string   { return string(f.b) }
+func (f field) bytes() []byte    { return append([]byte(nil), f.b...) }
+
+var errWireType = errors.New("wrong wire type")
+
+// want returns an error if f was not encoded with wire type typ.
+func (f field) want(typ protowire.Type) error {
+	if f.typ != typ {
+		return fmt.Errorf("field %d: %w (%d)", f.num, errWireType, f.typ)
+	}
+	return nil
+}
+
+// message decodes f, which must hold an embedded message, with unmarshal.
+func (f field) message(unmarshal func([]byte) error) error {
+	if err := f.want(protowire.BytesType); err != nil {
+		return err
+	}
+	return unmarshal(f.b)
+}
+
+// walk calls fn for every top-level field in the encoded message b.
+func walk(b []byte, fn func(f field) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		f := field{num: num, typ: typ}
+		switch typ {
+		case protowire.VarintType:
+			f.u, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed32Type:
+			var v uint32
+			v, n = protowire.ConsumeFixed32(b)
+			f.u = uint64(v)
+		case protowire.Fixed64Type:
+			f.u, n = protowire.ConsumeFixed64(b)
+		case protowire.BytesType:
+			f.b, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return fmt.Errorf("field %d: %w", num, protowire.ParseError(n))
+		}
+		b = b[n:]
+
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ── append helpers (proto3: zero values are omitted) ──────────────────────
+
+func appendVarint(b []byte, num protowire.Number, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+func appendInt32(b []byte, num protowire.Number, v int32) []byte {
+	// Negative int32 values are sign-extended to 64 bits on the wire.
+	return appendVarint(b, num, uint64(int64(v)))
+}
+
+func appendBool(b []byte, num protowire.Number, v bool) []byte {
+	return appendVarint(b, num, protowire.EncodeBool(v))
+}
+
+func appendFixed32(b []byte, num protowire.Number, v uint32) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.Fixed32Type)
+	return protowire.AppendFixed32(b, v)
+}
+
+func appendFloat32(b []byte, num protowire.Number, v float32) []byte {
+	return appendFixed32(b, num, math.Float32bits(v))
+}
+
+func appendBytes(b []byte, num protowire.Number, v []byte) []byte {
+	if len(v) == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, v)
+}
+
+func appendString(b []byte, num protowire.Number, v string) []byte {
+	if v == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, v)
+}
+
+// appendMessage writes a submessage even when it encodes to zero bytes,
+// because presence of a oneof member is significant.
+func appendMessage(b []byte, num protowire.Number, m []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, m)
+}
+
+// ── FromRadio / ToRadio ───────────────────────────────────────────────────
+
+func (x *FromRadio) unmarshal(b []byte) error {
+	return walk(b, func(f field) error {
+		switch f.num {
+		case 1: // id
+			x.ID = f.uint32()
+		case 2: // packet
+			x.Packet = &MeshPacket{}
+			return f.message(x.Packet.unmarshal)
+		case 3: // my_info
+			x.MyInfo = &MyNodeInfo{}
+			return f.message(x.MyInfo.unmarshal)
+		case 4: // node_info
+			x.NodeInfo = &NodeInfo{}
+			return f.message(x.NodeInfo.unmarshal)
+		case 5: // config
+			x.Config = &Config{}
+			return f.message(x.Config.unmarshal)
+		case 7: // config_complete_id
+			x.ConfigCompleteID = f.uint32()
+		case 8: // rebooted
+			x.Rebooted = f.bool()
+		case 10: // channel
+			x.Channel = &Channel{}
+			return f.message(x.Channel.unmarshal)
+		case 13: // metadata
+			x.Metadata = &DeviceMetadata{}
+			return f.message(x.Metadata.unmarshal)
+		}
+		return nil
+	})
+}
+
+func (x *FromRadio) marshal(b []byte) []byte {
+	b = appendVarint(b, 1, uint64(x.ID)) // id
+	switch {
+	case x.Packet != nil:
+		b = appendMessage(b, 2, x.Packet.marshal(nil)) // packet
+	case x.MyInfo != nil:
+		b = appendMessage(b, 3, x.MyInfo.marshal(nil)) // my_info
+	case x.NodeInfo != nil:
+		b = appendMessage(b, 4, x.NodeInfo.marshal(nil)) // node_info
+	case x.Config != nil:
+		b = appendMessage(b, 5, x.Config.marshal(nil)) // config
+	case x.ConfigCompleteID != 0:
+		b = appendVarint(b, 7, uint64(x.ConfigCompleteID)) // config_complete_id
+	case x.Rebooted:
+		b = appendBool(b, 8, true) // rebooted
+	case x.Channel != nil:
+		b = appendMessage(b, 10, x.Channel.marshal(nil)) // channel
+	case x.Metadata != nil:
+		b = appendMessage(b, 13, x.Metadata.marshal(nil)) // metadata
+	}
+	return b
+}
+
+func (x *ToRadio) unmarshal(b []byte) error {
+	return walk(b, func(f field) error {
+		switch f.num {
+		case 1: // packet
+			x.Packet = &MeshPacket{}
+			return f.message(x.Packet.unmarshal)
+		case 3: // want_config_id
+			x.WantConfigID = f.uint32()
+		}
+		return nil
+	})
+}
+
+func (x *ToRadio) marshal(b []byte) []byte {
+	if x.Packet != nil {
+		b = appendMessage(b, 1, x.Packet.marshal(nil)) // packet
+	}
+	b = appendVarint(b, 3, uint64(x.WantConfigID)) // want_config_id
+	return b
+}
+
+// ── MeshPacket / Data ─────────────────────────────────────────────────────
+
+func (p *MeshPacket) unmarshal(b []byte) error {
+	return walk(b, func(f field) error {
+		switch f.num {
+		case 1: // from (fixed32)
+			p.From = f.uint32()
+		case 2: // to (fixed32)
+			p.To = f.uint32()
+		case 3: // channel
+			p.Channel = f.uint32()
+		case 4: // decoded (Data)
+			return f.message(p.unmarshalData)
+		case 5: // encrypted
+			p.Encrypted = f.bytes()
+		case 6: // id (fixed32)
+			p.ID = f.uint32()
+		case 7: // rx_time (fixed32)
+			p.RxTime = f.uint32()
+		case 8: // rx_snr (float)
+			p.RxSNR = f.float32()
+		case 9: // hop_limit
+			p.HopLimit = f.uint32()
+		case 10: // want_ack
+			p.WantAck = f.bool()
+		case 11: // priority
+			p.Priority = f.uint32()
+		case 12: // rx_rssi
+			p.RxRSSI = f.int32()
+		case 14: // via_mqtt
+			p.ViaMQTT = f.bool()
+		case 15: // hop_start
+			p.HopStart = f.uint32()
+		}
+		return nil
+	})
+}
+
+func (p *MeshPacket) unmarshalData(b []byte) error {
+	return walk(b, func(f field) error {
+		switch f.num {
+		case 1: // portnum
+			p.PortNum = PortNum(f.uint32())
+		case 2: // payload
+			p.Payload = f.bytes()
+		case 3: // want_response
+			p.WantResponse = f.bool()
+		case 4: // dest (fixed32)
+			p.Dest = f.uint32()
+		case 5: // source (fixed32)
+			p.Source = f.uint32()
+		case 6: // request_id (fixed32)
+			p.RequestID = f.uint32()
+		case 7: // reply_id (fixed32)
+			p.ReplyID = f.uint32()
+		case 8: // emoji (fixed32)
+			p.Emoji = f.uint32()
+		}
+		return nil
+	})
+}
+
+func (p *MeshPacket) marshal(b []byte) []byte {
+	b = appendFixed32(b, 1, p.From)
+	b = appendFixed32(b, 2, p.To)
+	b = appendVarint(b, 3, uint64(p.Channel))
+	if p.Encrypted != nil {
+		b = appendBytes(b, 5, p.Encrypted)
+	} else {
+		b = appendMessage(b, 4, p.marshalData(nil))
+	}
+	b = appendFixed32(b, 6, p.ID)
+	b = appendFixed32(b, 7, p.RxTime)
+	b = appendFloat32(b, 8, p.RxSNR)
+	b = appendVarint(b, 9, uint64(p.HopLimit))
+	b = appendBool(b, 10, p.WantAck)
+	b = appendVarint(b, 11, uint64(p.Priority))
+	b = appendInt32(b, 12, p.RxRSSI)
+	b = appendBool(b, 14, p.ViaMQTT)
+	b = appendVarint(b, 15, uint64(p.HopStart))
+	return b
+}
+
+func (p *MeshPacket) marshalData(b []byte) []byte {
+	b = appendVarint(b, 1, uint64(p.PortNum))
+	b = appendBytes(b, 2, p.Payload)
+	b = appendBool(b, 3, p.WantResponse)
+	b = appendFixed32(b, 4, p.Dest)
+	b = appendFixed32(b, 5, p.Source)
+	b = appendFixed32(b, 6, p.RequestID)
+	b = appendFixed32(b, 7, p.ReplyID)
+	b = appendFixed32(b, 8, p.Emoji)
+	return b
+}
+
+// ── Node DB ───────────────────────────────────────────────────────────────
+
+func (x *MyNodeInfo) unmarshal(b []byte) error {
+	return walk(b, func(f field) error {
+		switch f.num {
+		case 1: // my_node_num
+			x.MyNodeNum = f.uint32()
+		case 8: // reboot_count
+			x.RebootCount = f.uint32()
+		case 11: // min_app_version
+			x.MinAppVersion = f.uint32()
+		case 12: // device_id
+			x.DeviceID = f.bytes()
+		case 13: // pio_env
+			x.PioEnv = f.string()
+		}
+		return nil
+	})
+}
+
+func (x *MyNodeInfo) marshal(b []byte) []byte {
+	b = appendVarint(b, 1, uint64(x.MyNodeNum))
+	b = appendVarint(b, 8, uint64(x.RebootCount))
+	b = appendVarint(b, 11, uint64(x.MinAppVersion))
+	b = appendBytes(b, 12, x.DeviceID)
+	b = appendString(b, 13, x.PioEnv)
+	return b
+}
+
+func (n *NodeInfo) unmarshal(b []byte) error {
+	return walk(b, func(f field) error {
+		switch f.num {
+		case 1: // num
+			n.NodeID = f.uint32()
+		case 2: // user
+			return f.message(n.unmarshalUser)
+		case 3: // position
+			n.Position = &Position{}
+			return f.message(n.Position.unmarshal)
+		case 4: // snr
+			n.SNR = f.float32()
+		case 5: // last_heard (fixed32)
+			n.LastHeard = f.uint32()
+		case 6: // device_metrics
+			n.DeviceMetrics = &DeviceMetrics{}
+			return f.message(n.DeviceMetrics.unmarshal)
+		case 7: // channel
+			n.Channel = f.uint32()
+		case 8: // via_mqtt
+			n.ViaMQTT = f.bool()
+		case 9: // hops_away
+			n.HopsAway = f.uint32()
+		case 10: // is_favorite
+			n.IsFavorite = f.bool()
+		}
+		return nil
+	})
+}
+
+// unmarshalUser decodes a User message into the user fields of n.
+func (n *NodeInfo) unmarshalUser(b []byte) error {
+	return walk(b, func(f field) error {
+		switch f.num {
+		case 1: // id
+			n.UserID = f.string()
+		case 2: // long_name
+			n.LongName = f.string()
+		case 3: // short_name
+			n.ShortName = f.string()
+		case 5: // hw_model
+			n.HardwareModel = HardwareModelName(f.uint32())
+		case 6: // is_licensed
+			n.IsLicensed = f.bool()
+		case 7: // role
+			n.Role = RoleName(f.uint32())
+		case 8: // public_key
+			n.PublicKey = f.bytes()
+		}
+		return nil
+	})
+}
+
+func (n *NodeInfo) marshal(b []byte) []byte {
+	b = appendVarint(b, 1, uint64(n.NodeID))
+	b = appendMessage(b, 2, n.marshalUser(nil))
+	if n.Position != nil {
+		b = appendMessage(b, 3, n.Position.marshal(nil))
+	}
+	b = appendFloat32(b, 4, n.SNR)
+	b = appendFixed32(b, 5, n.LastHeard)
+	if n.DeviceMetrics != nil {
+		b = appendMessage(b, 6, n.DeviceMetrics.marshal(nil))
+	}
+	b = appendVarint(b, 7, uint64(n.Channel))
+	b = appendBool(b, 8, n.ViaMQTT)
+	b = appendVarint(b, 9, uint64(n.HopsAway))
+	b = appendBool(b, 10, n.IsFavorite)
+	return b
+}
+
+func (x *DeviceMetadata) unmarshal(b []byte) error {
+	return walk(b, func(f field) error {
+		switch f.num {
+		case 1: // firmware_version
+			x.FirmwareVersion = f.string()
+		case 2: // device_state_version
+			x.DeviceStateVersion = f.uint32()
+		case 3: // canShutdown
+			x.CanShutdown = f.bool()
+		case 4: // hasWifi
+			x.HasWifi = f.bool()
+		case 5: // hasBluetooth
+			x.HasBluetooth = f.bool()
+		case 6: // hasEthernet
+			x.HasEthernet = f.bool()
+		case 7: // role
+			x.Role = RoleName(f.uint32())
+		case 9: // hw_model
+			x.HardwareModel = HardwareModelName(f.uint32())
+		case 11: // hasPKC
+			x.HasPKC = f.bool()
+		}
+		return nil
+	})
+}
+
+func (x *DeviceMetadata) marshal(b []byte) []byte {
+	b = appendString(b, 1, x.FirmwareVersion)
+	b = appendVarint(b, 2, uint64(x.DeviceStateVersion))
+	b = appendBool(b, 3, x.CanShutdown)
+	b = appendBool(b, 4, x.HasWifi)
+	b = appendBool(b, 5, x.HasBluetooth)
+	b = appendBool(b, 6, x.HasEthernet)
+	b = appendVarint(b, 7, uint64(RoleNumber(x.Role)))
+	b = appendVarint(b, 9, uint64(HardwareModelNumber(x.HardwareModel)))
+	b = appendBool(b, 11, x.HasPKC)
+	return b
+}
+
+func (x *DeviceMetrics) unmarshal(b []byte) error {
+	return walk(b, func(f field) error {
+		switch f.num {
+		case 1: // battery_level
+			x.BatteryLevel = f.uint32()
+		case 2: // voltage
+			x.Voltage = f.float32()
+		case 3: // channel_utilization
+			x.ChannelUtil = f.float32()
+		case 4: // air_util_tx
+			x.AirUtil = f.float32()
+		case 5: // uptime_seconds
+			x.UptimeSeconds = f.uint32()
+		}
+		return nil
+	})
+}
+
+func (x *DeviceMetrics) marshal(b []byte) []byte {
+	b = appendVarint(b, 1, uint64(x.BatteryLevel))
+	b = appendFloat32(b, 2, x.Voltage)
+	b = appendFloat32(b, 3, x.ChannelUtil)
+	b = appendFloat32(b, 4, x.AirUtil)
+	b = appendVarint(b, 5, uint64(x.UptimeSeconds))
+	return b
+}
+
+// marshalUser encodes the user fields of n as a User message.
+func (n *NodeInfo) marshalUser(b []byte) []byte {
+	b = appendString(b, 1, n.UserID)
+	b = appendString(b, 2, n.LongName)
+	b = appendString(b, 3, n.ShortName)
+	b = appendVarint(b, 5, uint64(HardwareModelNumber(n.HardwareModel)))
+	b = appendBool(b, 6, n.IsLicensed)
+	b = appendVarint(b, 7, uint64(RoleNumber(n.Role)))
+	b = appendBytes(b, 8, n.PublicKey)
+	return b
+}
+
+func (x *Position) unmarshal(b []byte) error {
+	return walk(b, func(f field) error {
+		switch f.num {
+		case 1: // latitude_i (sfixed32)
+			x.LatitudeI = f.int32()
+		case 2: // longitude_i (sfixed32)
+			x.LongitudeI = f.int32()
+		case 3: // altitude
+			x.Altitude = f.int32()
+		case 4: // time (fixed32)
+			x.Time = f.uint32()
+		case 7: // timestamp (fixed32)
+			x.Timestamp = f.uint32()
+		case 11: // PDOP
+			x.PDOP = f.uint32()
+		case 15: // ground_speed
+			x.GroundSpeed = f.uint32()
+		case 16: // ground_track
+			x.GroundTrack = f.uint32()
+		case 19: // sats_in_view
+			x.SatsInView = f.uint32()
+		case 23: // precision_bits
+			x.PrecisionBits = f.uint32()
+		}
+		return nil
+	})
+}
+
+func (x *Position) marshal(b []byte) []byte {
+	b = appendFixed32(b, 1, uint32(x.LatitudeI))
+	b = appendFixed32(b, 2, uint32(x.LongitudeI))
+	b = appendInt32(b, 3, x.Altitude)
+	b = appendFixed32(b, 4, x.Time)
+	b = appendFixed32(b, 7, x.Timestamp)
+	b = appendVarint(b, 11, uint64(x.PDOP))
+	b = appendVarint(b, 15, uint64(x.GroundSpeed))
+	b = appendVarint(b, 16, uint64(x.GroundTrack))
+	b = appendVarint(b, 19, uint64(x.SatsInView))
+	b = appendVarint(b, 23, uint64(x.PrecisionBits))
+	return b
+}
+
+// ── Telemetry ─────────────────────────────────────────────────────────────
+
+func (t *Telemetry) unmarshal(b []byte) error {
+	return walk(b, func(f field) error {
+		switch f.num {
+		case 1: // time (fixed32)
+			t.Time = f.uint32()
+		case 2: // device_metrics
+			t.Device = &DeviceMetrics{}
+			return f.message(t.Device.unmarshal)
+		case 3: // environment_metrics
+			t.Environment = &EnvironmentMetrics{}
+			return f.message(t.Environment.unmarshal)
+		case 5: // power_metrics
+			t.Power = &PowerMetrics{}
+			return f.message(t.Power.unmarshal)
+		}
+		return nil
+	})
+}
+
+func (t *Telemetry) marshal(b []byte) []byte {
+	b = appendFixed32(b, 1, t.Time)
+	switch {
+	case t.Device != nil:
+		b = appendMessage(b, 2, t.Device.marshal(nil))
+	case t.Environment != nil:
+		b = appendMessage(b, 3, t.Environment.marshal(nil))
+	case t.Power != nil:
+		b = appendMessage(b, 5, t.Power.marshal(nil))
+	}
+	return b
+}
+
+func (e *EnvironmentMetrics) unmarshal(b []byte) error {
+	return walk(b, func(f field) error {
+		v := f.float32()
+		switch f.num {
+		case 1: // temperature
+			e.Temperature = &v
+		case 2: // relative_humidity
+			e.RelativeHumidity = &v
+		case 3: // barometric_pressure
+			e.BarometricPressure = &v
+		case 4: // gas_resistance
+			e.GasResistance = &v
+		case 5: // voltage
+			e.Voltage = &v
+		case 6: // current
+			e.Current = &v
+		case 7: // iaq
+			iaq := f.uint32()
+			e.IAQ = &iaq
+		case 9: // lux
+			e.Lux = &v
+		}
+		return nil
+	})
+}
+
+func (e *EnvironmentMetrics) marshal(b []byte) []byte {
+	// Optional fields: encode whenever present, including zero values.
+	float := func(b []byte, num protowire.Number, v *float32) []byte {
+		if v == nil {
+			return b
+		}
+		b = protowire.AppendTag(b, num, protowire.Fixed32Type)
+		return protowire.AppendFixed32(b, math.Float32bits(*v))
+	}
+	b = float(b, 1, e.Temperature)
+	b = float(b, 2, e.RelativeHumidity)
+	b = float(b, 3, e.BarometricPressure)
+	b = float(b, 4, e.GasResistance)
+	b = float(b, 5, e.Voltage)
+	b = float(b, 6, e.Current)
+	if e.IAQ != nil {
+		b = protowire.AppendTag(b, 7, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(*e.IAQ))
+	}
+	b = float(b, 9, e.Lux)
+	return b
+}
+
+func (p *PowerMetrics) unmarshal(b []byte) error {
+	return walk(b, func(f field) error {
+		switch f.num {
+		case 1:
+			p.Ch1Voltage = f.float32()
+		case 2:
+			p.Ch1Current = f.float32()
+		case 3:
+			p.Ch2Voltage = f.float32()
+		case 4:
+			p.Ch2Current = f.float32()
+		case 5:
+			p.Ch3Voltage = f.float32()
+		case 6:
+			p.Ch3Current = f.float32()
+		}
+		return nil
+	})
+}
+
+func (p *PowerMetrics) marshal(b []byte) []byte {
+	b = appendFloat32(b, 1, p.Ch1Voltage)
+	b = appendFloat32(b, 2, p.Ch1Current)
+	b = appendFloat32(b, 3, p.Ch2Voltage)
+	b = appendFloat32(b, 4, p.Ch2Current)
+	b = appendFloat32(b, 5, p.Ch3Voltage)
+	b = appendFloat32(b, 6, p.Ch3Current)
+	return b
+}
+
+// ── Routing ───────────────────────────────────────────────────────────────
+
+func (r *Routing) unmarshal(b []byte) error {
+	return walk(b, func(f field) error {
+		switch f.num {
+		case 1: // route_request
+			r.RouteRequest = &RouteDiscovery{}
+			return f.message(r.RouteRequest.unmarshal)
+		case 2: // route_reply
+			r.RouteReply = &RouteDiscovery{}
+			return f.message(r.RouteReply.unmarshal)
+		case 3: // error_reason
+			r.ErrorReason = RoutingError(f.uint32())
+		}
+		return nil
+	})
+}
+
+func (r *Routing) marshal(b []byte) []byte {
+	switch {
+	case r.RouteRequest != nil:
+		b = appendMessage(b, 1, r.RouteRequest.marshal(nil))
+	case r.RouteReply != nil:
+		b = appendMessage(b, 2, r.RouteReply.marshal(nil))
+	default:
+		// error_reason is the oneof member here, so NONE (the ACK) must
+		// still be written explicitly.
+		b = protowire.AppendTag(b, 3, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(r.ErrorReason))
+	}
+	return b
+}
+
+func (d *RouteDiscovery) unmarshal(b []byte) error {
+	return walk(b, func(f field) error {
+		var err error
+		switch f.num {
+		case 1: // route (repeated fixed32)
+			d.Route, err = appendRepeatedFixed32(d.Route, f)
+		case 2: // snr_towards (repeated int32)
+			d.SNRTowards, err = appendRepeatedInt32(d.SNRTowards, f)
+		case 3: // route_back (repeated fixed32)
+			d.RouteBack, err = appendRepeatedFixed32(d.RouteBack, f)
+		case 4: // snr_back (repeated int32)
+			d.SNRBack, err = appendRepeatedInt32(d.SNRBack, f)
+		}
+		return err
+	})
+}
+
+func (d *RouteDiscovery) marshal(b []byte) []byte {
+	packedFixed32 := func(b []byte, num protowire.Number, vs []uint32) []byte {
+		if len(vs) == 0 {
+			return b
+		}
+		var p []byte
+		for _, v := range vs {
+			p = protowire.AppendFixed32(p, v)
+		}
+		return appendMessage(b, num, p)
+	}
+	packedInt32 := func(b []byte, num protowire.Number, vs []int32) []byte {
+		if len(vs) == 0 {
+			return b
+		}
+		var p []byte
+		for _, v := range vs {
+			p = protowire.AppendVarint(p, uint64(int64(v)))
+		}
+		return appendMessage(b, num, p)
+	}
+	b = packedFixed32(b, 1, d.Route)
+	b = packedInt32(b, 2, d.SNRTowards)
+	b = packedFixed32(b, 3, d.RouteBack)
+	b = packedInt32(b, 4, d.SNRBack)
+	return b
+}
+
+// appendRepeatedFixed32 accepts both packed and unpacked encodings.
+func appendRepeatedFixed32(dst []uint32, f field) ([]uint32, error) {
+	if f.typ != protowire.BytesType {
+		return append(dst, f.uint32()), nil
+	}
+	b := f.b
+	for len(b) > 0 {
+		v, n := protowire.ConsumeFixed32(b)
+		if n < 0 {
+			return dst, protowire.ParseError(n)
+		}
+		dst = append(dst, v)
+		b = b[n:]
+	}
+	return dst, nil
+}
+
+// appendRepeatedInt32 accepts both packed and unpacked encodings.
+func appendRepeatedInt32(dst []int32, f field) ([]int32, error) {
+	if f.typ != protowire.BytesType {
+		return append(dst, f.int32()), nil
+	}
+	b := f.b
+	for len(b) > 0 {
+		v, n := protowire.ConsumeVarint(b)
+		if n < 0 {
+			return dst, protowire.ParseError(n)
+		}
+		dst = append(dst, int32(v))
+		b = b[n:]
+	}
+	return dst, nil
+}
+
+// ── Channels & config ─────────────────────────────────────────────────────
+
+func (c *Channel) unmarshal(b []byte) error {
+	return walk(b, func(f field) error {
+		switch f.num {
+		case 1: // index
+			c.Index = f.int32()
+		case 2: // settings
+			c.Settings = &ChannelSettings{}
+			return f.message(c.Settings.unmarshal)
+		case 3: // role
+			c.Role = ChannelRole(f.int32())
+		}
+		return nil
+	})
+}
+
+func (s *ChannelSettings) unmarshal(b []byte) error {
+	return walk(b, func(f field) error {
+		switch f.num {
+		case 2: // psk
+			s.PSK = f.bytes()
+		case 3: // name
+			s.Name = f.string()
+		case 4: // id (fixed32)
+			s.ID = f.uint32()
+		case 5: // uplink_enabled
+			s.UplinkEnabled = f.bool()
+		case 6: // downlink_enabled
+			s.DownlinkEnabled = f.bool()
+		case 7: // module_settings
+			return walk(f.b, func(f field) error {
+				switch f.num {
+				case 1: // position_precision
+					s.PositionPrecision = f.uint32()
+				case 2: // is_client_muted
+					s.IsClientMuted = f.bool()
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+}
+
+func (c *Channel) marshal(b []byte) []byte {
+	b = appendInt32(b, 1, c.Index)
+	if c.Settings != nil {
+		b = appendMessage(b, 2, c.Settings.marshal(nil))
+	}
+	b = appendVarint(b, 3, uint64(c.Role))
+	return b
+}
+
+func (s *ChannelSettings) marshal(b []byte) []byte {
+	b = appendBytes(b, 2, s.PSK)
+	b = appendString(b, 3, s.Name)
+	b = appendFixed32(b, 4, s.ID)
+	b = appendBool(b, 5, s.UplinkEnabled)
+	b = appendBool(b, 6, s.DownlinkEnabled)
+	var ms []byte
+	ms = appendVarint(ms, 1, uint64(s.PositionPrecision))
+	ms = appendBool(ms, 2, s.IsClientMuted)
+	if len(ms) > 0 {
+		b = appendMessage(b, 7, ms)
+	}
+	return b
+}
+
+func (a *AdminMessage) marshal(b []byte) []byte {
+	switch {
+	case a.SetChannel != nil:
+		b = appendMessage(b, 33, a.SetChannel.marshal(nil)) // set_channel
+	case a.BeginEditSettings:
+		b = appendBool(b, 64, true) // begin_edit_settings
+	case a.CommitEditSettings:
+		b = appendBool(b, 65, true) // commit_edit_settings
+	}
+	return b
+}
+
+func (c *Config) unmarshal(b []byte) error {
+	return walk(b, func(f field) error {
+		switch f.num {
+		case 1: // device
+			c.Device = &DeviceConfig{}
+			return walk(f.b, func(f field) error {
+				if f.num == 1 { // role
+					c.Device.Role = RoleName(f.uint32())
+				}
+				return nil
+			})
+		case 6: // lora
+			c.LoRa = &LoRaConfig{}
+			return f.message(c.LoRa.unmarshal)
+		}
+		return nil
+	})
+}
+
+func (c *Config) marshal(b []byte) []byte {
+	switch {
+	case c.Device != nil:
+		b = appendMessage(b, 1, appendVarint(nil, 1, uint64(RoleNumber(c.Device.Role))))
+	case c.LoRa != nil:
+		b = appendMessage(b, 6, c.LoRa.marshal(nil))
+	}
+	return b
+}
+
+func (l *LoRaConfig) unmarshal(b []byte) error {
+	return walk(b, func(f field) error {
+		switch f.num {
+		case 1: // use_preset
+			l.UsePreset = f.bool()
+		case 2: // modem_preset
+			l.ModemPreset = f.uint32()
+		case 7: // region
+			l.Region = f.uint32()
+		case 8: // hop_limit
+			l.HopLimit = f.uint32()
+		case 9: // tx_enabled
+			l.TxEnabled = f.bool()
+		case 10: // tx_power
+			l.TxPower = f.int32()
+		case 11: // channel_num
+			l.ChannelNum = f.uint32()
+		}
+		return nil
+	})
+}
+
+func (l *LoRaConfig) marshal(b []byte) []byte {
+	b = appendBool(b, 1, l.UsePreset)
+	b = appendVarint(b, 2, uint64(l.ModemPreset))
+	b = appendVarint(b, 7, uint64(l.Region))
+	b = appendVarint(b, 8, uint64(l.HopLimit))
+	b = appendBool(b, 9, l.TxEnabled)
+	b = appendInt32(b, 10, l.TxPower)
+	b = appendVarint(b, 11, uint64(l.ChannelNum))
+	return b
+}
+
+// ── MQTT ──────────────────────────────────────────────────────────────────
+
+func (e *ServiceEnvelope) unmarshal(b []byte) error {
+	return walk(b, func(f field) error {
+		switch f.num {
+		case 1: // packet
+			e.Packet = &MeshPacket{}
+			return f.message(e.Packet.unmarshal)
+		case 2: // channel_id
+			e.ChannelID = f.string()
+		case 3: // gateway_id
+			e.GatewayID = f.string()
+		}
+		return nil
+	})
+}
+
+func (e *ServiceEnvelope) marshal(b []byte) []byte {
+	b = appendMessage(b, 1, e.Packet.marshal(nil))
+	b = appendString(b, 2, e.ChannelID)
+	b = appendString(b, 3, e.GatewayID)
+	return b
+}
+
+// ── enum names ────────────────────────────────────────────────────────────
+
+var modemPresetNames = []string{
+	"LongFast", "LongSlow", "VeryLongSlow", "MediumSlow", "MediumFast",
+	"ShortSlow", "ShortFast", "LongModerate", "ShortTurbo",
+}
+
+// ModemPresetName returns the display name the firmware gives an unnamed
+// primary channel for LoRa modem preset v (e.g. "LongFast").
+func ModemPresetName(v uint32) string {
+	if int(v) < len(modemPresetNames) {
+		return modemPresetNames[v]
+	}
+	return fmt.Sprintf("Preset%d", v)
+}
+
+var roleNames = []string{
+	"CLIENT", "CLIENT_MUTE", "ROUTER", "ROUTER_CLIENT", "REPEATER",
+	"TRACKER", "SENSOR", "TAK", "CLIENT_HIDDEN", "LOST_AND_FOUND",
+	"TAK_TRACKER", "ROUTER_LATE",
+}
+
+// RoleName returns the Config.DeviceConfig.Role enum name for v.
+func RoleName(v uint32) string {
+	if int(v) < len(roleNames) {
+		return roleNames[v]
+	}
+	return fmt.Sprintf("ROLE_%d", v)
+}
+
+// RoleNumber is the inverse of RoleName. Unknown names map to CLIENT (0).
+func RoleNumber(name string) uint32 {
+	for i, n := range roleNames {
+		if n == name {
+			return uint32(i)
+		}
+	}
+	var v uint32
+	fmt.Sscanf(name, "ROLE_%d", &v) //nolint:errcheck
+	return v
+}
+
+var hardwareModelNames = map[uint32]string{
+	0: "UNSET", 1: "TLORA_V2", 2: "TLORA_V1", 3: "TLORA_V2_1_1P6",
+	4: "TBEAM", 5: "HELTEC_V2_0", 6: "TBEAM_V0P7", 7: "T_ECHO",
+	8: "TLORA_V1_1P3", 9: "RAK4631", 10: "HELTEC_V2_1", 11: "HELTEC_V1",
+	12: "LILYGO_TBEAM_S3_CORE", 13: "RAK11200", 14: "NANO_G1",
+	15: "TLORA_V2_1_1P8", 16: "TLORA_T3_S3", 17: "NANO_G1_EXPLORER",
+	18: "NANO_G2_ULTRA", 25: "STATION_G1", 26: "RAK11310",
+	31: "STATION_G2", 37: "PORTDUINO", 39: "DIY_V1", 42: "M5STACK",
+	43: "HELTEC_V3", 44: "HELTEC_WSL_V3", 47: "RPI_PICO",
+	48: "HELTEC_WIRELESS_TRACKER", 49: "HELTEC_WIRELESS_PAPER",
+	50: "T_DECK", 51: "T_WATCH_S3", 53: "HELTEC_HT62",
+	57: "HELTEC_WIRELESS_PAPER_V1_0", 58: "HELTEC_WIRELESS_TRACKER_V1_0",
+	65: "HELTEC_CAPSULE_SENSOR_V3", 66: "HELTEC_VISION_MASTER_T190",
+	67: "HELTEC_VISION_MASTER_E213", 68: "HELTEC_VISION_MASTER_E290",
+	69: "HELTEC_MESH_NODE_T114", 70: "SENSECAP_INDICATOR",
+	71: "TRACKER_T1000_E", 255: "PRIVATE_HW",
+}
+
+// HardwareModelName returns the HardwareModel enum name for v. Models not
+// in the table are rendered as HW_MODEL_<n> rather than rejected.
+func HardwareModelName(v uint32) string {
+	if name, ok := hardwareModelNames[v]; ok {
+		return name
+	}
+	return fmt.Sprintf("HW_MODEL_%d", v)
+}
+
+// HardwareModelNumber is the inverse of HardwareModelName. Unknown names
+// map to UNSET (0).
+func HardwareModelNumber(name string) uint32 {
+	for v, n := range hardwareModelNames {
+		if n == name {
+			return v
+		}
+	}
+	var v uint32
+	fmt.Sscanf(name, "HW_MODEL_%d", &v) //nolint:errcheck
+	return v
+}
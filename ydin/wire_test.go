@@ -0,0 +1,218 @@
+package proto
+
+import (
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// Golden frames are the protobuf encodings of the messages below under the
+// meshtastic/protobufs schema (mesh.proto), produced by protobuf-go from
+// descriptors of those messages rather than by this package. They are in
+// field-number order, which is what the firmware's nanopb encoder writes,
+// so EncodeFromRadio/EncodeToRadio must reproduce them byte for byte.
+
+func unhex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+var fromRadioGolden = []struct {
+	name  string
+	frame string
+	want  *FromRadio
+}{
+	{
+		name:  "text message",
+		frame: "0829123d0defbeadde15ffffffff22130801120a68656c6c6f206d6573683dfecaad0b35785634123d00f15365450000c8404802609fffffffffffffffff017803",
+		want: &FromRadio{ID: 41, Packet: &MeshPacket{
+			ID: 0x12345678, From: 0xdeadbeef, To: BroadcastAddr,
+			RxTime: 1700000000, RxSNR: 6.25, RxRSSI: -97, HopLimit: 2, HopStart: 3,
+			PortNum: PortTextMessage, Payload: []byte("hello mesh"), ReplyID: 0x0badcafe,
+		}},
+	},
+	{
+		name:  "routing ack",
+		frame: "122b0dcdab341215efbeadde1801220b08051202180035cdab0000350df0ad0b45000060c04803587870017803",
+		want: &FromRadio{Packet: &MeshPacket{
+			ID: 0x0badf00d, From: 0x1234abcd, To: 0xdeadbeef, Channel: 1,
+			RxSNR: -3.5, HopLimit: 3, HopStart: 3, Priority: 120, ViaMQTT: true,
+			PortNum: PortRouting, Payload: []byte{0x18, 0x00}, RequestID: 0xabcd,
+		}},
+	},
+	{
+		name:  "encrypted packet",
+		frame: "0807121c0dd4c3b2a115ffffffff18082a058a1103fe42350100000048077807",
+		want: &FromRadio{ID: 7, Packet: &MeshPacket{
+			ID: 1, From: 0xa1b2c3d4, To: BroadcastAddr, Channel: 8,
+			Encrypted: []byte{0x8a, 0x11, 0x03, 0xfe, 0x42}, HopLimit: 7, HopStart: 7,
+		}},
+	},
+	{
+		name:  "my_info",
+		frame: "08011a0c08effdb6f50d400c58f8eb01",
+		want: &FromRadio{ID: 1, MyInfo: &MyNodeInfo{
+			MyNodeNum: 0xdeadbeef, RebootCount: 12, MinAppVersion: 30200,
+		}},
+	},
+	{
+		name: "node_info",
+		frame: "0802224d08cdd7d2910112230a09213132333461626364120d48696c6c746f702052656c61791a03485452282b3802" +
+			"25000018412d64f153653214085715000080401d00004841250000c03f28901c4801",
+		want: &FromRadio{ID: 2, NodeInfo: &NodeInfo{
+			NodeID: 0x1234abcd, UserID: "!1234abcd", LongName: "Hilltop Relay", ShortName: "HTR",
+			HardwareModel: "HELTEC_V3", Role: "ROUTER",
+			SNR: 9.5, LastHeard: 1700000100, HopsAway: 1,
+			DeviceMetrics: &DeviceMetrics{
+				BatteryLevel: 87, Voltage: 4, ChannelUtil: 12.5, AirUtil: 1.5, UptimeSeconds: 3600,
+			},
+		}},
+	},
+	{
+		name:  "config_complete_id",
+		frame: "080338d29eadea04",
+		want:  &FromRadio{ID: 3, ConfigCompleteID: 0x4d4b4f52},
+	},
+}
+
+var toRadioGolden = []struct {
+	name  string
+	frame string
+	want  *ToRadio
+}{
+	{
+		name:  "direct text with want_ack",
+		frame: "0a1a15cdab3412180222080801120470696e67350100ed5e48035001",
+		want: &ToRadio{Packet: &MeshPacket{
+			ID: 0x5eed0001, To: 0x1234abcd, Channel: 2, HopLimit: 3, WantAck: true,
+			PortNum: PortTextMessage, Payload: []byte("ping"),
+		}},
+	},
+	{
+		name:  "want_config_id",
+		frame: "18d29eadea04",
+		want:  &ToRadio{WantConfigID: 0x4d4b4f52},
+	},
+}
+
+func TestFromRadioGolden(t *testing.T) {
+	m := New()
+	for _, tc := range fromRadioGolden {
+		t.Run(tc.name, func(t *testing.T) {
+			frame := unhex(t, tc.frame)
+
+			got, err := m.DecodeFromRadio(frame)
+			if err != nil {
+				t.Fatalf("DecodeFromRadio: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("DecodeFromRadio:\n got %+v\nwant %+v", got, tc.want)
+			}
+
+			enc, err := m.EncodeFromRadio(tc.want)
+			if err != nil {
+				t.Fatalf("EncodeFromRadio: %v", err)
+			}
+			if hex.EncodeToString(enc) != tc.frame {
+				t.Errorf("EncodeFromRadio:\n got %x\nwant %s", enc, tc.frame)
+			}
+		})
+	}
+}
+
+func TestToRadioGolden(t *testing.T) {
+	m := New()
+	for _, tc := range toRadioGolden {
+		t.Run(tc.name, func(t *testing.T) {
+			frame := unhex(t, tc.frame)
+
+			enc, err := m.EncodeToRadio(tc.want)
+			if err != nil {
+				t.Fatalf("EncodeToRadio: %v", err)
+			}
+			if hex.EncodeToString(enc) != tc.frame {
+				t.Errorf("EncodeToRadio:\n got %x\nwant %s", enc, tc.frame)
+			}
+
+			got, err := m.DecodeToRadio(frame)
+			if err != nil {
+				t.Fatalf("DecodeToRadio: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("DecodeToRadio:\n got %+v\nwant %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+// TestDecodeFieldOrder checks that decoding does not depend on field order
+// or choke on fields this package does not know. protobuf-go, for one,
+// writes oneof members after the other fields.
+func TestDecodeFieldOrder(t *testing.T) {
+	m := New()
+
+	// fromRadioGolden[0] as protobuf-go orders it: MeshPacket.decoded last.
+	fr, err := m.DecodeFromRadio(unhex(t,
+		"0829123d0defbeadde15ffffffff35785634123d00f15365450000c8404802609fffffffffffffffff0178032213"+
+			"0801120a68656c6c6f206d6573683dfecaad0b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fr, fromRadioGolden[0].want) {
+		t.Errorf("reordered FromRadio:\n got %+v\nwant %+v", fr, fromRadioGolden[0].want)
+	}
+
+	// toRadioGolden[0] with MeshPacket.decoded last and an unknown field
+	// 99 (varint 1) appended to the packet.
+	tr, err := m.DecodeToRadio(unhex(t, "0a1d15cdab34121802350100ed5e4803500122080801120470696e67980601"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(tr, toRadioGolden[0].want) {
+		t.Errorf("reordered ToRadio:\n got %+v\nwant %+v", tr, toRadioGolden[0].want)
+	}
+}
+
+func TestDecodeRejectsMalformed(t *testing.T) {
+	m := New()
+	for name, frame := range map[string]string{
+		"empty":               "",
+		"truncated varint":    "08ff",
+		"truncated packet":    "122b0dcdab3412",
+		"decoded not a bytes": "12022001",
+		"packet a varint":     "1001",
+		"my_info a varint":    "1801",
+		"user a varint":       "22021001",
+		"metadata a fixed32":  "6d01000000",
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := m.DecodeFromRadio(unhex(t, frame)); err == nil {
+				t.Error("DecodeFromRadio: want an error")
+			}
+		})
+	}
+
+	// Submessages of payloads are checked the same way.
+	if _, err := m.DecodeTelemetry(unhex(t, "1001")); !errors.Is(err, errWireType) {
+		t.Errorf("DecodeTelemetry of a varint device_metrics: got %v, want errWireType", err)
+	}
+	if _, err := m.DecodeRouting(unhex(t, "0801")); !errors.Is(err, errWireType) {
+		t.Errorf("DecodeRouting of a varint route_request: got %v, want errWireType", err)
+	}
+}
+
+func TestEncodeToRadioLimits(t *testing.T) {
+	m := New()
+	if _, err := m.EncodeToRadio(&ToRadio{}); err == nil {
+		t.Error("ToRadio with no variant: want an error")
+	}
+	big := &ToRadio{Packet: &MeshPacket{PortNum: PortTextMessage, Payload: make([]byte, MaxFrameSize)}}
+	if _, err := m.EncodeToRadio(big); err == nil {
+		t.Error("oversized ToRadio: want an error")
+	}
+}